@@ -1,6 +1,8 @@
 package main
 
 import (
+	"crypto/subtle"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
@@ -8,94 +10,164 @@ import (
 	"time"
 
 	"github.com/GoogleCloudPlatform/functions-framework-go/functions"
-	"github.com/google/uuid"
 
+	"Cloudtacts/pkg/api"
 	"Cloudtacts/pkg/auth"
 	"Cloudtacts/pkg/config"
 	"Cloudtacts/pkg/model"
+	"Cloudtacts/pkg/notify"
 	"Cloudtacts/pkg/storage"
 	"Cloudtacts/pkg/util"
 )
 
 const (
-	functionKeyHeader = "CT-Function-Name"
-	errorCodeHeader   = "CT-Error-Code"
-	userTokenHeader   = "CT-User-Token"
-
-	loginUserNameDef    = "LoginUser"
-	getUserNameDef      = "GetUser"
-	addUserNameDef      = "AddUser"
-	deleteUserNameDef   = "DeleteUser"
-	updateUserNameDef   = "UpdateUser"
-	validateUserNameDef = "ValidateUser"
-
-	getUserResponse      = "{'username': '%v', 'profile': '%v', 'email': '%v', 'imageLoc': '%v', 'lastOn': '%v', 'validatedOn': '%v'}"
-	addUserResponse      = "{'username': '%v', 'profile': '%v', 'result': 'added'}"
-	deleteUserResponse   = "{'username': '%v', 'profile': '%v', 'result': 'deleted'}"
-	updateUserResponse   = "{'username': '%v', 'profile': '%v', 'result': 'updated'}"
-	validateUserResponse = "{'username': '%v', 'profile': '%v', 'result': 'validated'}"
-	loginUserResponse    = "{'username': '%v', 'profile': '%v', 'lastOn': '%v', 'result': 'logged'}"
+	functionKeyHeader = api.FUNCTION_NAME_HEADER
+	errorCodeHeader   = api.ERROR_CODE_HEADER
+	userTokenHeader   = api.USER_TOKEN_HEADER
+	refreshHeader     = api.REFRESH_TOKEN_HEADER
+	logoutAllHeader   = api.LOGOUT_ALL_HEADER
+
+	apiModeFunctions = "functions"
+	apiModeRest      = "rest"
+	apiModeBoth      = "both"
 )
 
+// userOperation is an operation on a user's information in the user
+// database, independent of how the referenced user was read from the
+// request. It returns the response body, one of the api package's response
+// types, and may add response headers to the given writer.
+type userOperation func(w http.ResponseWriter, r *http.Request, user *model.User, uc auth.UserDBClient) (any, model.ServiceError)
+
+// userFunction is a user database function served both as a function target,
+// selected by the CT-Function-Name header, and by the REST API routes (see
+// rest.go).
+type userFunction struct {
+	*api.Function
+	logName string        // handler name logged
+	errTmpl string        // error message template, see writeErrorResponse
+	run     userOperation // the function's operation
+}
+
+var userFunctions = []*userFunction{
+	{api.LoginUser, "loginUser", "Error reading user info: %v/%v.", loginUser},
+	{api.GetUser, "getUserInfo", "Error reading user info: %v/%v.", getUserInfo},
+	{api.AddUser, "addNewUser", "Error adding new user: %v/%v.", addNewUserInfo},
+	{api.DeleteUser, "deleteUser", "Error deleting user: %v/%v.", deleteUserInfo},
+	{api.UpdateUser, "updateUser", "Error updating user: %v/%v.", updateUserInfo},
+	{api.ValidateUser, "validateUser", "Error validating user info: %v/%v.", validateUserInfo},
+	{api.RefreshToken, "refreshToken", "Error refreshing user token: %v/%v.", refreshUserToken},
+	{api.Logout, "logoutUser", "Error logging out user: %v/%v.", logoutUser},
+}
+
 var testMode bool
 var cfg *config.Config
+var tokens *auth.TokenService
+var pool *auth.DBPool
+
+// Function loginUser is a user operation
+func loginUser(w http.ResponseWriter, r *http.Request, user *model.User, uc auth.UserDBClient) (any, model.ServiceError) {
+	loginPass := user.CtPass
+	user.CtPass = ""
 
-// Function loginUser is an HTTP handler
-func loginUser(w http.ResponseWriter, r *http.Request) {
-	logIt("Executing 'loginUser'...")
+	var token, refresh string
+	rehashed, serr := auth.AuthenticateUser(r.Context(), cfg, uc, user, loginPass)
 
-	user, uc, serr := connect(w, r, cfg.ValueOfWithDefault(model.KEY_AUTH_FUNCTION_LOG, loginUserNameDef))
-	var loginPass string
 	if !serr.IsError() {
-		defer uc.Close()
+		if rehashed {
+			logIt(fmt.Sprintf("Re-hashed password of user %v/%v.", user.CtUser, user.CtProf))
+		}
+		user.LLogin = time.Now().UTC().Format(model.FMT_DATETIME_GO)
+
+		var sess *model.Session
+		if sess, refresh, serr = auth.NewSession(cfg, user, r.UserAgent()); !serr.IsError() {
+			serr = uc.AddSessionContext(r.Context(), sess)
+		}
 
-		loginPass = user.CtPass
-		if user.HasTextPwd() {
-			loginPass = user.PwdHash(true)
+		if !serr.IsError() {
+			// the user's token stays its confirmation token, if pending
+			if token, _, serr = tokens.IssueToken(user, sess.SessId); !serr.IsError() {
+				serr = uc.UpdateUserContext(r.Context(), user)
+			}
 		}
-		user.CtPass = ""
+	}
 
-		serr = uc.UserInfo(user)
+	if serr.IsError() {
+		return nil, serr
 	}
 
+	w.Header().Add(userTokenHeader, token)
+	w.Header().Add(refreshHeader, refresh)
+
+	return api.NewLoginResult(user), model.NoError
+}
+
+// Function refreshUserToken is a user operation
+func refreshUserToken(w http.ResponseWriter, r *http.Request, user *model.User, uc auth.UserDBClient) (any, model.ServiceError) {
+	var token string
+
+	_, rtoken := headerValue(r, refreshHeader)
+	sess, refresh, serr := auth.RefreshSession(r.Context(), cfg, uc, user, rtoken)
+
 	if !serr.IsError() {
-		if loginPass != user.CtPass {
-			serr = model.InvalidLoginError
-		} else {
-			user.LLogin = time.Now().UTC().Format(model.FMT_DATETIME_GO)
-			user.AToken = fmt.Sprintf("%v%v", strings.ReplaceAll(uuid.New().String(), "-", "")[0:22], user.LLogin)
-			serr = uc.UpdateUser(user)
-		}
+		token, _, serr = tokens.IssueToken(user, sess.SessId)
+	}
+
+	if serr.IsError() {
+		return nil, serr
 	}
 
+	w.Header().Add(userTokenHeader, token)
+	w.Header().Add(refreshHeader, refresh)
+
+	return api.NewMutationResult(user, api.RESULT_REFRESHED), model.NoError
+}
+
+// Function logoutUser is a user operation
+func logoutUser(w http.ResponseWriter, r *http.Request, user *model.User, uc auth.UserDBClient) (any, model.ServiceError) {
+	claims, serr := validateToken(r, uc, user)
+
+	count := 0
 	if !serr.IsError() {
-		body := fmt.Sprintf(loginUserResponse, user.CtUser, user.CtProf, user.LLogin)
-		w.Header().Add(userTokenHeader, user.AToken)
-		if cnt, err := fmt.Fprintln(w, body); err != nil {
-			serr = model.IOError.WithCause(err)
-			logIt(fmt.Sprintf("Wrote %d bytes\nError = %v", cnt, err))
+		if _, all := headerValue(r, logoutAllHeader); all == "true" {
+			var sessions []model.Session
+			if sessions, serr = uc.UserSessionsContext(r.Context(), user); !serr.IsError() {
+				count = len(sessions)
+				serr = uc.DeleteUserSessionsContext(r.Context(), user)
+			}
+		} else if len(claims.SessId) > 0 {
+			count = 1
+			serr = uc.DeleteSessionContext(r.Context(), &model.Session{SessId: claims.SessId})
 		}
 	}
 
 	if serr.IsError() {
-		writeErrorResponse(w, "Error reading user info: %v/%v.", user, serr)
+		return nil, serr
 	}
-}
 
-// Function validateUser is an HTTP handler
-func validateUserInfo(w http.ResponseWriter, r *http.Request) {
-	logIt("Executing 'validateUser'...")
+	return api.NewLogoutResult(user, count), model.NoError
+}
 
-	var serr model.ServiceError
-	var user *model.User
+// Function validateUserInfo is a user operation
+func validateUserInfo(w http.ResponseWriter, r *http.Request, user *model.User, uc auth.UserDBClient) (any, model.ServiceError) {
 	var passedTime time.Duration
-	var uc auth.UserDBClient
+	var token string
 
-	user, uc, serr = connect(w, r, cfg.ValueOfWithDefault(model.KEY_AUTH_FUNCTION_VAL, validateUserNameDef))
-	if !serr.IsError() {
-		defer uc.Close()
+	if r.Method == http.MethodGet {
+		// confirmation link
+		token = r.URL.Query().Get("token")
+	} else {
+		_, token = headerValue(r, userTokenHeader)
+	}
+
+	serr := uc.UserInfoContext(r.Context(), user)
 
-		serr = uc.UserInfo(user)
+	if !serr.IsError() && len(user.UValid) > 0 {
+		// already validated: there's no pending registration to confirm, nor
+		// to remove
+		serr = model.InvalidTokenError
+	}
+	if !serr.IsError() && (len(token) == 0 || subtle.ConstantTimeCompare([]byte(token), []byte(user.AToken)) != 1) {
+		serr = model.InvalidTokenError
 	}
 
 	isValid := false
@@ -104,11 +176,11 @@ func validateUserInfo(w http.ResponseWriter, r *http.Request) {
 		added := util.StripDateStamp(user.LLogin)
 
 		if len(added) == 14 && len(user.UValid) == 0 {
-			// Is validation response in time? (<= 15min)
+			// Is validation response in time? (<= 15min by default)
 			addedDatetime, serr := util.ToDatetime(added)
 			if !serr.IsError() {
 				passedTime = currentTime.Sub(addedDatetime)
-				isValid = (passedTime.Abs().Milliseconds() <= (15 * 60 * 1000)) // <= 15 mins
+				isValid = (passedTime.Abs() <= auth.ValidationWindow(cfg))
 			}
 		}
 	}
@@ -116,14 +188,12 @@ func validateUserInfo(w http.ResponseWriter, r *http.Request) {
 	if !serr.IsError() {
 		if isValid {
 			user.UValid = currentTime.Format(model.FMT_DATETIME_GO)
-			if serr = uc.UpdateUser(user); !serr.IsError() {
-				if cnt, err := fmt.Fprintln(w, fmt.Sprintf(validateUserResponse, user.CtUser, user.CtProf)); err != nil {
-					serr = model.IOError.WithCause(err)
-					logIt(fmt.Sprintf("Wrote %d bytes\nError = %v", cnt, err))
-				}
-			}
+			user.AToken = ""
+			serr = uc.UpdateUserContext(r.Context(), user)
 		} else {
-			if serr = removeUserData(uc, user); serr.IsError() {
+			// unless validated concurrently, e.g. by a repeated request
+			before := currentTime.Add(-auth.ValidationWindow(cfg))
+			if _, serr = auth.RemoveUnvalidatedUser(r.Context(), cfg, uc, user, before); serr.IsError() {
 				util.LogIt("Cloudtacts", fmt.Sprintf("Error removing user data: %v", serr))
 			}
 
@@ -133,105 +203,63 @@ func validateUserInfo(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if serr.IsError() {
-		writeErrorResponse(w, "Error validating user info: %v/%v.", user, serr)
+		return nil, serr
 	}
-}
-
-// Function getUserInfo is an HTTP handler
-func getUserInfo(w http.ResponseWriter, r *http.Request) {
-	logIt("Executing 'getUserInfo'...")
-
-	user, uc, serr := connect(w, r, cfg.ValueOfWithDefault(model.KEY_AUTH_FUNCTION_GET, getUserNameDef))
-	if !serr.IsError() {
-		defer uc.Close()
 
-		serr = uc.UserInfo(user)
-	}
+	return api.NewMutationResult(user, api.RESULT_VALIDATED), model.NoError
+}
 
-	if !serr.IsError() {
-		body := fmt.Sprintf(getUserResponse, user.CtUser, user.CtProf, user.UEmail, user.CtPpic, user.LLogin, user.UValid)
-		if len(user.AToken) > 0 {
-			w.Header().Add("CT-User-Token", user.AToken)
-		}
-		if cnt, err := fmt.Fprintln(w, body); err != nil {
-			serr = model.IOError.WithCause(err)
-			logIt(fmt.Sprintf("Wrote %d bytes\nError = %v", cnt, err))
-		}
+// Function getUserInfo is a user operation
+func getUserInfo(w http.ResponseWriter, r *http.Request, user *model.User, uc auth.UserDBClient) (any, model.ServiceError) {
+	if serr := uc.UserInfoContext(r.Context(), user); serr.IsError() {
+		return nil, serr
 	}
 
-	if serr.IsError() {
-		writeErrorResponse(w, "Error reading user info: %v/%v.", user, serr)
-	}
+	return api.NewUserView(user), model.NoError
 }
 
-// Function addNewUser is an HTTP handler
-func addNewUserInfo(w http.ResponseWriter, r *http.Request) {
-	logIt("Executing 'addNewUser'...")
-
-	user, uc, serr := connect(w, r, cfg.ValueOfWithDefault(model.KEY_AUTH_FUNCTION_ADD, addUserNameDef))
-	if !serr.IsError() {
-		defer uc.Close()
+// Function addNewUserInfo is a user operation
+func addNewUserInfo(w http.ResponseWriter, r *http.Request, user *model.User, uc auth.UserDBClient) (any, model.ServiceError) {
+	serr := auth.HashUserPwd(cfg, user)
 
-		if user.HasTextPwd() {
-			user.CtPass = user.PwdHash(true)
-		}
-
-		if len(user.CtPpic) > 0 && !user.HasProfilePicKey() {
-			_, serr = storage.SaveProfilePic(cfg, user)
+	var store storage.ObjectStore
+	if !serr.IsError() && len(user.CtPpic) > 0 && !user.HasProfilePicKey() {
+		if store, serr = storage.GetObjectStore(cfg); !serr.IsError() {
+			defer store.Close()
 		}
 	}
 
 	if !serr.IsError() {
-		serr = uc.AddUser(user)
-		if !serr.IsError() {
-			user.LLogin = time.Now().UTC().Format(model.FMT_DATETIME_GO)
-			serr = uc.UpdateUser(user)
-		}
-	}
-
-	// TODO: generate verification e-mail
-	//if !serr.IsError() {
-	// serr = sendVerificationEmail(w, user)
-	//}
+		serr = auth.RegisterUser(r.Context(), cfg, uc, store, user)
 
-	if !serr.IsError() {
-		w.WriteHeader(http.StatusCreated)
-		if cnt, err := fmt.Fprintln(w, fmt.Sprintf(addUserResponse, user.CtUser, user.CtProf)); err != nil {
-			serr = model.IOError.WithCause(err)
-			logIt(fmt.Sprintf("Wrote %d bytes\nError = %v", cnt, err))
+		if !serr.IsError() {
+			if serr = notify.SendConfirmation(cfg, user, user.AToken); serr.IsError() {
+				// the registration can't be confirmed without the e-mail
+				if rerr := auth.RemoveUserData(r.Context(), cfg, uc, user); rerr.IsError() {
+					util.LogIt("Cloudtacts", fmt.Sprintf("Error removing user data: %v", rerr))
+				}
+			}
 		}
 	}
 
 	if serr.IsError() {
-		var msg, tmpl string
-		if strings.Contains(serr.Cause.Error(), model.UserExistsError) {
-			tmpl = "Error adding new user: %v/%v - user exists."
-			msg = fmt.Sprintf(tmpl, user.CtUser, user.CtProf)
+		if serr.Code == model.DbPKeyError.Code {
+			util.LogIt("Cloudtacts", fmt.Sprintf("Error adding new user: %v/%v - user exists.%v", user.CtUser, user.CtProf, serr))
 		} else {
-			tmpl = "Error adding new user: %v/%v."
-			msg = fmt.Sprintf(tmpl, user.CtUser, user.CtProf)
+			util.LogIt("Cloudtacts", fmt.Sprintf("Error adding new user: %v/%v.%v", user.CtUser, user.CtProf, serr))
 		}
-		writeErrorResponse(w, tmpl, user, serr)
-		util.LogIt("Cloudtacts", fmt.Sprintf("%v%v", msg, serr))
+		return nil, serr
 	}
-}
 
-// Function deleteUser is an HTTP handler
-func deleteUserInfo(w http.ResponseWriter, r *http.Request) {
-	logIt("Executing 'deleteUser'...")
-
-	var serr model.ServiceError
-	user, uc, serr := connect(w, r, cfg.ValueOfWithDefault(model.KEY_AUTH_FUNCTION_DEL, deleteUserNameDef))
+	return api.NewMutationResult(user, api.RESULT_ADDED), model.NoError
+}
 
-	var quser *model.User
+// Function deleteUserInfo is a user operation
+func deleteUserInfo(w http.ResponseWriter, r *http.Request, user *model.User, uc auth.UserDBClient) (any, model.ServiceError) {
+	quser := user.Clone()
+	serr := uc.UserInfoContext(r.Context(), quser)
 	if !serr.IsError() {
-		defer uc.Close()
-
-		quser = user.Clone()
-		serr = uc.UserInfo(quser)
-		if !serr.IsError() {
-			_, serr = validateToken(r, quser)
-		}
+		_, serr = validateToken(r, uc, quser)
 	}
 
 	if !serr.IsError() {
@@ -248,56 +276,86 @@ func deleteUserInfo(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if !serr.IsError() {
-		if serr = uc.DeleteUser(user); !serr.IsError() {
-			if cnt, err := fmt.Fprintln(w, fmt.Sprintf(deleteUserResponse, user.CtUser, user.CtProf)); err != nil {
-				serr = model.IOError.WithCause(err)
-				logIt(fmt.Sprintf("Wrote %d bytes\nError = %v", cnt, err))
-			}
+		serr = uc.DeleteUserContext(r.Context(), user)
+	}
+
+	if serr.IsError() {
+		return nil, serr
+	}
+
+	return api.NewMutationResult(user, api.RESULT_DELETED), model.NoError
+}
+
+// Function updateUserInfo is a user operation
+func updateUserInfo(w http.ResponseWriter, r *http.Request, user *model.User, uc auth.UserDBClient) (any, model.ServiceError) {
+	quser := user.Clone()
+	serr := uc.UserInfoContext(r.Context(), quser)
+	if !serr.IsError() {
+		_, serr = validateToken(r, uc, quser)
+		user.AToken = quser.AToken
+	}
+	if !serr.IsError() {
+		if len(user.CtPass) > 0 {
+			serr = auth.HashUserPwd(cfg, user)
+		} else {
+			user.CtPass = quser.CtPass
+		}
+	}
+	if !serr.IsError() {
+		if len(user.CtPpic) > 0 && !user.HasProfilePicKey() {
+			user.UserId = quser.UserId
+			_, serr = storage.SaveProfilePic(cfg, user)
+		} else {
+			user.CtPpic = quser.CtPpic
 		}
 	}
 
+	if !serr.IsError() {
+		serr = uc.UpdateUserContext(r.Context(), user)
+	}
+
 	if serr.IsError() {
-		writeErrorResponse(w, "Error deleting user: %v/%v.", user, serr)
+		return nil, serr
 	}
+
+	return api.NewMutationResult(user, api.RESULT_UPDATED), model.NoError
 }
 
-// Function updateUser is an HTTP handler
-func updateUserInfo(w http.ResponseWriter, r *http.Request) {
-	logIt("Executing 'updateUser'...")
+// Function serveFunction is the HTTP handler of the function's target. It
+// verifies the request names the function and runs the function's operation
+// on the user of the request body, or of the URL query of a link.
+func (fn *userFunction) serveFunction(w http.ResponseWriter, r *http.Request) {
+	logIt(fmt.Sprintf("Executing '%v'...", fn.logName))
+
+	var user *model.User
+	var uc auth.UserDBClient
+	var serr model.ServiceError
+
+	if fn.Linked && r.Method == http.MethodGet {
+		user, uc, serr = connectLink(r)
+	} else {
+		user, uc, serr = connect(w, r, cfg.ValueOfWithDefault(fn.KeyId, fn.Name))
+	}
 
-	user, uc, serr := connect(w, r, cfg.ValueOfWithDefault(model.KEY_AUTH_FUNCTION_UPD, updateUserNameDef))
+	fn.serve(w, r, user, uc, serr, fn.Status)
+}
+
+// Function serve runs the function's operation, unless the given connection
+// error occurred, and writes its response with the given success status.
+func (fn *userFunction) serve(w http.ResponseWriter, r *http.Request, user *model.User, uc auth.UserDBClient, serr model.ServiceError, status int) {
+	var body any
 	if !serr.IsError() {
 		defer uc.Close()
 
-		quser := user.Clone()
-		serr = uc.UserInfo(quser)
-		if !serr.IsError() {
-			user.AToken, serr = validateToken(r, quser)
-		}
-		if !serr.IsError() {
-			if user.HasTextPwd() {
-				user.CtPass = user.PwdHash(true)
-			}
-
-			if len(user.CtPpic) > 0 && !user.HasProfilePicKey() {
-				_, serr = storage.SaveProfilePic(cfg, user)
-			} else {
-				user.CtPpic = quser.CtPpic
-			}
-		}
+		body, serr = fn.run(w, r, user, uc)
 	}
 
 	if !serr.IsError() {
-		if serr = uc.UpdateUser(user); !serr.IsError() {
-			if cnt, err := fmt.Fprintln(w, fmt.Sprintf(updateUserResponse, user.CtUser, user.CtProf)); err != nil {
-				serr = model.IOError.WithCause(err)
-				logIt(fmt.Sprintf("Wrote %d bytes\nError = %v", cnt, err))
-			}
-		}
+		serr = writeResponse(w, status, body)
 	}
 
 	if serr.IsError() {
-		writeErrorResponse(w, "Error updating user: %v/%v.", user, serr)
+		writeErrorResponse(w, r, fn.errTmpl, user, serr)
 	}
 }
 
@@ -307,17 +365,15 @@ func updateUserInfo(w http.ResponseWriter, r *http.Request) {
 func connect(w http.ResponseWriter, r *http.Request, requestName string) (*model.User, auth.UserDBClient, model.ServiceError) {
 	var user model.User
 	var uc auth.UserDBClient
+	var body []byte
 	serr := model.NoError
 
 	if ok, _ := verifyRequestFunction(r, requestName); ok {
 
-		if body, serr := readRequestBody(w, r); !serr.IsError() {
+		if body, serr = readRequestBody(r); !serr.IsError() {
 
-			if user, serr = getUser(w, body); !serr.IsError() {
-				uc, serr = auth.GetDbClient(cfg, cfg.ValueOf(model.KEY_USERDB_HOST_IP), cfg.ValueOf(model.KEY_USERDB_PORT_NUM), cfg.ValueOf(model.KEY_USERDB_DATABASE))
-				if serr.IsError() {
-					util.LogIt("Cloudtacts", serr.Error())
-				}
+			if user, serr = getUser(body); !serr.IsError() {
+				uc, serr = connectDb()
 			}
 		}
 	} else {
@@ -327,75 +383,88 @@ func connect(w http.ResponseWriter, r *http.Request, requestName string) (*model
 	return &user, uc, serr
 }
 
-func removeUserData(uc auth.UserDBClient, user *model.User) model.ServiceError {
-	var serr model.ServiceError
+// Function connectLink returns the user instance referenced by the request's
+// URL query, e.g. of a registration confirmation link, and a database
+// connection handle. An instance of ServiceError is returned if an error
+// occurs.
+func connectLink(r *http.Request) (*model.User, auth.UserDBClient, model.ServiceError) {
+	query := r.URL.Query()
+	user := model.User{
+		CtUser: query.Get("ctuser"),
+		CtProf: query.Get("ctprof"),
+		UEmail: query.Get("uemail"),
+	}
 
-	quser := user.Clone()
-	serr = uc.UserInfo(quser)
+	uc, serr := connectDb()
 
-	if !serr.IsError() {
-		if len(quser.CtPpic) > 0 {
-			quser.CtImgt = util.ImageFileType(quser.CtPpic)
-		}
-
-		if quser.HasProfilePicKey() {
-			_, serr = storage.DeleteProfilePic(cfg, quser)
-			if serr.IsError() {
-				util.LogIt("Cloudtacts", fmt.Sprintf("Error attempting to delete profile image: %v", serr))
-			}
-		}
-	}
+	return &user, uc, serr
+}
 
-	if !serr.IsError() {
-		serr = uc.DeleteUser(user)
+// Function connectDb borrows the shared user database connection handle. The
+// handle's Close function returns it to the pool.
+func connectDb() (auth.UserDBClient, model.ServiceError) {
+	uc, serr := pool.Client()
+	if serr.IsError() {
+		util.LogIt("Cloudtacts", serr.Error())
 	}
 
-	return serr
+	return uc, serr
 }
 
-func getUser(w http.ResponseWriter, body []byte) (model.User, model.ServiceError) {
+func getUser(body []byte) (model.User, model.ServiceError) {
 	var userList model.UserList
 
-	if err := util.ToUserList(body, &userList); err == nil {
-		return userList.Users[0], model.NoError
-	} else {
-		user := model.User{}
-		serr := model.InvalidMsgError.WithCause(err)
-		writeErrorResponse(w, "Error converting request body.", &user, serr)
-		return user, serr
+	if err := util.ToUserList(body, &userList); err != nil {
+		return model.User{}, model.InvalidMsgError.WithCause(err)
+	}
+	if len(userList.Users) == 0 {
+		return model.User{}, model.InvalidKeyError.WithCause(model.NoUserIdError)
 	}
+
+	return userList.Users[0], model.NoError
 }
 
-func readRequestBody(w http.ResponseWriter, r *http.Request) ([]byte, model.ServiceError) {
+func readRequestBody(r *http.Request) ([]byte, model.ServiceError) {
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		serr := model.InternalReadError.WithCause(err)
-		writeErrorResponse(w, "Error reading request body.", &model.User{}, serr)
-		return nil, serr
+		return nil, model.InternalReadError.WithCause(err)
 	}
 	return body, model.NoError
 }
 
-// Function writeErrorResponse writes an HTTP status and response message back
+func writeResponse(w http.ResponseWriter, status int, body any) model.ServiceError {
+	bbuff, err := json.Marshal(body)
+	if err != nil {
+		return model.SystemError.WithCause(err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if cnt, err := w.Write(bbuff); err != nil {
+		logIt(fmt.Sprintf("Wrote %d bytes\nError = %v", cnt, err))
+	}
+
+	return model.NoError
+}
+
+// Function writeErrorResponse writes an HTTP status and problem details back
 // to the calling client. Parameter tmpl should be either: 1. a complete message or
 // a message template with fmt compatible placeholders for the user identifier
-// and profile name in that order. If the given user is undefined then a
-// complete
-func writeErrorResponse(w http.ResponseWriter, tmpl string, user *model.User, serr model.ServiceError) {
+// and profile name in that order. If the given user is undefined then only
+// the message preceding the template's placeholders is used. The error's cause
+// is logged, and only returned to the client in test mode.
+func writeErrorResponse(w http.ResponseWriter, r *http.Request, tmpl string, user *model.User, serr model.ServiceError) {
+	detail := strings.SplitN(tmpl, ":", 2)[0]
+	if len(user.CtUser) > 0 {
+		detail = fmt.Sprintf(tmpl, user.CtUser, user.CtProf)
+	}
+
+	problem := api.NewProblem(serr, detail, api.RequestId(r), testMode)
+	util.LogIt("Cloudtacts", fmt.Sprintf("Request %v failed: %v\n%v", problem.RequestId, detail, serr))
+
 	w.Header().Add(errorCodeHeader, serr.Code)
-	w.WriteHeader(model.HttpErrorStatus[serr.Code])
-	if serr.Cause == nil {
-		if len(user.CtUser) > 0 {
-			w.Write([]byte(fmt.Sprintf("%v\n%v.", fmt.Sprintf(tmpl, user.CtUser, user.CtProf), serr.Message)))
-		} else {
-			w.Write([]byte(fmt.Sprintf("%v\n%v.", tmpl, serr.Message)))
-		}
-	} else {
-		if len(user.CtUser) > 0 {
-			w.Write([]byte(fmt.Sprintf("%v\n%v\n%v", fmt.Sprintf(tmpl, user.CtUser, user.CtProf), serr.Message, serr.Cause)))
-		} else {
-			w.Write([]byte(fmt.Sprintf("%v\n%v\n%v", tmpl, serr.Message, serr.Cause)))
-		}
+	if err := api.WriteProblem(w, problem); err != nil {
+		logIt(fmt.Sprintf("Error writing error response: %v", err))
 	}
 }
 
@@ -412,21 +481,20 @@ func verifyRequestFunction(r *http.Request, name string) (bool, string) {
 	return ok, hval
 }
 
-func validateToken(r *http.Request, user *model.User) (string, model.ServiceError) {
-	ok, token := headerValue(r, userTokenHeader)
+// Function validateToken verifies the request's user access token was issued
+// to the referenced user, and that its session hasn't been revoked, and
+// returns its claims.
+func validateToken(r *http.Request, uc auth.UserDBClient, user *model.User) (*auth.TokenClaims, model.ServiceError) {
+	_, token := headerValue(r, userTokenHeader)
 
-	if !ok || token != user.AToken {
-		return "", model.InvalidTokenError
-	}
-
-	if ttime, serr := util.ToDatetime(token[22:36]); !serr.IsError() {
-		passedTime := time.Since(ttime).Abs().Milliseconds()
-		if passedTime > (60 * 60 * 1000) {
-			return "", model.ExpiredTokenError
+	claims, serr := tokens.VerifyUserToken(token, user)
+	if !serr.IsError() && len(claims.SessId) > 0 {
+		if serr = uc.SessionInfoContext(r.Context(), &model.Session{SessId: claims.SessId}); serr.Code == model.DbPKeyMissingError.Code {
+			serr = model.InvalidTokenError
 		}
 	}
 
-	return token, model.NoError
+	return claims, serr
 }
 
 func headerValue(r *http.Request, key string) (bool, string) {
@@ -450,39 +518,33 @@ func init() {
 		util.LogError("Cloudtacts", "function - Failed to parse configuration.", err)
 	}
 	util.LogIt("Cloudtacts", fmt.Sprintf("Parsed configuration = %v", cfgx.IsParsed()))
-	testMode = (cfgx.ValueOfWithDefault(model.KEY_USERDB_TEST_MODE, "false") == "true")
-
-	// Register an HTTP function with the Functions Framework
-	targetList := [][]string{
-		{model.KEY_AUTH_FUNCTION_LOG, loginUserNameDef},
-		{model.KEY_AUTH_FUNCTION_GET, getUserNameDef},
-		{model.KEY_AUTH_FUNCTION_ADD, addUserNameDef},
-		{model.KEY_AUTH_FUNCTION_DEL, deleteUserNameDef},
-		{model.KEY_AUTH_FUNCTION_UPD, updateUserNameDef},
-		{model.KEY_AUTH_FUNCTION_VAL, validateUserNameDef},
-	}
-	for _, targetName := range targetList {
-		target := cfgx.ValueOfWithDefault(targetName[0], targetName[1])
-		switch targetName[1] {
-		case "LoginUser":
-			logIt(fmt.Sprintf("Binding function to target '%v'->'loginUser'.", target))
-			functions.HTTP(target, loginUser)
-		case "GetUser":
-			logIt(fmt.Sprintf("Binding function to target '%v'->'getUserInfo'.", target))
-			functions.HTTP(target, getUserInfo)
-		case "AddUser":
-			logIt(fmt.Sprintf("Binding function to target '%v'->'addNewUser'.", target))
-			functions.HTTP(target, addNewUserInfo)
-		case "DeleteUser":
-			logIt(fmt.Sprintf("Binding function to target '%v'->'deleteUser'.", target))
-			functions.HTTP(target, deleteUserInfo)
-		case "UpdateUser":
-			logIt(fmt.Sprintf("Binding function to target '%v'->'updateUser'.", target))
-			functions.HTTP(target, updateUserInfo)
-		case "ValidateUser":
-			logIt(fmt.Sprintf("Binding function to target '%v'->'validateUser'.", target))
-			functions.HTTP(target, validateUserInfo)
+	cfgx.UsageOnHelp("auth", "Serves the user auth functions and REST API.")
+	if err = cfgx.Validate(cfgx.CommandParameters("auth")...); err != nil {
+		util.LogError("Cloudtacts", "function - Invalid configuration.", err)
+	}
+	testMode, _ = cfgx.Bool(model.KEY_USERDB_TEST_MODE)
+
+	var serr model.ServiceError
+	if tokens, serr = auth.NewTokenService(cfgx); serr.IsError() {
+		util.LogError("Cloudtacts", "function - Failed to configure token service.", serr)
+	}
+
+	pool = auth.NewDBPool(cfgx, cfgx.ValueOf(model.KEY_USERDB_HOST_IP), cfgx.ValueOf(model.KEY_USERDB_PORT_NUM), cfgx.ValueOf(model.KEY_USERDB_DATABASE))
+
+	apiMode := strings.ToLower(cfgx.ValueOfWithDefault(model.KEY_AUTH_API_MODE, apiModeBoth))
+
+	// Register HTTP functions with the Functions Framework
+	if apiMode == apiModeFunctions || apiMode == apiModeBoth {
+		for _, fn := range userFunctions {
+			target := cfgx.ValueOfWithDefault(fn.KeyId, fn.Name)
+			logIt(fmt.Sprintf("Binding function to target '%v'->'%v'.", target, fn.logName))
+			functions.HTTP(target, fn.serveFunction)
 		}
 	}
+	if apiMode == apiModeRest || apiMode == apiModeBoth {
+		target := cfgx.ValueOfWithDefault(model.KEY_AUTH_FUNCTION_API, api.AUTH_API_NAME)
+		logIt(fmt.Sprintf("Binding REST API to target '%v'->'serveRest'.", target))
+		functions.HTTP(target, serveRest)
+	}
 	cfg = cfgx
 }
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"Cloudtacts/pkg/api"
+	"Cloudtacts/pkg/auth"
+	"Cloudtacts/pkg/model"
+	"Cloudtacts/pkg/util"
+)
+
+const (
+	restApiVersion = "v1"
+
+	ctuserParam = "{ctuser}"
+	ctprofParam = "{ctprof}"
+)
+
+// restMethod binds an HTTP method of a REST resource to a user function.
+type restMethod struct {
+	method string
+	fn     *userFunction
+	status int
+}
+
+// restRoute is a REST resource of the auth API. Path segments in braces are
+// parameters, e.g. {ctuser}.
+type restRoute struct {
+	path    []string
+	methods []restMethod
+}
+
+// The REST resources of the api package's routes
+var restRoutes = newRestRoutes(api.Routes)
+
+// Function newRestRoutes returns the REST resources of the given routes,
+// binding each route's method to its user function.
+func newRestRoutes(routes []api.Route) []restRoute {
+	resources := []restRoute{}
+
+	for _, route := range routes {
+		path := strings.Split(strings.TrimPrefix(route.Path, "/"+restApiVersion+"/"), "/")
+		rm := restMethod{route.Method, userFunctionOf(route.Function), route.Status}
+
+		found := false
+		for i := range resources {
+			if strings.Join(resources[i].path, "/") == strings.Join(path, "/") {
+				resources[i].methods = append(resources[i].methods, rm)
+				found = true
+				break
+			}
+		}
+		if !found {
+			resources = append(resources, restRoute{path, []restMethod{rm}})
+		}
+	}
+
+	return resources
+}
+
+// Function userFunctionOf returns the user function of the given function
+// description.
+func userFunctionOf(fn *api.Function) *userFunction {
+	for _, ufn := range userFunctions {
+		if ufn.Function == fn {
+			return ufn
+		}
+	}
+
+	panic(fmt.Sprintf("no user function of '%v'", fn.Name))
+}
+
+// Function serveRest is the HTTP handler of the REST API target. Any path
+// prefix preceding the API version, e.g. of a gateway, is ignored.
+func serveRest(w http.ResponseWriter, r *http.Request) {
+	route, params := matchRoute(r.URL.Path)
+	if route == nil {
+		util.LogIt("Cloudtacts", fmt.Sprintf("Unknown API resource: %v %v", r.Method, r.URL.Path))
+		writeErrorResponse(w, r, "Error routing request.", &model.User{}, model.UnknownPathError)
+		return
+	}
+
+	rm := route.method(r.Method)
+	if rm == nil {
+		w.Header().Set("Allow", route.allow())
+		writeErrorResponse(w, r, "Error routing request.", &model.User{}, model.InvalidMethodError)
+		return
+	}
+	logIt(fmt.Sprintf("Executing '%v'...", rm.fn.logName))
+
+	var uc auth.UserDBClient
+	user, serr := restUser(r, params)
+	if !serr.IsError() {
+		uc, serr = connectDb()
+	}
+
+	rm.fn.serve(w, r, user, uc, serr, rm.status)
+}
+
+// Function matchRoute returns the REST route matching the given URL path and
+// the values of its path parameters, or nil if no route matches. A trailing
+// slash is ignored; other empty path segments match no route.
+func matchRoute(path string) (*restRoute, map[string]string) {
+	prefix := "/" + restApiVersion + "/"
+	path = strings.TrimSuffix(path, "/") + "/"
+	idx := strings.Index(path, prefix)
+	if idx < 0 {
+		return nil, nil
+	}
+	segments := strings.Split(strings.TrimSuffix(path[idx+len(prefix):], "/"), "/")
+
+	for i := range restRoutes {
+		route := &restRoutes[i]
+		if len(route.path) != len(segments) {
+			continue
+		}
+
+		params := map[string]string{}
+		for j, seg := range route.path {
+			if strings.HasPrefix(seg, "{") {
+				if len(segments[j]) == 0 {
+					break
+				}
+				params[seg] = segments[j]
+			} else if seg != segments[j] {
+				break
+			}
+			if j == len(route.path)-1 {
+				return route, params
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+// Function method returns the route's binding of the given HTTP method, or nil
+// if the method isn't allowed.
+func (route *restRoute) method(method string) *restMethod {
+	for i := range route.methods {
+		if route.methods[i].method == method {
+			return &route.methods[i]
+		}
+	}
+
+	return nil
+}
+
+// Function allow returns the value of the Allow header of the route.
+func (route *restRoute) allow() string {
+	methods := []string{}
+	for _, rm := range route.methods {
+		methods = append(methods, rm.method)
+	}
+
+	return strings.Join(methods, ", ")
+}
+
+// Function restUser returns the user instance referenced by the REST request.
+// The request body, if any, is a single user; its key is superseded by the
+// URL query parameters ctuser, ctprof and uemail, and the route's path
+// parameters, in that order.
+func restUser(r *http.Request, params map[string]string) (*model.User, model.ServiceError) {
+	var user model.User
+
+	body, serr := readRequestBody(r)
+	if !serr.IsError() && len(strings.TrimSpace(string(body))) > 0 {
+		if err := util.ToUser(body, &user); err != nil {
+			serr = model.InvalidMsgError.WithCause(err)
+		}
+	}
+
+	if !serr.IsError() {
+		query := r.URL.Query()
+		for _, key := range []struct {
+			val   *string
+			query string
+			param string
+		}{
+			{&user.CtUser, "ctuser", ctuserParam},
+			{&user.CtProf, "ctprof", ctprofParam},
+			{&user.UEmail, "uemail", ""},
+		} {
+			if val := query.Get(key.query); len(val) > 0 {
+				*key.val = val
+			}
+			if val, ok := params[key.param]; ok {
+				*key.val = val
+			}
+		}
+	}
+
+	return &user, serr
+}
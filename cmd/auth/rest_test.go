@@ -0,0 +1,165 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"Cloudtacts/pkg/api"
+	"Cloudtacts/pkg/model"
+)
+
+func TestNewRestRoutes(t *testing.T) {
+	routes := newRestRoutes(api.Routes)
+
+	for _, test := range []struct {
+		path    string
+		methods []string
+	}{
+		{"users", []string{http.MethodPost}},
+		{"users/{ctuser}/{ctprof}", []string{http.MethodGet, http.MethodPatch, http.MethodDelete}},
+		{"users/{ctuser}/{ctprof}/validate", []string{http.MethodGet}},
+		{"sessions", []string{http.MethodPost, http.MethodDelete}},
+		{"sessions/refresh", []string{http.MethodPost}},
+	} {
+		var route *restRoute
+		for i := range routes {
+			if strings.Join(routes[i].path, "/") == test.path {
+				route = &routes[i]
+			}
+		}
+		if route == nil {
+			t.Errorf("Expected a route of resource %q, got: %v", test.path, routes)
+			continue
+		}
+		if allow := route.allow(); allow != strings.Join(test.methods, ", ") {
+			t.Errorf("Expected resource %q methods %v, got: %v", test.path, test.methods, allow)
+		}
+		for _, rm := range route.methods {
+			if rm.fn == nil || rm.status == 0 {
+				t.Errorf("Expected resource %q method %v bound to a user function, got: %+v", test.path, rm.method, rm)
+			}
+		}
+	}
+	if len(routes) != 5 {
+		t.Errorf("Expected the routes of 5 resources, got %d: %v", len(routes), routes)
+	}
+}
+
+func TestMatchRoute(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		path   string
+		route  string
+		params map[string]string
+	}{
+		{"collection", "/v1/users", "users", map[string]string{}},
+		{"trailing slash", "/v1/users/", "users", map[string]string{}},
+		{"gateway prefix", "/cloudtacts/api/v1/sessions/refresh", "sessions/refresh", map[string]string{}},
+		{"path parameters", "/v1/users/pendracon1/Pendracon1", "users/{ctuser}/{ctprof}",
+			map[string]string{ctuserParam: "pendracon1", ctprofParam: "Pendracon1"}},
+		{"nested resource", "/gw/v1/users/pendracon1/Pendracon1/validate", "users/{ctuser}/{ctprof}/validate",
+			map[string]string{ctuserParam: "pendracon1", ctprofParam: "Pendracon1"}},
+		{"empty parameter", "/v1/users//Pendracon1", "", nil},
+		{"empty segment", "/v1//users", "", nil},
+		{"version only", "/v1", "", nil},
+		{"no version", "/users", "", nil},
+		{"other version", "/v2/users", "", nil},
+		{"version suffix", "/v10/users", "", nil},
+		{"too few segments", "/v1/users/pendracon1", "", nil},
+		{"unknown resource", "/v1/contacts", "", nil},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			route, params := matchRoute(test.path)
+			if len(test.route) == 0 {
+				if route != nil {
+					t.Errorf("Expected no route of %q, got: %v", test.path, route.path)
+				}
+				return
+			}
+			if route == nil || strings.Join(route.path, "/") != test.route || !reflect.DeepEqual(params, test.params) {
+				t.Errorf("Expected route %q with %v of %q, got: %v with %v", test.route, test.params, test.path, route, params)
+			}
+		})
+	}
+}
+
+func TestServeRestRouting(t *testing.T) {
+	for _, test := range []struct {
+		method string
+		path   string
+		serr   model.ServiceError
+		allow  string
+	}{
+		{http.MethodPut, "/v1/users", model.InvalidMethodError, "POST"},
+		{http.MethodPost, "/api/v1/users/pendracon1/Pendracon1", model.InvalidMethodError, "GET, PATCH, DELETE"},
+		{http.MethodGet, "/v1/sessions/refresh", model.InvalidMethodError, "POST"},
+		{http.MethodGet, "/v1/users//Pendracon1", model.UnknownPathError, ""},
+		{http.MethodGet, "/users", model.UnknownPathError, ""},
+	} {
+		w := httptest.NewRecorder()
+		serveRest(w, httptest.NewRequest(test.method, test.path, nil))
+
+		if w.Code != model.HttpErrorStatus[test.serr.Code] || w.Header().Get(errorCodeHeader) != test.serr.Code {
+			t.Errorf("Expected %v (HTTP %d) of %v %v, got HTTP %d: %v", test.serr, model.HttpErrorStatus[test.serr.Code],
+				test.method, test.path, w.Code, w.Header().Get(errorCodeHeader))
+		}
+		if allow := w.Header().Get("Allow"); allow != test.allow {
+			t.Errorf("Expected Allow header %q of %v %v, got: %q", test.allow, test.method, test.path, allow)
+		}
+	}
+}
+
+func TestRestUser(t *testing.T) {
+	params := map[string]string{ctuserParam: "pendracon1", ctprofParam: "Pendracon1"}
+
+	for _, test := range []struct {
+		name   string
+		target string
+		body   string
+		params map[string]string
+		user   model.User
+		serr   model.ServiceError
+	}{
+		{"body", "/v1/users", `{"ctuser": "body1", "ctprof": "Body1", "uemail": "body1@example.com"}`, nil,
+			model.User{CtUser: "body1", CtProf: "Body1", UEmail: "body1@example.com"}, model.NoError},
+		{"query over body", "/v1/sessions?ctuser=query1&uemail=query1@example.com", `{"ctuser": "body1", "ctprof": "Body1"}`, nil,
+			model.User{CtUser: "query1", CtProf: "Body1", UEmail: "query1@example.com"}, model.NoError},
+		{"path over body and query", "/v1/users/pendracon1/Pendracon1?ctuser=query1&ctprof=Query1&uemail=query1@example.com",
+			`{"ctuser": "body1", "ctprof": "Body1", "uemail": "body1@example.com"}`, params,
+			model.User{CtUser: "pendracon1", CtProf: "Pendracon1", UEmail: "query1@example.com"}, model.NoError},
+		{"path only", "/v1/users/pendracon1/Pendracon1", "", params,
+			model.User{CtUser: "pendracon1", CtProf: "Pendracon1"}, model.NoError},
+		{"blank body", "/v1/users/pendracon1/Pendracon1", " \n", params,
+			model.User{CtUser: "pendracon1", CtProf: "Pendracon1"}, model.NoError},
+		{"empty query value", "/v1/sessions?ctuser=&ctprof=", `{"ctuser": "body1", "ctprof": "Body1"}`, nil,
+			model.User{CtUser: "body1", CtProf: "Body1"}, model.NoError},
+		{"malformed body", "/v1/users", `{"ctuser": `, nil, model.User{}, model.InvalidMsgError},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, test.target, strings.NewReader(test.body))
+			user, serr := restUser(r, test.params)
+			if serr.Code != test.serr.Code {
+				t.Fatalf("Expected %v, got: %v", test.serr, serr)
+			}
+			if !serr.IsError() && (user.CtUser != test.user.CtUser || user.CtProf != test.user.CtProf || user.UEmail != test.user.UEmail) {
+				t.Errorf("Expected user %v/%v/%v, got: %v/%v/%v", test.user.CtUser, test.user.CtProf, test.user.UEmail,
+					user.CtUser, user.CtProf, user.UEmail)
+			}
+		})
+	}
+}
+
+// The package's init function parses the configuration; tests run in test
+// mode with the repository's configuration, set ahead of it.
+var _ = func() bool {
+	if _, ok := os.LookupEnv("CT_USERDB_TEST_MODE"); !ok {
+		os.Setenv("CT_USERDB_TEST_MODE", "true")
+	}
+	model.ParserConfigPath = "../../config/parameters_config.json"
+	model.ApplicationConfigPath = "../../config/application.properties"
+	return true
+}()
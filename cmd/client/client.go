@@ -7,9 +7,9 @@ import (
 	"io"
 	"net/http"
 	"os"
-	"strconv"
 	"strings"
 
+	"Cloudtacts/pkg/api"
 	"Cloudtacts/pkg/config"
 	"Cloudtacts/pkg/model"
 	"Cloudtacts/pkg/util"
@@ -39,7 +39,11 @@ func postRequest(cfg *config.Config, token *string, function, body string) (int,
 	req.Header.Add("Content-Type", "application/json")
 	req.Header.Add("CT-Function-Name", function)
 	if len(*token) > 0 {
-		req.Header.Add("CT-User-Token", *token)
+		if function == "RefreshToken" {
+			req.Header.Add("CT-Refresh-Token", *token)
+		} else {
+			req.Header.Add("CT-User-Token", *token)
+		}
 	}
 
 	resp, err := client.Do(req)
@@ -50,28 +54,34 @@ func postRequest(cfg *config.Config, token *string, function, body string) (int,
 	}
 	defer resp.Body.Close()
 
-	size, err := strconv.Atoi(resp.Header.Get("Content-Length"))
-	logIt(fmt.Sprintf("Got %d length response", size))
-
-	var buff []byte
+	buff, err := io.ReadAll(resp.Body)
 	if err != nil {
-		serr = model.ClientProtocolError.WithCause(err)
-	} else {
-		buff = make([]byte, size-1)
-		if size, err = resp.Body.Read(buff); err != nil {
-			serr = model.ClientReadError.WithCause(err)
-			logIt(fmt.Sprintf("Error reading response of size %d: %v.", size, serr))
-			return 0, "", serr
-		}
+		serr = model.ClientReadError.WithCause(err)
+		logIt(fmt.Sprintf("Error reading response: %v.", serr))
+		return 0, "", serr
 	}
+	logIt(fmt.Sprintf("Got %d length response", len(buff)))
 
 	if len(resp.Header.Get("CT-User-Token")) > 0 {
 		*token = resp.Header.Get("CT-User-Token")
 	}
+	if len(resp.Header.Get("CT-Refresh-Token")) > 0 {
+		logIt(fmt.Sprintf("Refresh token: %v", resp.Header.Get("CT-Refresh-Token")))
+	}
 
 	return resp.StatusCode, string(buff[:]), serr
 }
 
+// decodeResponse decodes the given response body of the command.
+func decodeResponse(command, body string) (any, model.ServiceError) {
+	result, err := api.DecodeResponse(command, []byte(body))
+	if err != nil {
+		return nil, model.ClientProtocolError.WithCause(err)
+	}
+
+	return result, model.NoError
+}
+
 func readInput(cfg *config.Config) (string, model.ServiceError) {
 	serr := model.NoError
 	var data string
@@ -168,6 +178,10 @@ func main() {
 	if cfg, err = config.ContextConfig(); err != nil {
 		util.LogError("Client", "Failed to parse configuration.", err)
 	}
+	cfg.UsageOnHelp("client", "Sends the request of the given command, read from the --input file, to the user auth functions.")
+	if err = cfg.Validate(cfg.CommandParameters("client")...); err != nil {
+		util.LogError("Client", "Invalid configuration.", err)
+	}
 
 	switch cfg.ValueOf(model.KEY_CLIENT_COMMAND) {
 	case "GetUser":
@@ -176,6 +190,8 @@ func main() {
 	case "UpdateUser":
 	case "ValidateUser":
 	case "LoginUser":
+	case "RefreshToken":
+	case "Logout":
 	default:
 		if !cfg.AssignedValue(model.KEY_CLIENT_COMMAND) {
 			util.LogError("Client", "Command not specified", nil)
@@ -216,6 +232,20 @@ func main() {
 			logIt(fmt.Sprintf("User token: %v", token))
 			logIt(fmt.Sprintf("Response:\n%v", resp))
 		}
+
+		if !serr.IsError() && status < http.StatusBadRequest {
+			var result any
+			if result, serr = decodeResponse(cfg.ValueOf(model.KEY_CLIENT_COMMAND), resp); !serr.IsError() {
+				logIt(fmt.Sprintf("Result: %+v", result))
+			}
+		} else if !serr.IsError() {
+			var problem api.Problem
+			if err := json.Unmarshal([]byte(resp), &problem); err != nil {
+				serr = model.ClientProtocolError.WithCause(err)
+			} else {
+				logIt(fmt.Sprintf("Request %v failed: %v", problem.RequestId, problem.Error()))
+			}
+		}
 	}
 
 	if serr.IsError() {
@@ -2,26 +2,72 @@ package main
 
 import (
 	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
 
 	"Cloudtacts/pkg/config"
 	"Cloudtacts/pkg/model"
-
-	//"Cloudtacts/pkg/model"
 	"Cloudtacts/pkg/util"
 )
 
+// explain writes a table of the parameters' identifiers, effective values
+// (secrets masked), sources, and descriptions to the given writer.
+func explain(w io.Writer, cfg *config.Config) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "OPTION ID\tVALUE\tSOURCE\tDESCRIPTION")
+	for _, parm := range cfg.Parameters() {
+		fmt.Fprintf(tw, "%v\t%v\t%v\t%v\n", parm.OptionId, cfg.DisplayValueOf(parm.OptionId), cfg.SourceOf(parm.OptionId), parm.Description)
+	}
+	return tw.Flush()
+}
+
+// template writes the properties template to the --output file, or to
+// standard output if none is given.
+func template(cfg *config.Config) error {
+	if !cfg.AssignedValue(model.KEY_CLIENT_OUTPUT_FILE) {
+		_, err := os.Stdout.Write(cfg.PropertiesTemplate())
+		return err
+	}
+
+	output := cfg.ValueOf(model.KEY_CLIENT_OUTPUT_FILE)
+	if err := os.WriteFile(output, cfg.PropertiesTemplate(), 0644); err != nil {
+		return err
+	}
+	util.LogIt("Parameters", fmt.Sprintf("Wrote properties template to %v.", output))
+
+	return nil
+}
+
+// Runs the configuration command given by the first positional argument
+// (e.g. config explain), or the --configCommand parameter: explain lists the
+// parameters' effective values and where they were read from, and template
+// writes the properties template.
 func main() {
 	cfg, err := config.ContextConfig()
 	if err != nil {
-		util.LogError("Parameters", "TestConfig", err)
+		util.LogError("Parameters", "Failed to parse configuration.", err)
+	}
+	cfg.UsageOnHelp("config", "Lists the parameters' effective values and where they were read from, or writes the properties template, as given by the first argument or the --configCommand parameter.")
+	if err = cfg.Validate(cfg.CommandParameters("config")...); err != nil {
+		util.LogError("Parameters", "Invalid configuration.", err)
 	}
-	logIt(fmt.Sprintf("Parsed configuration = %v", cfg.IsParsed()))
 
-	logIt("Running VTIS 'parameters' demo...")
-	logIt(fmt.Sprintf("Got userdbTestMode = %v", cfg.ValueOf(model.KEY_USERDB_TEST_MODE)))
-	logIt(fmt.Sprintf("Got userdbHost = %v", cfg.ValueOf(model.KEY_USERDB_HOST_IP)))
-}
+	command := cfg.ValueOfWithDefault(model.KEY_CONFIG_COMMAND, "explain")
+	if arguments := cfg.Arguments(); len(arguments) > 0 {
+		command = arguments[0]
+	}
 
-func logIt(message string) {
-	util.LogIt("ParametersCLI", message)
+	switch command {
+	case "explain":
+		err = explain(os.Stdout, cfg)
+	case "template":
+		err = template(cfg)
+	default:
+		err = fmt.Errorf("unknown configuration command: %v", command)
+	}
+
+	if err != nil {
+		util.LogError("Parameters", "Configuration command failed.", err)
+	}
 }
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/GoogleCloudPlatform/functions-framework-go/functions"
+
+	"Cloudtacts/pkg/api"
+	"Cloudtacts/pkg/auth"
+	"Cloudtacts/pkg/config"
+	"Cloudtacts/pkg/contacts"
+	"Cloudtacts/pkg/model"
+	"Cloudtacts/pkg/util"
+)
+
+const (
+	functionKeyHeader = api.FUNCTION_NAME_HEADER
+	errorCodeHeader   = api.ERROR_CODE_HEADER
+	userTokenHeader   = api.USER_TOKEN_HEADER
+)
+
+// contactFunction is a contacts function served as a function target,
+// selected by the CT-Function-Name header.
+type contactFunction struct {
+	*api.Function
+	logName string           // handler name logged
+	handler http.HandlerFunc // the function's HTTP handler
+}
+
+var contactFunctions = []*contactFunction{
+	{api.ListContacts, "listContacts", listContacts},
+	{api.GetContact, "getContact", getContact},
+	{api.AddContact, "addContact", addContact},
+	{api.UpdateContact, "updateContact", updateContact},
+	{api.DeleteContact, "deleteContact", deleteContact},
+}
+
+var testMode bool
+var cfg *config.Config
+var tokens *auth.TokenService
+
+// Function listContacts is an HTTP handler
+func listContacts(w http.ResponseWriter, r *http.Request) {
+	logIt("Executing 'listContacts'...")
+
+	clist, cs, serr := connect(w, r, targetName(api.ListContacts))
+	if !serr.IsError() {
+		defer cs.Close()
+
+		clist.Contacts, serr = cs.ListContacts(r.Context(), clist.Owner())
+	}
+
+	if !serr.IsError() {
+		serr = writeResponse(w, api.ListContacts.Status, clist)
+	}
+
+	if serr.IsError() {
+		writeErrorResponse(w, r, "Error listing contacts: %v/%v.", clist, serr)
+	}
+}
+
+// Function getContact is an HTTP handler
+func getContact(w http.ResponseWriter, r *http.Request) {
+	logIt("Executing 'getContact'...")
+
+	clist, cs, serr := connect(w, r, targetName(api.GetContact))
+	var contact *model.Contact
+	if !serr.IsError() {
+		defer cs.Close()
+
+		if contact, serr = requestContact(clist); !serr.IsError() {
+			contact, serr = cs.GetContact(r.Context(), clist.Owner(), contact.CtId)
+		}
+	}
+
+	if !serr.IsError() {
+		clist.Contacts = []model.Contact{*contact}
+		serr = writeResponse(w, api.GetContact.Status, clist)
+	}
+
+	if serr.IsError() {
+		writeErrorResponse(w, r, "Error reading contact: %v/%v.", clist, serr)
+	}
+}
+
+// Function addContact is an HTTP handler
+func addContact(w http.ResponseWriter, r *http.Request) {
+	logIt("Executing 'addContact'...")
+
+	clist, cs, serr := connect(w, r, targetName(api.AddContact))
+	var contact *model.Contact
+	if !serr.IsError() {
+		defer cs.Close()
+
+		if contact, serr = requestContact(clist); !serr.IsError() {
+			serr = cs.AddContact(r.Context(), clist.Owner(), contact)
+		}
+	}
+
+	if !serr.IsError() {
+		clist.Contacts = []model.Contact{*contact}
+		serr = writeResponse(w, api.AddContact.Status, clist)
+	}
+
+	if serr.IsError() {
+		writeErrorResponse(w, r, "Error adding contact: %v/%v.", clist, serr)
+	}
+}
+
+// Function updateContact is an HTTP handler
+func updateContact(w http.ResponseWriter, r *http.Request) {
+	logIt("Executing 'updateContact'...")
+
+	clist, cs, serr := connect(w, r, targetName(api.UpdateContact))
+	var contact *model.Contact
+	if !serr.IsError() {
+		defer cs.Close()
+
+		if contact, serr = requestContact(clist); !serr.IsError() {
+			serr = cs.UpdateContact(r.Context(), clist.Owner(), contact)
+		}
+	}
+
+	if !serr.IsError() {
+		clist.Contacts = []model.Contact{*contact}
+		serr = writeResponse(w, api.UpdateContact.Status, clist)
+	}
+
+	if serr.IsError() {
+		writeErrorResponse(w, r, "Error updating contact: %v/%v.", clist, serr)
+	}
+}
+
+// Function deleteContact is an HTTP handler
+func deleteContact(w http.ResponseWriter, r *http.Request) {
+	logIt("Executing 'deleteContact'...")
+
+	clist, cs, serr := connect(w, r, targetName(api.DeleteContact))
+	var contact *model.Contact
+	if !serr.IsError() {
+		defer cs.Close()
+
+		if contact, serr = requestContact(clist); !serr.IsError() {
+			serr = cs.DeleteContact(r.Context(), clist.Owner(), contact.CtId)
+		}
+	}
+
+	if !serr.IsError() {
+		serr = writeResponse(w, api.DeleteContact.Status, api.ContactResult{CtId: contact.CtId, Result: api.RESULT_DELETED})
+	}
+
+	if serr.IsError() {
+		writeErrorResponse(w, r, "Error deleting contact: %v/%v.", clist, serr)
+	}
+}
+
+// Function connect verifies and authorizes the request and returns the
+// corresponding contact list and contact store handle. An instance of
+// ServiceError is returned if an error occurs.
+func connect(w http.ResponseWriter, r *http.Request, requestName string) (*model.ContactList, contacts.ContactStore, model.ServiceError) {
+	var clist model.ContactList
+	var cs contacts.ContactStore
+	serr := model.NoError
+
+	if ok, _ := verifyRequestFunction(r, requestName); !ok {
+		return &clist, cs, model.InvalidMsgError
+	}
+
+	var body []byte
+	if body, serr = readRequestBody(r); !serr.IsError() {
+		if err := json.Unmarshal(body, &clist); err != nil {
+			serr = model.InvalidMsgError.WithCause(err)
+		}
+	}
+
+	if !serr.IsError() {
+		serr = authorize(r, clist.Owner())
+	}
+
+	if !serr.IsError() {
+		if cs, serr = contacts.GetContactStore(cfg); serr.IsError() {
+			util.LogIt("Cloudtacts", serr.Error())
+		}
+	}
+
+	return &clist, cs, serr
+}
+
+// Function authorize verifies the request's user access token was issued to
+// the contact list owner. The token is verified statelessly, without looking
+// up its session, so the token of a revoked session is accepted until it
+// expires (see KEY_USERDB_TOKEN_LIFETIME).
+func authorize(r *http.Request, owner *model.User) model.ServiceError {
+	_, token := headerValue(r, userTokenHeader)
+	_, serr := tokens.VerifyUserToken(token, owner)
+
+	return serr
+}
+
+// Function requestContact returns the first contact in the request's contact
+// list.
+func requestContact(clist *model.ContactList) (*model.Contact, model.ServiceError) {
+	if len(clist.Contacts) == 0 {
+		return nil, model.InvalidContactError.WithCause(model.NoContactIdError)
+	}
+
+	return &clist.Contacts[0], model.NoError
+}
+
+func readRequestBody(r *http.Request) ([]byte, model.ServiceError) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, model.InternalReadError.WithCause(err)
+	}
+	return body, model.NoError
+}
+
+func writeResponse(w http.ResponseWriter, status int, body any) model.ServiceError {
+	bbuff, err := json.Marshal(body)
+	if err != nil {
+		return model.SystemError.WithCause(err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if cnt, err := w.Write(bbuff); err != nil {
+		logIt(fmt.Sprintf("Wrote %d bytes\nError = %v", cnt, err))
+	}
+
+	return model.NoError
+}
+
+// Function writeErrorResponse writes an HTTP status and problem details back
+// to the calling client. Parameter tmpl should be a message template with fmt
+// compatible placeholders for the owner's user identifier and profile name in
+// that order. The error's cause is logged, and only returned to the client in
+// test mode.
+func writeErrorResponse(w http.ResponseWriter, r *http.Request, tmpl string, clist *model.ContactList, serr model.ServiceError) {
+	detail := fmt.Sprintf(tmpl, clist.CtUser, clist.CtProf)
+
+	problem := api.NewProblem(serr, detail, api.RequestId(r), testMode)
+	util.LogIt("Cloudtacts", fmt.Sprintf("Request %v failed: %v\n%v", problem.RequestId, detail, serr))
+
+	w.Header().Add(errorCodeHeader, serr.Code)
+	if err := api.WriteProblem(w, problem); err != nil {
+		logIt(fmt.Sprintf("Error writing error response: %v", err))
+	}
+}
+
+func verifyRequestFunction(r *http.Request, name string) (bool, string) {
+	ok, hval := headerValue(r, functionKeyHeader)
+	if !ok {
+		util.LogIt("Cloudtacts", "Request missing required header.")
+		return false, ""
+	}
+	if hval != name {
+		util.LogIt("Cloudtacts", "Request function mismatch.")
+		return false, ""
+	}
+	return ok, hval
+}
+
+// Function targetName returns the configured target name of the function.
+func targetName(fn *api.Function) string {
+	return cfg.ValueOfWithDefault(fn.KeyId, fn.Name)
+}
+
+func headerValue(r *http.Request, key string) (bool, string) {
+	val := r.Header.Get(key)
+	return len(val) > 0, val
+}
+
+func logIt(message string) {
+	if testMode {
+		util.LogIt("Cloudtacts", message)
+	}
+}
+
+func init() {
+	cfgx, err := config.ContextConfig()
+	if err != nil {
+		util.LogError("Cloudtacts", "function - Failed to parse configuration.", err)
+	}
+	util.LogIt("Cloudtacts", fmt.Sprintf("Parsed configuration = %v", cfgx.IsParsed()))
+	cfgx.UsageOnHelp("contacts", "Serves the contacts functions.")
+	if err = cfgx.Validate(cfgx.CommandParameters("contacts")...); err != nil {
+		util.LogError("Cloudtacts", "function - Invalid configuration.", err)
+	}
+	testMode, _ = cfgx.Bool(model.KEY_USERDB_TEST_MODE)
+
+	var serr model.ServiceError
+	if tokens, serr = auth.NewTokenService(cfgx); serr.IsError() {
+		util.LogError("Cloudtacts", "function - Failed to configure token service.", serr)
+	}
+
+	// Register HTTP functions with the Functions Framework
+	for _, fn := range contactFunctions {
+		target := cfgx.ValueOfWithDefault(fn.KeyId, fn.Name)
+		logIt(fmt.Sprintf("Binding function to target '%v'->'%v'.", target, fn.logName))
+		functions.HTTP(target, fn.handler)
+	}
+	cfg = cfgx
+}
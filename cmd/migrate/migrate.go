@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"Cloudtacts/pkg/auth"
+	"Cloudtacts/pkg/config"
+	"Cloudtacts/pkg/migrate"
+	"Cloudtacts/pkg/model"
+	"Cloudtacts/pkg/util"
+)
+
+// status logs the status of the user database's schema migrations.
+func status(ctx context.Context, migrator *migrate.Migrator) model.ServiceError {
+	status, serr := migrator.Status(ctx)
+	if serr.IsError() {
+		return serr
+	}
+
+	for _, st := range status {
+		switch {
+		case !st.IsApplied():
+			logIt(fmt.Sprintf("%v: pending", st.Migration))
+		case len(st.Up) == 0:
+			logIt(fmt.Sprintf("%v: applied on %v (unknown migration)", st.Migration, st.Applied.Format(model.FMT_DATETIME_GO)))
+		default:
+			logIt(fmt.Sprintf("%v: applied on %v", st.Migration, st.Applied.Format(model.FMT_DATETIME_GO)))
+		}
+	}
+
+	return model.NoError
+}
+
+// target returns the configured schema version to migrate to, or the given
+// default version if none is configured.
+func target(cfg *config.Config, defVersion int) (int, error) {
+	if !cfg.AssignedValue(model.KEY_MIGRATE_TARGET) {
+		return defVersion, nil
+	}
+
+	return cfg.Int(model.KEY_MIGRATE_TARGET)
+}
+
+// Migrates the schema of the user database up or down, or logs its status,
+// as given by the --migrateCommand and --migrateTarget parameters.
+func main() {
+	var cfg *config.Config
+	var err error
+
+	if cfg, err = config.ContextConfig(); err != nil {
+		util.LogError("Migrate", "Failed to parse configuration.", err)
+	}
+	cfg.UsageOnHelp("migrate", "Migrates the schema of the user database up or down, or logs its status, as given by the --migrateCommand and --migrateTarget parameters.")
+	if err = cfg.Validate(cfg.CommandParameters("migrate")...); err != nil {
+		util.LogError("Migrate", "Invalid configuration.", err)
+	}
+
+	dialect, serr := auth.DialectOf(cfg)
+	if serr.IsError() {
+		util.LogError("Migrate", serr.Message, serr.Cause)
+	}
+
+	db, serr := auth.OpenDB(cfg, cfg.ValueOf(model.KEY_USERDB_HOST_IP), cfg.ValueOf(model.KEY_USERDB_PORT_NUM), cfg.ValueOf(model.KEY_USERDB_DATABASE))
+	if serr.IsError() {
+		util.LogError("Migrate", serr.Message, serr.Cause)
+	}
+	defer db.Close()
+
+	migrator, serr := migrate.NewMigrator(db, dialect)
+	if serr.IsError() {
+		util.LogError("Migrate", serr.Message, serr.Cause)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	command := cfg.ValueOfWithDefault(model.KEY_MIGRATE_COMMAND, "status")
+	switch command {
+	case "up":
+		var version int
+		if version, err = target(cfg, migrator.Latest()); err == nil {
+			var done []migrate.Migration
+			done, serr = migrator.Up(ctx, version)
+			logIt(fmt.Sprintf("Applied %d migration(s).", len(done)))
+		}
+	case "down":
+		var current, version int
+		if current, serr = migrator.Version(ctx); !serr.IsError() {
+			if version, err = target(cfg, max(current-1, 0)); err == nil {
+				var done []migrate.Migration
+				done, serr = migrator.Down(ctx, version)
+				logIt(fmt.Sprintf("Reverted %d migration(s).", len(done)))
+			}
+		}
+	case "status":
+	default:
+		err = fmt.Errorf("unknown migration command: %v", command)
+	}
+
+	if err != nil {
+		util.LogError("Migrate", model.DbMigrationError.Message, err)
+	}
+	if serr.IsError() {
+		util.LogError("Migrate", serr.Message, serr.Cause)
+	}
+	if serr = status(ctx, migrator); serr.IsError() {
+		util.LogError("Migrate", serr.Message, serr.Cause)
+	}
+}
+
+func logIt(message string) {
+	util.LogIt("Migrate", message)
+}
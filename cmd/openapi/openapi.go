@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"Cloudtacts/pkg/api"
+	"Cloudtacts/pkg/config"
+	"Cloudtacts/pkg/model"
+	"Cloudtacts/pkg/util"
+)
+
+// Generates the OpenAPI document of the user auth and contacts APIs, writing
+// it to the --output file, or to standard output if none is given.
+func main() {
+	var cfg *config.Config
+	var err error
+
+	if cfg, err = config.ContextConfig(); err != nil {
+		util.LogError("OpenAPI", "Failed to parse configuration.", err)
+	}
+	cfg.UsageOnHelp("openapi", "Writes the OpenAPI document of the user auth and contacts APIs to the --output file, or to standard output if none is given.")
+
+	spec, err := api.OpenAPI()
+	if err != nil {
+		util.LogError("OpenAPI", "Failed to generate OpenAPI document.", err)
+	}
+
+	if !cfg.AssignedValue(model.KEY_CLIENT_OUTPUT_FILE) {
+		os.Stdout.Write(spec)
+		return
+	}
+
+	output := cfg.ValueOf(model.KEY_CLIENT_OUTPUT_FILE)
+	if err = os.WriteFile(output, spec, 0644); err != nil {
+		util.LogError("OpenAPI", model.ClientOutputError.Message, err)
+	}
+	util.LogIt("OpenAPI", fmt.Sprintf("Wrote OpenAPI document to %v.", output))
+}
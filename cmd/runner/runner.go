@@ -20,7 +20,7 @@ func main() {
 		util.LogError("CloudtactsRunner", "Failed to parse configuration.", err)
 	}
 
-	port := cfg.ValueOfWithDefault(model.KEY_AUTH_FUNCTION_PORT, "8088")
+	port := cfg.ValueOfWithDefault(model.KEY_AUTH_FUNCTION_PORT, "8888")
 
 	if err := funcframework.RegisterHTTPFunctionContext(cfg.Context(), "/", nondeclarative.HTTP); err != nil {
 		util.LogError("CloudtactsRunner", "Failed to register function context.", err)
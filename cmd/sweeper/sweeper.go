@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"Cloudtacts/pkg/auth"
+	"Cloudtacts/pkg/config"
+	"Cloudtacts/pkg/model"
+	"Cloudtacts/pkg/util"
+)
+
+// sweep removes unvalidated users whose validation window has passed, until
+// the given context is done.
+func sweep(ctx context.Context, cfg *config.Config, pool *auth.DBPool, dryRun bool) model.ServiceError {
+	uc, serr := pool.Client()
+	if serr.IsError() {
+		return serr
+	}
+	defer uc.Close()
+
+	window := auth.ValidationWindow(cfg)
+	removed, serr := auth.SweepUnvalidatedUsers(ctx, cfg, uc, window, dryRun)
+	if !serr.IsError() {
+		if dryRun {
+			logIt(fmt.Sprintf("Dry run: %d unvalidated user(s) older than %v to remove.", len(removed), window))
+		} else {
+			logIt(fmt.Sprintf("Removed %d unvalidated user(s) older than %v.", len(removed), window))
+		}
+	}
+
+	return serr
+}
+
+func main() {
+	var cfg *config.Config
+	var err error
+
+	if cfg, err = config.ContextConfig(); err != nil {
+		util.LogError("Sweeper", "Failed to parse configuration.", err)
+	}
+	cfg.UsageOnHelp("sweeper", "Removes the unvalidated users whose validation window has passed, once or at the --sweeperInterval.")
+	if err = cfg.Validate(cfg.CommandParameters("sweeper")...); err != nil {
+		util.LogError("Sweeper", "Invalid configuration.", err)
+	}
+
+	dryRun, _ := cfg.Bool(model.KEY_SWEEPER_DRY_RUN)
+	interval, _ := cfg.Duration(model.KEY_SWEEPER_INTERVAL)
+
+	pool := auth.NewDBPool(cfg, cfg.ValueOf(model.KEY_USERDB_HOST_IP), cfg.ValueOf(model.KEY_USERDB_PORT_NUM), cfg.ValueOf(model.KEY_USERDB_DATABASE))
+	defer pool.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if interval == 0 {
+		if serr := sweep(ctx, cfg, pool, dryRun); serr.IsError() {
+			util.LogError("Sweeper", serr.Message, serr.Cause)
+		}
+		return
+	}
+
+	logIt(fmt.Sprintf("Sweeping unvalidated users every %v.", interval))
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if serr := sweep(ctx, cfg, pool, dryRun); serr.IsError() {
+			logIt(fmt.Sprintf("Sweep failed: %v", serr))
+		}
+
+		select {
+		case <-ctx.Done():
+			logIt("Stopping sweeper.")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func logIt(message string) {
+	util.LogIt("Sweeper", message)
+}
@@ -1,18 +1,6 @@
-\! echo 'Creating Cloudtacts user database and table...'
+\! echo 'Creating Cloudtacts user database...'
 
 CREATE DATABASE IF NOT EXISTS cloudtacts;
 
-CREATE TABLE IF NOT EXISTS cloudtacts.user
-(
-	ctuser	VARCHAR(20) NOT NULL,
-	ctpass	CHAR(66) NOT NULL,
-	ctprof	VARCHAR(20) NOT NULL,
-	uemail	VARCHAR(50) NOT NULL,
-	ctppic	VARCHAR(52),
-	atoken	CHAR(36),
-	llogin	DATETIME,
-	uvalid	DATETIME,
-	CONSTRAINT PRIMARY KEY (ctuser, ctprof, uemail)
-) ENGINE=InnoDB;
-
-COMMIT;
+-- The user database's tables are created and upgraded by its schema
+-- migrations (see pkg/migrate), e.g.: make migrate
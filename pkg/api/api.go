@@ -0,0 +1,108 @@
+// Package api provides the response bodies of the Cloudtacts functions,
+// including the problem details of errors, shared by the functions and their
+// clients.
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"Cloudtacts/pkg/model"
+)
+
+const (
+	RESULT_ADDED      = "added"
+	RESULT_DELETED    = "deleted"
+	RESULT_UPDATED    = "updated"
+	RESULT_VALIDATED  = "validated"
+	RESULT_LOGGED     = "logged"
+	RESULT_REFRESHED  = "refreshed"
+	RESULT_LOGGED_OUT = "logged out"
+)
+
+// UserView is the response body of a user info query. It excludes the user's
+// password and tokens.
+type UserView struct {
+	UserId string `json:"uid"`
+	CtUser string `json:"username"`
+	CtProf string `json:"profile"`
+	UEmail string `json:"email"`
+	CtPpic string `json:"imageLoc"`
+	LLogin string `json:"lastOn"`
+	UValid string `json:"validatedOn"`
+}
+
+// MutationResult is the response body of a change to a user's information or
+// sessions. Sessions is the number of sessions ended by a logout.
+type MutationResult struct {
+	CtUser   string `json:"username"`
+	CtProf   string `json:"profile"`
+	Result   string `json:"result"`
+	Sessions *int   `json:"sessions,omitempty"`
+}
+
+// LoginResult is the response body of a user login. The user's access and
+// refresh tokens are returned in the response headers.
+type LoginResult struct {
+	UserId string `json:"uid"`
+	CtUser string `json:"username"`
+	CtProf string `json:"profile"`
+	LLogin string `json:"lastOn"`
+	Result string `json:"result"`
+}
+
+// ContactResult is the response body of a contact's deletion.
+type ContactResult struct {
+	CtId   string `json:"ctid"`
+	Result string `json:"result"`
+}
+
+// NewUserView returns the view of the referenced user.
+func NewUserView(user *model.User) *UserView {
+	return &UserView{user.UserId, user.CtUser, user.CtProf, user.UEmail, user.CtPpic, user.LLogin, user.UValid}
+}
+
+// NewMutationResult returns the given result of a change to the referenced
+// user.
+func NewMutationResult(user *model.User, result string) *MutationResult {
+	return &MutationResult{CtUser: user.CtUser, CtProf: user.CtProf, Result: result}
+}
+
+// NewLogoutResult returns the result of a logout of the referenced user which
+// ended the given number of sessions.
+func NewLogoutResult(user *model.User, sessions int) *MutationResult {
+	result := NewMutationResult(user, RESULT_LOGGED_OUT)
+	result.Sessions = &sessions
+
+	return result
+}
+
+// NewLoginResult returns the result of a login of the referenced user.
+func NewLoginResult(user *model.User) *LoginResult {
+	return &LoginResult{user.UserId, user.CtUser, user.CtProf, user.LLogin, RESULT_LOGGED}
+}
+
+// ResponseOf returns an empty response body of the named function, as named by
+// its default target name, for decoding.
+func ResponseOf(function string) (any, error) {
+	fn := FunctionOf(function)
+	if fn == nil {
+		return nil, fmt.Errorf("unknown function '%v'", function)
+	}
+
+	return reflect.New(reflect.TypeOf(fn.Response)).Interface(), nil
+}
+
+// DecodeResponse decodes the given response body of the named function.
+func DecodeResponse(function string, body []byte) (any, error) {
+	resp, err := ResponseOf(function)
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(body, resp); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
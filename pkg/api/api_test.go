@@ -0,0 +1,72 @@
+package api
+
+import (
+	"encoding/json"
+	"testing"
+
+	"Cloudtacts/pkg/model"
+)
+
+func TestUserViewEscaping(t *testing.T) {
+	user := &model.User{
+		CtUser: "pendracon1",
+		CtPass: "H:secret",
+		CtProf: `Home', 'result': 'x"}`,
+		UEmail: "pendracon1@gmail.com",
+		AToken: "token",
+	}
+
+	bbuff, err := json.Marshal(NewUserView(user))
+	if err != nil {
+		t.Fatalf("Error marshalling user view: %v", err)
+	}
+
+	resp, err := DecodeResponse("GetUser", bbuff)
+	if err != nil {
+		t.Fatalf("Error decoding user view %s: %v", bbuff, err)
+	}
+	view := resp.(*UserView)
+	if view.CtProf != user.CtProf || view.UEmail != user.UEmail {
+		t.Errorf("Expected profile %q and e-mail %q, got: %+v", user.CtProf, user.UEmail, view)
+	}
+
+	var fields map[string]any
+	json.Unmarshal(bbuff, &fields)
+	for _, key := range []string{"ctpass", "atoken", "result"} {
+		if _, ok := fields[key]; ok {
+			t.Errorf("User view includes field %v: %s", key, bbuff)
+		}
+	}
+}
+
+func TestDecodeResponse(t *testing.T) {
+	user := &model.User{CtUser: "pendracon1", CtProf: "Pendracon1", LLogin: "20240101120000"}
+
+	tests := []struct {
+		function string
+		body     any
+	}{
+		{"AddUser", NewMutationResult(user, RESULT_ADDED)},
+		{"Logout", NewLogoutResult(user, 0)},
+		{"LoginUser", NewLoginResult(user)},
+	}
+	for _, test := range tests {
+		t.Run(test.function, func(t *testing.T) {
+			bbuff, _ := json.Marshal(test.body)
+			resp, err := DecodeResponse(test.function, bbuff)
+			if err != nil {
+				t.Fatalf("Error decoding %s: %v", bbuff, err)
+			}
+			if rbuff, _ := json.Marshal(resp); string(rbuff) != string(bbuff) {
+				t.Errorf("Expected %s, got: %s", bbuff, rbuff)
+			}
+		})
+	}
+
+	if _, err := DecodeResponse("Unknown", []byte("{}")); err == nil {
+		t.Error("Decoded response of unknown function.")
+	}
+	if _, err := DecodeResponse("GetUser", []byte("{'username': 'x'}")); err == nil {
+		t.Error("Decoded single-quoted response.")
+	}
+}
@@ -0,0 +1,155 @@
+package api
+
+import (
+	"net/http"
+
+	"Cloudtacts/pkg/model"
+)
+
+const (
+	FUNCTION_NAME_HEADER = "CT-Function-Name"
+	ERROR_CODE_HEADER    = "CT-Error-Code"
+	USER_TOKEN_HEADER    = "CT-User-Token"
+	REFRESH_TOKEN_HEADER = "CT-Refresh-Token"
+	LOGOUT_ALL_HEADER    = "CT-Logout-All"
+
+	// Default target name of the REST API (see Routes)
+	AUTH_API_NAME = "AuthApi"
+)
+
+// Function describes a user auth or contacts function target. The function's
+// target name is configured by KeyId, defaulting to Name. Its request body is
+// a user list, or a single user when requested through a REST route, unless
+// it has another Request type.
+type Function struct {
+	Name       string
+	KeyId      string
+	Summary    string
+	Status     int      // success status of the function target
+	Request    any      // zero value of the request body type, if not a user list
+	Response   any      // zero value of the response body type
+	InHeaders  []string // request headers, besides CT-Function-Name
+	OutHeaders []string // success response headers
+	Linked     bool     // true if the function accepts GET requests of links
+}
+
+// Route is a REST API route of a user auth function. Path segments in braces
+// are parameters.
+type Route struct {
+	Method   string
+	Path     string
+	Function *Function
+	Status   int
+	Query    []string // URL query parameters
+}
+
+var (
+	LoginUser = &Function{
+		Name: "LoginUser", KeyId: model.KEY_AUTH_FUNCTION_LOG,
+		Summary: "Logs in a user, starting a new session.",
+		Status:  http.StatusOK, Response: LoginResult{},
+		OutHeaders: []string{USER_TOKEN_HEADER, REFRESH_TOKEN_HEADER},
+	}
+	GetUser = &Function{
+		Name: "GetUser", KeyId: model.KEY_AUTH_FUNCTION_GET,
+		Summary: "Returns a user's information.",
+		Status:  http.StatusOK, Response: UserView{},
+	}
+	AddUser = &Function{
+		Name: "AddUser", KeyId: model.KEY_AUTH_FUNCTION_ADD,
+		Summary: "Registers a new user and sends a confirmation e-mail.",
+		Status:  http.StatusCreated, Response: MutationResult{},
+	}
+	DeleteUser = &Function{
+		Name: "DeleteUser", KeyId: model.KEY_AUTH_FUNCTION_DEL,
+		Summary: "Deletes a user and the user's sessions.",
+		Status:  http.StatusOK, Response: MutationResult{},
+		InHeaders: []string{USER_TOKEN_HEADER},
+	}
+	UpdateUser = &Function{
+		Name: "UpdateUser", KeyId: model.KEY_AUTH_FUNCTION_UPD,
+		Summary: "Updates a user's password or profile image.",
+		Status:  http.StatusOK, Response: MutationResult{},
+		InHeaders: []string{USER_TOKEN_HEADER},
+	}
+	ValidateUser = &Function{
+		Name: "ValidateUser", KeyId: model.KEY_AUTH_FUNCTION_VAL,
+		Summary: "Confirms a new user's registration with its confirmation token.",
+		Status:  http.StatusOK, Response: MutationResult{},
+		InHeaders: []string{USER_TOKEN_HEADER}, Linked: true,
+	}
+	RefreshToken = &Function{
+		Name: "RefreshToken", KeyId: model.KEY_AUTH_FUNCTION_REF,
+		Summary: "Exchanges a session's refresh token for new access and refresh tokens.",
+		Status:  http.StatusOK, Response: MutationResult{},
+		InHeaders:  []string{REFRESH_TOKEN_HEADER},
+		OutHeaders: []string{USER_TOKEN_HEADER, REFRESH_TOKEN_HEADER},
+	}
+	Logout = &Function{
+		Name: "Logout", KeyId: model.KEY_AUTH_FUNCTION_OUT,
+		Summary: "Ends the access token's session, or all of the user's sessions.",
+		Status:  http.StatusOK, Response: MutationResult{},
+		InHeaders: []string{USER_TOKEN_HEADER, LOGOUT_ALL_HEADER},
+	}
+
+	ListContacts = &Function{
+		Name: "ListContacts", KeyId: model.KEY_CONTACTS_FUNCTION_LIST,
+		Summary: "Returns the contacts of a user's contact list.",
+		Status:  http.StatusOK, Request: model.ContactList{}, Response: model.ContactList{},
+		InHeaders: []string{USER_TOKEN_HEADER},
+	}
+	GetContact = &Function{
+		Name: "GetContact", KeyId: model.KEY_CONTACTS_FUNCTION_GET,
+		Summary: "Returns the contact of a user's contact list with the first contact's identifier.",
+		Status:  http.StatusOK, Request: model.ContactList{}, Response: model.ContactList{},
+		InHeaders: []string{USER_TOKEN_HEADER},
+	}
+	AddContact = &Function{
+		Name: "AddContact", KeyId: model.KEY_CONTACTS_FUNCTION_ADD,
+		Summary: "Adds the first contact to a user's contact list.",
+		Status:  http.StatusCreated, Request: model.ContactList{}, Response: model.ContactList{},
+		InHeaders: []string{USER_TOKEN_HEADER},
+	}
+	UpdateContact = &Function{
+		Name: "UpdateContact", KeyId: model.KEY_CONTACTS_FUNCTION_UPD,
+		Summary: "Updates the first contact of a user's contact list.",
+		Status:  http.StatusOK, Request: model.ContactList{}, Response: model.ContactList{},
+		InHeaders: []string{USER_TOKEN_HEADER},
+	}
+	DeleteContact = &Function{
+		Name: "DeleteContact", KeyId: model.KEY_CONTACTS_FUNCTION_DEL,
+		Summary: "Deletes the contact of a user's contact list with the first contact's identifier.",
+		Status:  http.StatusOK, Request: model.ContactList{}, Response: ContactResult{},
+		InHeaders: []string{USER_TOKEN_HEADER},
+	}
+
+	// The user auth function targets
+	Functions = []*Function{LoginUser, GetUser, AddUser, DeleteUser, UpdateUser, ValidateUser, RefreshToken, Logout}
+
+	// The contacts function targets
+	ContactFunctions = []*Function{ListContacts, GetContact, AddContact, UpdateContact, DeleteContact}
+
+	// The REST API routes, served by the AuthApi target
+	Routes = []Route{
+		{http.MethodPost, "/v1/users", AddUser, http.StatusCreated, nil},
+		{http.MethodGet, "/v1/users/{ctuser}/{ctprof}", GetUser, http.StatusOK, []string{"uemail"}},
+		{http.MethodPatch, "/v1/users/{ctuser}/{ctprof}", UpdateUser, http.StatusOK, []string{"uemail"}},
+		{http.MethodDelete, "/v1/users/{ctuser}/{ctprof}", DeleteUser, http.StatusOK, []string{"uemail"}},
+		{http.MethodGet, "/v1/users/{ctuser}/{ctprof}/validate", ValidateUser, http.StatusOK, []string{"uemail", "token"}},
+		{http.MethodPost, "/v1/sessions", LoginUser, http.StatusCreated, nil},
+		{http.MethodDelete, "/v1/sessions", Logout, http.StatusOK, []string{"ctuser", "ctprof", "uemail"}},
+		{http.MethodPost, "/v1/sessions/refresh", RefreshToken, http.StatusOK, nil},
+	}
+)
+
+// FunctionOf returns the function with the given default target name, or nil
+// if there's none.
+func FunctionOf(name string) *Function {
+	for _, fn := range Functions {
+		if fn.Name == name {
+			return fn
+		}
+	}
+
+	return nil
+}
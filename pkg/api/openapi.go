@@ -0,0 +1,271 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"Cloudtacts/pkg/model"
+)
+
+const (
+	OPENAPI_VERSION = "3.0.3"
+	OPENAPI_TITLE   = "Cloudtacts User Auth and Contacts API"
+	API_VERSION     = "1.0.0"
+
+	schemaRef = "#/components/schemas/"
+)
+
+// object is a JSON object of the OpenAPI document.
+type object map[string]any
+
+// OpenAPI returns the OpenAPI 3 document of the user auth and contacts
+// function targets, and of the REST API routes, formatted as indented JSON.
+func OpenAPI() ([]byte, error) {
+	paths := object{}
+
+	for _, fn := range append(append([]*Function{}, Functions...), ContactFunctions...) {
+		ops := object{"post": functionOperation(fn)}
+		if fn.Linked {
+			ops["get"] = linkOperation(fn)
+		}
+		paths["/"+fn.Name] = ops
+	}
+
+	for _, route := range Routes {
+		ops, ok := paths[route.Path].(object)
+		if !ok {
+			ops = object{}
+			paths[route.Path] = ops
+		}
+		ops[strings.ToLower(route.Method)] = routeOperation(route)
+	}
+
+	doc := object{
+		"openapi": OPENAPI_VERSION,
+		"info": object{
+			"title":   OPENAPI_TITLE,
+			"version": API_VERSION,
+			"description": "Function targets are served at their configured target names, " +
+				"and the REST API routes by the " + AUTH_API_NAME + " target.",
+		},
+		"paths": paths,
+		"components": object{
+			"schemas": schemas(),
+			"headers": object{
+				USER_TOKEN_HEADER:    header("The user's signed access token (JWT)."),
+				REFRESH_TOKEN_HEADER: header("The session's single use refresh token."),
+			},
+			"responses": object{
+				"Problem": object{
+					"description": "Error problem details (RFC 7807).",
+					"content": object{
+						PROBLEM_CONTENT_TYPE: object{"schema": ref("Problem")},
+					},
+				},
+			},
+		},
+	}
+
+	bbuff, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append(bbuff, '\n'), nil
+}
+
+// functionOperation returns the operation of the function's target.
+func functionOperation(fn *Function) object {
+	params := []any{object{
+		"name":        FUNCTION_NAME_HEADER,
+		"in":          "header",
+		"required":    true,
+		"description": "The function's configured target name.",
+		"schema":      object{"type": "string", "example": fn.Name},
+	}}
+	for _, name := range fn.InHeaders {
+		params = append(params, headerParam(name))
+	}
+
+	request := "UserList"
+	if fn.Request != nil {
+		request = reflect.TypeOf(fn.Request).Name()
+	}
+
+	op := operation(fn, fn.Name, fn.Status, params)
+	op["requestBody"] = object{
+		"required": true,
+		"content":  object{"application/json": object{"schema": ref(request)}},
+	}
+
+	return op
+}
+
+// linkOperation returns the operation of GET requests of the function's
+// links, e.g. of registration confirmation links.
+func linkOperation(fn *Function) object {
+	params := []any{}
+	for _, name := range []string{"ctuser", "ctprof", "uemail", "token"} {
+		params = append(params, queryParam(name, true))
+	}
+
+	return operation(fn, fn.Name+"Link", fn.Status, params)
+}
+
+// routeOperation returns the operation of the REST API route.
+func routeOperation(route Route) object {
+	params := []any{}
+	for _, seg := range strings.Split(route.Path, "/") {
+		if strings.HasPrefix(seg, "{") {
+			params = append(params, object{
+				"name":     strings.Trim(seg, "{}"),
+				"in":       "path",
+				"required": true,
+				"schema":   object{"type": "string"},
+			})
+		}
+	}
+	for _, name := range route.Query {
+		params = append(params, queryParam(name, route.Function == ValidateUser))
+	}
+	for _, name := range route.Function.InHeaders {
+		if route.Method == http.MethodGet && route.Function.Linked && name == USER_TOKEN_HEADER {
+			// the token is given by the link's query
+			continue
+		}
+		params = append(params, headerParam(name))
+	}
+
+	opId := strings.ToLower(route.Method) + route.Function.Name
+	op := operation(route.Function, opId, route.Status, params)
+	if route.Method != http.MethodGet {
+		op["requestBody"] = object{
+			"required": route.Method == http.MethodPost || route.Method == http.MethodPatch,
+			"content":  object{"application/json": object{"schema": ref("User")}},
+		}
+	}
+
+	return op
+}
+
+// operation returns the function's operation with the given identifier,
+// success status, and parameters.
+func operation(fn *Function, opId string, status int, params []any) object {
+	headers := object{}
+	for _, name := range fn.OutHeaders {
+		headers[name] = object{"$ref": "#/components/headers/" + name}
+	}
+
+	success := object{
+		"description": http.StatusText(status),
+		"content": object{
+			"application/json": object{"schema": ref(reflect.TypeOf(fn.Response).Name())},
+		},
+	}
+	if len(headers) > 0 {
+		success["headers"] = headers
+	}
+
+	return object{
+		"operationId": opId,
+		"summary":     fn.Summary,
+		"parameters":  params,
+		"responses": object{
+			fmt.Sprint(status): success,
+			"default":          object{"$ref": "#/components/responses/Problem"},
+		},
+	}
+}
+
+// schemas returns the schemas of the request and response bodies, and of the
+// service error codes.
+func schemas() object {
+	schemas := object{}
+	for _, body := range []any{model.UserList{}, UserView{}, MutationResult{}, LoginResult{},
+		model.ContactList{}, ContactResult{}, Problem{}} {
+		addSchema(schemas, reflect.TypeOf(body))
+	}
+	schemas["Problem"].(object)["properties"].(object)["code"] = ref("ErrorCode")
+
+	codes := []string{}
+	desc := []string{"Service error codes:"}
+	for _, serr := range model.ServiceErrors {
+		codes = append(codes, serr.Code)
+		if status, ok := model.HttpErrorStatus[serr.Code]; ok {
+			desc = append(desc, fmt.Sprintf("- %v (%d): %v", serr.Code, status, serr.Message))
+		} else {
+			desc = append(desc, fmt.Sprintf("- %v (client): %v", serr.Code, serr.Message))
+		}
+	}
+	schemas["ErrorCode"] = object{
+		"type":        "string",
+		"enum":        codes,
+		"description": strings.Join(desc, "\n"),
+	}
+
+	return schemas
+}
+
+// addSchema adds the schema of the given struct type, and of the struct
+// types it refers to, to the given schemas.
+func addSchema(schemas object, t reflect.Type) {
+	props := object{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if len(name) == 0 || name == "-" {
+			continue
+		}
+		props[name] = typeSchema(schemas, field.Type)
+	}
+
+	schemas[t.Name()] = object{"type": "object", "properties": props}
+}
+
+// typeSchema returns the schema of the given field type.
+func typeSchema(schemas object, t reflect.Type) object {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return typeSchema(schemas, t.Elem())
+	case reflect.Int:
+		return object{"type": "integer"}
+	case reflect.Bool:
+		return object{"type": "boolean"}
+	case reflect.Slice:
+		return object{"type": "array", "items": typeSchema(schemas, t.Elem())}
+	case reflect.Struct:
+		addSchema(schemas, t)
+		return ref(t.Name())
+	}
+
+	return object{"type": "string"}
+}
+
+func ref(name string) object {
+	return object{"$ref": schemaRef + name}
+}
+
+func header(desc string) object {
+	return object{"description": desc, "schema": object{"type": "string"}}
+}
+
+func headerParam(name string) object {
+	return object{
+		"name":     name,
+		"in":       "header",
+		"required": name != LOGOUT_ALL_HEADER,
+		"schema":   object{"type": "string"},
+	}
+}
+
+func queryParam(name string, required bool) object {
+	return object{
+		"name":     name,
+		"in":       "query",
+		"required": required,
+		"schema":   object{"type": "string"},
+	}
+}
@@ -0,0 +1,65 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"testing"
+
+	"Cloudtacts/pkg/model"
+)
+
+// The committed OpenAPI document
+const OPENAPI_SPEC_FILE = "../../api/openapi.json"
+
+func TestOpenAPISpecDrift(t *testing.T) {
+	spec, err := OpenAPI()
+	if err != nil {
+		t.Fatalf("Error generating OpenAPI document: %v", err)
+	}
+
+	committed, err := os.ReadFile(OPENAPI_SPEC_FILE)
+	if err != nil {
+		t.Fatalf("Error reading %v: %v", OPENAPI_SPEC_FILE, err)
+	}
+	if !bytes.Equal(spec, bytes.ReplaceAll(committed, []byte("\r\n"), []byte("\n"))) {
+		t.Errorf("%v is out of date, regenerate it with: make openapi", OPENAPI_SPEC_FILE)
+	}
+}
+
+func TestOpenAPISpecCoverage(t *testing.T) {
+	spec, _ := OpenAPI()
+
+	var doc struct {
+		Paths      map[string]map[string]any
+		Components struct {
+			Schemas map[string]struct {
+				Enum []string
+			}
+		}
+	}
+	if err := json.Unmarshal(spec, &doc); err != nil {
+		t.Fatalf("Error decoding OpenAPI document: %v", err)
+	}
+
+	for _, fn := range append(append([]*Function{}, Functions...), ContactFunctions...) {
+		if _, ok := doc.Paths["/"+fn.Name]["post"]; !ok {
+			t.Errorf("Function %v not documented.", fn.Name)
+		}
+	}
+	for _, route := range Routes {
+		if len(doc.Paths[route.Path]) == 0 {
+			t.Errorf("Route %v %v not documented.", route.Method, route.Path)
+		}
+	}
+
+	codes := map[string]bool{}
+	for _, code := range doc.Components.Schemas["ErrorCode"].Enum {
+		codes[code] = true
+	}
+	for code := range model.HttpErrorStatus {
+		if !codes[code] {
+			t.Errorf("Error code %v not documented.", code)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"Cloudtacts/pkg/model"
+)
+
+const (
+	PROBLEM_CONTENT_TYPE = "application/problem+json"
+	PROBLEM_TYPE_PREFIX  = "urn:cloudtacts:error:"
+
+	REQUEST_ID_HEADER = "X-Request-Id"
+)
+
+// Problem is the response body of a failed request, as "problem details"
+// (RFC 7807) extended with the ServiceError code and the request identifier.
+// Cause, the underlying error, is only returned in test mode. Accounts are the
+// accounts to choose from when a login is ambiguous (AmbiguousLoginError).
+type Problem struct {
+	Type      string    `json:"type"`
+	Title     string    `json:"title"`
+	Status    int       `json:"status"`
+	Detail    string    `json:"detail,omitempty"`
+	Code      string    `json:"code"`
+	RequestId string    `json:"requestId"`
+	Cause     string    `json:"cause,omitempty"`
+	Accounts  []Account `json:"accounts,omitempty"`
+}
+
+// Account identifies one of the accounts matching an ambiguous login. The
+// login is retried with the account's user ID, or its complete key.
+type Account struct {
+	UserId string `json:"uid"`
+	CtUser string `json:"username"`
+	CtProf string `json:"profile"`
+	UEmail string `json:"email"`
+}
+
+// NewProblem returns the problem details of the given error of the
+// identified request. The error's cause is included only if withCause is
+// true.
+func NewProblem(serr model.ServiceError, detail, requestId string, withCause bool) *Problem {
+	status, ok := model.HttpErrorStatus[serr.Code]
+	if !ok {
+		status = http.StatusInternalServerError
+	}
+
+	problem := &Problem{
+		Type:      PROBLEM_TYPE_PREFIX + serr.Code,
+		Title:     serr.Message,
+		Status:    status,
+		Detail:    detail,
+		Code:      serr.Code,
+		RequestId: requestId,
+	}
+	if withCause && serr.Cause != nil {
+		problem.Cause = serr.Cause.Error()
+	}
+
+	var ambiguous *model.AmbiguousLogin
+	if errors.As(serr.Cause, &ambiguous) {
+		for _, user := range ambiguous.Users {
+			problem.Accounts = append(problem.Accounts, Account{user.UserId, user.CtUser, user.CtProf, user.UEmail})
+		}
+	}
+
+	return problem
+}
+
+// RequestId returns the identifier of the request, given by its X-Request-Id
+// header, or a new identifier if it has none.
+func RequestId(r *http.Request) string {
+	if id := r.Header.Get(REQUEST_ID_HEADER); len(id) > 0 {
+		return id
+	}
+
+	return uuid.New().String()
+}
+
+// WriteProblem writes the given problem details as the response.
+func WriteProblem(w http.ResponseWriter, problem *Problem) error {
+	bbuff, err := json.Marshal(problem)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", PROBLEM_CONTENT_TYPE)
+	w.Header().Set(REQUEST_ID_HEADER, problem.RequestId)
+	w.WriteHeader(problem.Status)
+	_, err = w.Write(bbuff)
+
+	return err
+}
+
+// Error returns the problem as an error message.
+func (p *Problem) Error() string {
+	if len(p.Detail) == 0 {
+		return p.Code + ": " + p.Title
+	}
+
+	return p.Code + ": " + p.Title + "\n" + p.Detail
+}
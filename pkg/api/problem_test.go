@@ -0,0 +1,74 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"Cloudtacts/pkg/model"
+)
+
+func TestNewProblem(t *testing.T) {
+	serr := model.DbPKeyError.WithCause(errors.New("duplicate key: user 'pendracon1/Pendracon1'"))
+
+	problem := NewProblem(serr, "Error adding new user: pendracon1/Pendracon1.", "req1", false)
+	if problem.Status != model.HttpErrorStatus[serr.Code] || problem.Code != serr.Code || problem.Title != serr.Message {
+		t.Errorf("Problem doesn't match error %v: %+v", serr, problem)
+	}
+	if problem.Type != PROBLEM_TYPE_PREFIX+serr.Code {
+		t.Errorf("Expected type %v, got: %v", PROBLEM_TYPE_PREFIX+serr.Code, problem.Type)
+	}
+	if len(problem.Cause) > 0 {
+		t.Errorf("Problem exposes cause: %v", problem.Cause)
+	}
+
+	if problem = NewProblem(serr, "", "req1", true); problem.Cause != serr.Cause.Error() {
+		t.Errorf("Expected cause %q, got: %q", serr.Cause, problem.Cause)
+	}
+
+	if problem = NewProblem(model.ClientError, "", "req1", false); problem.Status != http.StatusInternalServerError {
+		t.Errorf("Expected status %d of unmapped error, got: %d", http.StatusInternalServerError, problem.Status)
+	}
+
+	ambiguous := &model.AmbiguousLogin{Login: "pendracon1", Users: []model.User{
+		{UserId: "uid1", CtUser: "pendracon1", CtProf: "Pendracon1", UEmail: "pendracon1@example.com", CtPass: "H:0"},
+		{UserId: "uid2", CtUser: "pendracon1", CtProf: "Pendracon2", UEmail: "pendracon2@example.com", CtPass: "H:0"},
+	}}
+	problem = NewProblem(model.AmbiguousLoginError.WithCause(ambiguous), "", "req1", false)
+	if len(problem.Accounts) != 2 || problem.Accounts[1] != (Account{"uid2", "pendracon1", "Pendracon2", "pendracon2@example.com"}) {
+		t.Errorf("Expected the ambiguous login's accounts, got: %+v", problem.Accounts)
+	}
+}
+
+func TestWriteProblem(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/GetUser", nil)
+	r.Header.Set(REQUEST_ID_HEADER, "req1")
+	if id := RequestId(r); id != "req1" {
+		t.Errorf("Expected request id req1, got: %v", id)
+	}
+	if id := RequestId(httptest.NewRequest(http.MethodPost, "/GetUser", nil)); len(id) == 0 {
+		t.Error("No request id generated.")
+	}
+
+	w := httptest.NewRecorder()
+	if err := WriteProblem(w, NewProblem(model.InvalidTokenError, "", RequestId(r), false)); err != nil {
+		t.Fatalf("Error writing problem: %v", err)
+	}
+
+	if w.Code != model.HttpErrorStatus[model.InvalidTokenError.Code] {
+		t.Errorf("Expected status %d, got: %d", model.HttpErrorStatus[model.InvalidTokenError.Code], w.Code)
+	}
+	if ctype := w.Header().Get("Content-Type"); ctype != PROBLEM_CONTENT_TYPE {
+		t.Errorf("Expected content type %v, got: %v", PROBLEM_CONTENT_TYPE, ctype)
+	}
+
+	var problem Problem
+	if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+		t.Fatalf("Error decoding problem %s: %v", w.Body.Bytes(), err)
+	}
+	if problem.Code != model.InvalidTokenError.Code || problem.RequestId != "req1" {
+		t.Errorf("Unexpected problem: %+v", problem)
+	}
+}
@@ -0,0 +1,152 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+
+	"Cloudtacts/pkg/config"
+	"Cloudtacts/pkg/model"
+)
+
+const (
+	DIALECT_MYSQL    = "mysql"
+	DIALECT_POSTGRES = "postgres"
+	DIALECT_SQLITE   = "sqlite"
+
+	MYSQL_DUPLICATE_ENTRY          = 1062    // ER_DUP_ENTRY
+	MYSQL_LOCK_DEADLOCK            = 1213    // ER_LOCK_DEADLOCK
+	POSTGRES_UNIQUE_VIOLATION      = "23505" // unique_violation
+	POSTGRES_SERIALIZATION_FAILURE = "40001" // serialization_failure
+	POSTGRES_DEADLOCK_DETECTED     = "40P01" // deadlock_detected
+)
+
+// errDuplicateKey is the dialect neutral cause of the DbPKeyError of a key
+// found to be duplicate other than by the database, e.g. by the in-memory
+// client or by a uniqueness policy.
+var errDuplicateKey = errors.New("duplicate key")
+
+// Dialect adapts the user database's queries, connections, and errors to a
+// SQL database and its driver. Queries are written for MySQL, with "?"
+// placeholders and backtick quoted identifiers, and rebound for the others
+// (see Rebind).
+type Dialect struct {
+	Name   string
+	Driver string
+
+	dsn       func(cfg *config.Config, hostUrl, database string) string
+	numbered  bool // placeholders are numbered: $1, $2, ...
+	quote     string
+	duplicate func(err error) bool
+	conflict  func(err error) bool
+}
+
+var dialects = map[string]*Dialect{
+	DIALECT_MYSQL: {
+		Name: DIALECT_MYSQL, Driver: "mysql", quote: "`",
+		dsn: func(cfg *config.Config, hostUrl, database string) string {
+			return fmt.Sprintf("%v:%v@tcp(%v)/%v?tls=skip-verify&autocommit=true&parseTime=true", cfg.ValueOf(model.KEY_USERDB_LOGIN), cfg.ValueOf(model.KEY_USERDB_PASSWORD), hostUrl, database)
+		},
+		duplicate: func(err error) bool {
+			var merr *mysql.MySQLError
+			return errors.As(err, &merr) && merr.Number == MYSQL_DUPLICATE_ENTRY
+		},
+		conflict: func(err error) bool {
+			var merr *mysql.MySQLError
+			return errors.As(err, &merr) && merr.Number == MYSQL_LOCK_DEADLOCK
+		},
+	},
+	DIALECT_POSTGRES: {
+		Name: DIALECT_POSTGRES, Driver: "pgx", quote: `"`, numbered: true,
+		dsn: func(cfg *config.Config, hostUrl, database string) string {
+			dsn := url.URL{
+				Scheme:   "postgres",
+				User:     url.UserPassword(cfg.ValueOf(model.KEY_USERDB_LOGIN), cfg.ValueOf(model.KEY_USERDB_PASSWORD)),
+				Host:     hostUrl,
+				Path:     "/" + database,
+				RawQuery: "sslmode=prefer",
+			}
+			return dsn.String()
+		},
+		duplicate: func(err error) bool {
+			var perr *pgconn.PgError
+			return errors.As(err, &perr) && perr.Code == POSTGRES_UNIQUE_VIOLATION
+		},
+		conflict: func(err error) bool {
+			var perr *pgconn.PgError
+			return errors.As(err, &perr) && (perr.Code == POSTGRES_SERIALIZATION_FAILURE || perr.Code == POSTGRES_DEADLOCK_DETECTED)
+		},
+	},
+	DIALECT_SQLITE: {
+		Name: DIALECT_SQLITE, Driver: "sqlite", quote: "`",
+		dsn: func(cfg *config.Config, hostUrl, database string) string {
+			// the database is a file; sessions are deleted with their users, and
+			// transactions take the write lock when they begin, serializing them
+			return fmt.Sprintf("file:%v?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_time_format=sqlite&_txlock=immediate", database)
+		},
+		duplicate: func(err error) bool {
+			var serr *sqlite.Error
+			return errors.As(err, &serr) && (serr.Code() == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY || serr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE)
+		},
+		conflict: func(err error) bool {
+			var serr *sqlite.Error
+			return errors.As(err, &serr) && serr.Code()&0xff == sqlite3.SQLITE_BUSY
+		},
+	},
+}
+
+// DialectOf returns the configured dialect of the user database (see
+// KEY_USERDB_DIALECT), MySQL by default.
+func DialectOf(cfg *config.Config) (*Dialect, model.ServiceError) {
+	name := cfg.ValueOfWithDefault(model.KEY_USERDB_DIALECT, DIALECT_MYSQL)
+	if dialect, ok := dialects[name]; ok {
+		return dialect, model.NoError
+	}
+
+	return nil, model.DbOpenError.WithCause(fmt.Errorf("unknown user database dialect: %v", name))
+}
+
+// Rebind returns the given MySQL query in the dialect's placeholder and
+// identifier quoting syntax.
+func (d *Dialect) Rebind(query string) string {
+	if d.quote != "`" {
+		query = strings.ReplaceAll(query, "`", d.quote)
+	}
+	if !d.numbered {
+		return query
+	}
+
+	var rebound strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			rebound.WriteString("$" + strconv.Itoa(n))
+		} else {
+			rebound.WriteRune(r)
+		}
+	}
+
+	return rebound.String()
+}
+
+// IsDuplicate returns true if the given error is the dialect's error of a
+// duplicate primary or unique key.
+func (d *Dialect) IsDuplicate(err error) bool {
+	return err != nil && d.duplicate(err)
+}
+
+// IsConflict returns true if the given error is the dialect's error of a
+// transaction which conflicted with a concurrent one (e.g. a serialization
+// failure or deadlock), and may be retried.
+func (d *Dialect) IsConflict(err error) bool {
+	return err != nil && d.conflict(err)
+}
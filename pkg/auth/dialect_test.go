@@ -0,0 +1,83 @@
+package auth
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestRebind(t *testing.T) {
+	query := "UPDATE `user` SET ctpass = ? WHERE ctuser = ? AND ctprof = ?"
+	rebound := map[string]string{
+		DIALECT_MYSQL:    query,
+		DIALECT_SQLITE:   query,
+		DIALECT_POSTGRES: `UPDATE "user" SET ctpass = $1 WHERE ctuser = $2 AND ctprof = $3`,
+	}
+
+	for name, want := range rebound {
+		if got := dialects[name].Rebind(query); got != want {
+			t.Errorf("Expected %v query %q, got: %q", name, want, got)
+		}
+	}
+}
+
+func TestIsDuplicate(t *testing.T) {
+	duplicates := map[string]error{
+		DIALECT_MYSQL:    &mysql.MySQLError{Number: MYSQL_DUPLICATE_ENTRY, Message: "Duplicate entry 'pendracon1' for key 'user.PRIMARY'"},
+		DIALECT_POSTGRES: &pgconn.PgError{Code: POSTGRES_UNIQUE_VIOLATION, Message: "duplicate key value violates unique constraint \"user_pkey\""},
+	}
+
+	for name, err := range duplicates {
+		dialect := dialects[name]
+		if !dialect.IsDuplicate(err) || !dialect.IsDuplicate(fmt.Errorf("insert: %w", err)) {
+			t.Errorf("Expected %v duplicate key error: %v", name, err)
+		}
+		if dialect.IsDuplicate(errors.New(err.Error())) || dialect.IsDuplicate(nil) {
+			t.Errorf("Expected %v to match duplicate key errors by type, not message.", name)
+		}
+	}
+	if dialects[DIALECT_SQLITE].IsDuplicate(duplicates[DIALECT_MYSQL]) {
+		t.Error("Expected sqlite not to match mysql duplicate key error.")
+	}
+}
+
+func TestIsDuplicateSQLite(t *testing.T) {
+	dialect := dialects[DIALECT_SQLITE]
+	db, err := sql.Open(dialect.Driver, dialect.dsn(cfg, "", filepath.Join(t.TempDir(), "dup.sqlite")))
+	if err != nil {
+		t.Fatalf("Error opening database: %v", err)
+	}
+	defer db.Close()
+	if _, err = db.Exec("CREATE TABLE dup (id TEXT PRIMARY KEY, uid TEXT NOT NULL UNIQUE)"); err != nil {
+		t.Fatalf("Error creating table: %v", err)
+	}
+	if _, err = db.Exec("INSERT INTO dup VALUES ('1', 'a')"); err != nil {
+		t.Fatalf("Error inserting row: %v", err)
+	}
+
+	for _, test := range []struct {
+		name      string
+		insert    string
+		duplicate bool
+	}{
+		{"primary key", "INSERT INTO dup VALUES ('1', 'b')", true},
+		{"unique", "INSERT INTO dup VALUES ('2', 'a')", true},
+		{"not null", "INSERT INTO dup VALUES ('3', NULL)", false},
+	} {
+		_, err := db.Exec(test.insert)
+		if err == nil || dialect.IsDuplicate(err) != test.duplicate {
+			t.Errorf("Expected %v constraint violation duplicate %v, got: %v", test.name, test.duplicate, err)
+		}
+	}
+}
+
+func TestDialectOf(t *testing.T) {
+	if dialect, serr := DialectOf(cfg); serr.IsError() || dialect.Name != DIALECT_MYSQL {
+		t.Errorf("Expected default dialect %v, got %v: %v", DIALECT_MYSQL, dialect, serr)
+	}
+}
@@ -0,0 +1,213 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"Cloudtacts/pkg/config"
+	"Cloudtacts/pkg/model"
+)
+
+const (
+	SELECT_USERS_BY_LOGIN string = "SELECT uid, ctuser, ctpass, ctprof, uemail, ctppic, atoken, llogin, uvalid FROM `user` WHERE ctuser = ? OR uemail = ? OR uid = ? ORDER BY ctuser, ctprof, uemail"
+
+	// User uniqueness policies (see UniquenessPolicyOf)
+	UNIQUE_COLLECTIVE = "collective"
+	UNIQUE_LOGIN      = "login"
+	UNIQUE_EMAIL      = "email"
+)
+
+// UniquenessPolicy is the policy of which users' keys must be unique besides
+// the collective key (login identifier, profile name, and e-mail address)
+// enforced by the database.
+type UniquenessPolicy struct {
+	Login bool // login identifiers are unique
+	Email bool // e-mail addresses are unique
+}
+
+// querier queries a database connection (sql.DB) or transaction (sql.Tx).
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+func (uc *userClient) UsersByLogin(login string) ([]model.User, model.ServiceError) {
+	return uc.UsersByLoginContext(context.Background(), login)
+}
+
+func (uc *userClient) UsersByLoginContext(ctx context.Context, login string) ([]model.User, model.ServiceError) {
+	return queryUsers(ctx, uc, uc.conn, login)
+}
+
+// queryUsers returns the users whose login identifier, e-mail address, or
+// user ID is the given login, with the given connection or transaction.
+func queryUsers(ctx context.Context, uc *userClient, conn querier, login string) ([]model.User, model.ServiceError) {
+	if len(login) == 0 {
+		return nil, model.InvalidKeyError.WithCause(model.NoUserIdError)
+	}
+
+	ctx, cancel := uc.queryContext(ctx)
+	defer cancel()
+
+	rows, err := conn.QueryContext(ctx, uc.dialect.Rebind(SELECT_USERS_BY_LOGIN), login, login, login)
+	if err != nil {
+		return nil, dbError(model.DbQueryError, err)
+	}
+	defer rows.Close()
+
+	users := []model.User{}
+	for rows.Next() {
+		var user model.User
+		var uid, ctppic, atoken, llogin, uvalid []byte
+		if err = rows.Scan(&uid, &user.CtUser, &user.CtPass, &user.CtProf, &user.UEmail, &ctppic, &atoken, &llogin, &uvalid); err != nil {
+			return nil, dbError(model.DbScanError, err)
+		}
+		user.UserId = string(uid)
+		user.CtPpic = string(ctppic)
+		user.AToken = string(atoken)
+		user.LLogin = string(llogin)
+		user.UValid = string(uvalid)
+		users = append(users, user)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, dbError(model.DbResultsError, err)
+	}
+
+	return users, model.NoError
+}
+
+// UniquenessPolicyOf returns the configured uniqueness policy of new users
+// (see KEY_USERDB_UNIQUENESS): a comma separated list of "login" and
+// "email", or "collective" (the default) if only the collective key is
+// unique.
+func UniquenessPolicyOf(cfg *config.Config) (UniquenessPolicy, model.ServiceError) {
+	var policy UniquenessPolicy
+
+	for _, name := range strings.Split(cfg.ValueOfWithDefault(model.KEY_USERDB_UNIQUENESS, UNIQUE_COLLECTIVE), ",") {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case UNIQUE_COLLECTIVE:
+		case UNIQUE_LOGIN:
+			policy.Login = true
+		case UNIQUE_EMAIL:
+			policy.Email = true
+		default:
+			return policy, model.SystemError.WithCause(fmt.Errorf("unknown user uniqueness policy: %v", name))
+		}
+	}
+
+	return policy, model.NoError
+}
+
+// check returns DbPKeyError if the policy forbids adding the referenced user
+// in the given transaction.
+func (policy UniquenessPolicy) check(tx UserTx, user *model.User) model.ServiceError {
+	for _, key := range []struct {
+		unique bool
+		name   string
+		value  string
+		of     func(*model.User) string
+	}{
+		{policy.Login, "login ID", user.CtUser, func(u *model.User) string { return u.CtUser }},
+		{policy.Email, "e-mail address", user.UEmail, func(u *model.User) string { return u.UEmail }},
+	} {
+		if !key.unique {
+			continue
+		}
+
+		users, serr := tx.UsersByLogin(key.value)
+		if serr.IsError() {
+			return serr
+		}
+		for i := range users {
+			if key.of(&users[i]) == key.value {
+				return model.DbPKeyError.WithCause(fmt.Errorf("%w: %v '%v' is already registered", errDuplicateKey, key.name, key.value))
+			}
+		}
+	}
+
+	return model.NoError
+}
+
+// AuthenticateUser verifies the given text password of the referenced user,
+// and updates the user with its information from the database. A user whose
+// key is incomplete, or who is given by user ID, is looked up by its user
+// ID, login identifier, or e-mail address, whichever is given first, among
+// the users matching the other parts of the key given. Login fails with
+// AmbiguousLoginError, caused by the AmbiguousLogin listing the accounts to
+// choose from, if the password matches several users. Re-hashed passwords
+// are returned as by VerifyUserPwd.
+func AuthenticateUser(ctx context.Context, cfg *config.Config, uc UserDBClient, user *model.User, password string) (rehashed bool, serr model.ServiceError) {
+	if ok, _ := validateUserKey(user); ok && len(user.UserId) == 0 {
+		if serr = uc.UserInfoContext(ctx, user); serr.IsError() {
+			return false, serr
+		}
+		if ok, rehashed = VerifyUserPwd(cfg, user, password); !ok {
+			return false, model.InvalidLoginError
+		}
+		return rehashed, model.NoError
+	}
+
+	login := user.UserId
+	for _, key := range []string{user.CtUser, user.UEmail} {
+		if len(login) == 0 {
+			login = key
+		}
+	}
+	if len(login) == 0 {
+		return false, model.InvalidKeyError.WithCause(model.NoUserIdError)
+	}
+
+	users, serr := uc.UsersByLoginContext(ctx, login)
+	if serr.IsError() {
+		return false, serr
+	}
+
+	found := false
+	matched := []model.User{}
+	for i := range users {
+		if !matchesKey(user, &users[i], login) {
+			continue
+		}
+		found = true
+
+		if ok, re := VerifyUserPwd(cfg, &users[i], password); ok {
+			matched = append(matched, users[i])
+			rehashed = re
+		}
+	}
+
+	switch {
+	case !found:
+		return false, model.DbPKeyMissingError
+	case len(matched) == 0:
+		return false, model.InvalidLoginError
+	case len(matched) > 1:
+		accounts := []model.User{}
+		for _, match := range matched {
+			accounts = append(accounts, model.User{UserId: match.UserId, CtUser: match.CtUser, CtProf: match.CtProf, UEmail: match.UEmail})
+		}
+		return false, model.AmbiguousLoginError.WithCause(&model.AmbiguousLogin{Login: login, Users: accounts})
+	}
+
+	*user = matched[0]
+
+	return rehashed, model.NoError
+}
+
+// matchesKey returns true if the given user, found by the given login,
+// matches the parts of the referenced user's key other than the login.
+func matchesKey(user, found *model.User, login string) bool {
+	for _, key := range []struct{ given, found string }{
+		{user.UserId, found.UserId},
+		{user.CtUser, found.CtUser},
+		{user.CtProf, found.CtProf},
+		{user.UEmail, found.UEmail},
+	} {
+		if len(key.given) > 0 && key.given != login && key.given != key.found {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,201 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"Cloudtacts/pkg/config"
+	"Cloudtacts/pkg/model"
+)
+
+func TestUniquenessPolicyOf(t *testing.T) {
+	for _, test := range []struct {
+		value  string
+		policy UniquenessPolicy
+		ok     bool
+	}{
+		{"collective", UniquenessPolicy{}, true},
+		{"login", UniquenessPolicy{Login: true}, true},
+		{"email", UniquenessPolicy{Email: true}, true},
+		{"login, Email", UniquenessPolicy{Login: true, Email: true}, true},
+		{"profile", UniquenessPolicy{}, false},
+	} {
+		t.Setenv("CT_USERDB_UNIQUENESS", test.value)
+		pcfg, err := config.ContextConfig()
+		if err != nil {
+			t.Fatalf("Error parsing configuration: %v", err)
+		}
+
+		policy, serr := UniquenessPolicyOf(pcfg)
+		if serr.IsError() == test.ok || (test.ok && policy != test.policy) {
+			t.Errorf("Expected policy %+v (valid %v) of %q, got %+v: %v", test.policy, test.ok, test.value, policy, serr)
+		}
+	}
+}
+
+func TestAuthenticateUser(t *testing.T) {
+	uc := newMemoryClient()
+	users := []*model.User{
+		{CtUser: "login1", CtPass: "Secret#1", CtProf: "Home", UEmail: "login1@example.com"},
+		{CtUser: "login1", CtPass: "Secret#1", CtProf: "Work", UEmail: "login1@example.org"},
+		{CtUser: "login1", CtPass: "Secret#2", CtProf: "Club", UEmail: "club@example.com"},
+	}
+	for _, user := range users {
+		if serr := HashUserPwd(cfg, user); serr.IsError() {
+			t.Fatalf("Error hashing password: %v", serr)
+		}
+		if serr := uc.AddUser(user); serr.IsError() {
+			t.Fatalf("Error adding user: %v", serr)
+		}
+	}
+
+	for _, test := range []struct {
+		name     string
+		login    model.User
+		password string
+		user     *model.User
+		serr     model.ServiceError
+	}{
+		{"key", model.User{CtUser: "login1", CtProf: "Work", UEmail: "login1@example.org"}, "Secret#1", users[1], model.NoError},
+		{"login and profile", model.User{CtUser: "login1", CtProf: "Work"}, "Secret#1", users[1], model.NoError},
+		{"login and password", model.User{CtUser: "login1"}, "Secret#2", users[2], model.NoError},
+		{"e-mail", model.User{UEmail: "login1@example.com"}, "Secret#1", users[0], model.NoError},
+		{"e-mail as login", model.User{CtUser: "club@example.com"}, "Secret#2", users[2], model.NoError},
+		{"user ID", model.User{UserId: users[0].UserId}, "Secret#1", users[0], model.NoError},
+		{"ambiguous", model.User{CtUser: "login1"}, "Secret#1", nil, model.AmbiguousLoginError},
+		{"wrong password", model.User{CtUser: "login1", CtProf: "Home"}, "Secret#2", nil, model.InvalidLoginError},
+		{"unknown", model.User{CtUser: "login2"}, "Secret#1", nil, model.DbPKeyMissingError},
+		{"no login", model.User{CtProf: "Home"}, "Secret#1", nil, model.InvalidKeyError},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			user := test.login
+			_, serr := AuthenticateUser(context.Background(), cfg, uc, &user, test.password)
+			if serr.Code != test.serr.Code {
+				t.Fatalf("Expected %v, got: %v", test.serr, serr)
+			}
+			if test.user != nil && (user.UserId != test.user.UserId || user.CtProf != test.user.CtProf || user.UEmail != test.user.UEmail) {
+				t.Errorf("Expected user %v, got: %v", test.user, user)
+			}
+		})
+	}
+
+	user := model.User{CtUser: "login1"}
+	_, serr := AuthenticateUser(context.Background(), cfg, uc, &user, "Secret#1")
+	var ambiguous *model.AmbiguousLogin
+	if !errors.As(serr.Cause, &ambiguous) || len(ambiguous.Users) != 2 {
+		t.Fatalf("Expected the 2 accounts matching the login, got: %v", serr)
+	}
+	for _, account := range ambiguous.Users {
+		if len(account.UserId) == 0 || len(account.CtPass) > 0 {
+			t.Errorf("Expected the account's user ID and no password, got: %v", account)
+		}
+	}
+}
+
+func TestRegisterCraftedHash(t *testing.T) {
+	uc := newMemoryClient()
+	victim := &model.User{CtUser: "login1", CtPass: "Secret#1", CtProf: "Home", UEmail: "login1@example.com"}
+	if serr := HashUserPwd(cfg, victim); serr.IsError() {
+		t.Fatalf("Error hashing password: %v", serr)
+	}
+	if serr := uc.AddUser(victim); serr.IsError() {
+		t.Fatalf("Error adding user: %v", serr)
+	}
+
+	crafted := "A:$argon2id$v=19$m=8,t=0,p=1$c2FsdHNhbHQ$a2V5a2V5"
+	user := &model.User{CtUser: "login1", CtPass: crafted, CtProf: "Crafted", UEmail: "crafted@example.com"}
+	if serr := HashUserPwd(cfg, user); serr.IsError() {
+		t.Fatalf("Error hashing password: %v", serr)
+	}
+	if serr := RegisterUser(context.Background(), cfg, uc, nil, user); serr.IsError() {
+		t.Fatalf("Error registering user: %v", serr)
+	}
+	if ok, err := model.VerifyPassword(user.CtPass, crafted); user.CtPass == crafted || !ok || err != nil {
+		t.Errorf("Expected the crafted hash hashed as a password, got: %v (%v)", user.CtPass, err)
+	}
+
+	// a crafted hash stored regardless fails verification rather than login
+	if serr := uc.AddUser(&model.User{CtUser: "login1", CtPass: crafted, CtProf: "Raw", UEmail: "raw@example.com"}); serr.IsError() {
+		t.Fatalf("Error adding user: %v", serr)
+	}
+	login := model.User{CtUser: "login1"}
+	if _, serr := AuthenticateUser(context.Background(), cfg, uc, &login, "Secret#1"); serr.IsError() || login.CtProf != "Home" {
+		t.Errorf("Expected the victim's login, got %v: %v", login, serr)
+	}
+}
+
+func TestRegisterUniqueUser(t *testing.T) {
+	t.Setenv("CT_USERDB_UNIQUENESS", "login,email")
+	ucfg, err := config.ContextConfig()
+	if err != nil {
+		t.Fatalf("Error parsing configuration: %v", err)
+	}
+
+	uc := newMemoryClient()
+	user := &model.User{CtUser: "unique1", CtPass: "H:0", CtProf: "Home", UEmail: "unique1@example.com"}
+	if serr := RegisterUser(context.Background(), ucfg, uc, nil, user.Clone()); serr.IsError() {
+		t.Fatalf("Error registering user: %v", serr)
+	}
+
+	for _, dup := range []*model.User{
+		{CtUser: "unique1", CtPass: "H:0", CtProf: "Work", UEmail: "unique1@example.org"},
+		{CtUser: "unique2", CtPass: "H:0", CtProf: "Home", UEmail: "unique1@example.com"},
+	} {
+		if serr := RegisterUser(context.Background(), ucfg, uc, nil, dup); serr.Code != model.DbPKeyError.Code || !errors.Is(serr.Cause, errDuplicateKey) {
+			t.Errorf("Expected %v of a duplicate key registering %v, got: %v", model.DbPKeyError, dup, serr)
+		}
+	}
+
+	// the collective key only
+	other := &model.User{CtUser: "unique1", CtPass: "H:0", CtProf: "Work", UEmail: "unique1@example.org"}
+	if serr := RegisterUser(context.Background(), cfg, uc, nil, other); serr.IsError() {
+		t.Errorf("Error registering user with collective policy: %v", serr)
+	}
+}
+
+func TestRegisterUniqueUserConcurrently(t *testing.T) {
+	t.Setenv("CT_USERDB_UNIQUENESS", "login")
+	ucfg, err := config.ContextConfig()
+	if err != nil {
+		t.Fatalf("Error parsing configuration: %v", err)
+	}
+
+	registerConcurrently(t, ucfg, newMemoryClient())
+}
+
+// registerConcurrently registers users with the same login ID concurrently
+// with the given client, and checks that the login uniqueness policy of the
+// given configuration admits only one of them.
+func registerConcurrently(t *testing.T, ucfg *config.Config, uc UserDBClient) {
+	const registrants = 8
+
+	var wg sync.WaitGroup
+	results := make([]model.ServiceError, registrants)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			user := &model.User{CtUser: "race1", CtPass: "H:0", CtProf: fmt.Sprintf("Profile%d", i), UEmail: fmt.Sprintf("race%d@example.com", i)}
+			results[i] = RegisterUser(context.Background(), ucfg, uc, nil, user)
+		}(i)
+	}
+	wg.Wait()
+
+	registered := 0
+	for _, serr := range results {
+		switch serr.Code {
+		case model.NoError.Code:
+			registered++
+		case model.DbPKeyError.Code:
+		default:
+			t.Errorf("Expected %v of a concurrent registration, got: %v", model.DbPKeyError, serr)
+		}
+	}
+	users, serr := uc.UsersByLogin("race1")
+	if registered != 1 || serr.IsError() || len(users) != 1 {
+		t.Errorf("Expected 1 user registered, got %d and %v: %v", registered, users, serr)
+	}
+}
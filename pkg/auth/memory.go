@@ -0,0 +1,633 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"Cloudtacts/pkg/model"
+	"Cloudtacts/pkg/util"
+)
+
+const MEMORY_HOST_URL = "memory"
+
+var (
+	memClient     *memoryClient
+	memClientOnce sync.Once
+)
+
+// memoryClient is a thread-safe, in-memory representation of the user
+// database for use in test mode (see KEY_USERDB_TEST_MODE). It mirrors the
+// key and error semantics of the SQL clients: adding an existing user
+// returns DbPKeyError (D09) and querying a missing user returns
+// DbPKeyMissingError (D10). Records are lost on exit.
+type memoryClient struct {
+	mutex    sync.RWMutex
+	users    map[memoryKey]model.User
+	sessions map[string]model.Session
+
+	// sequence number of the users added, by key, and of the last one added
+	added map[memoryKey]uint64
+	seq   uint64
+}
+
+func (mc *memoryClient) UserInfo(user *model.User) model.ServiceError {
+	if ok, err := validateUserKey(user); !ok {
+		return model.InvalidKeyError.WithCause(err)
+	}
+
+	mc.mutex.RLock()
+	defer mc.mutex.RUnlock()
+
+	row, ok := mc.users[userKey(user)]
+	if !ok {
+		return model.DbPKeyMissingError
+	}
+
+	user.CtPass = row.CtPass
+	user.CtPpic = row.CtPpic
+	user.AToken = row.AToken
+	user.LLogin = row.LLogin
+	user.UValid = row.UValid
+	user.UserId = row.UserId
+
+	return model.NoError
+}
+
+func (mc *memoryClient) AddUser(user *model.User) model.ServiceError {
+	if serr := validateUserRow(user); serr.IsError() {
+		return serr
+	}
+
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+
+	return mc.insertUser(user)
+}
+
+// insertUser inserts the referenced user; the caller holds the write lock.
+func (mc *memoryClient) insertUser(user *model.User) model.ServiceError {
+	if len(user.UserId) == 0 {
+		user.UserId = uuid.New().String()
+	}
+	if serr := duplicateUser(mc.users, nil, user); serr.IsError() {
+		return serr
+	}
+
+	key := userKey(user)
+	mc.seq++
+	mc.added[key] = mc.seq
+	mc.users[key] = model.User{
+		UserId: user.UserId,
+		CtUser: user.CtUser,
+		CtPass: user.CtPass,
+		CtProf: user.CtProf,
+		UEmail: user.UEmail,
+		CtPpic: user.CtPpic,
+		AToken: user.AToken,
+		LLogin: util.StripDateStamp(user.LLogin),
+	}
+
+	return model.NoError
+}
+
+func (mc *memoryClient) DeleteUser(user *model.User) model.ServiceError {
+	if ok, err := validateUserKey(user); !ok {
+		return model.InvalidKeyError.WithCause(err)
+	}
+
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+
+	mc.deleteUser(userKey(user))
+
+	return model.NoError
+}
+
+// deleteUser deletes the user with the given key; the caller holds the
+// client's lock.
+func (mc *memoryClient) deleteUser(key memoryKey) {
+	delete(mc.users, key)
+	delete(mc.added, key)
+
+	// as with SQL, the user's sessions are deleted with the user
+	for sessId, sess := range mc.sessions {
+		if userKey(sess.Owner()) == key {
+			delete(mc.sessions, sessId)
+		}
+	}
+}
+
+func (mc *memoryClient) UpdateUser(user *model.User) model.ServiceError {
+	if serr := validateUserRow(user); serr.IsError() {
+		return serr
+	}
+
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+
+	key := userKey(user)
+	row, ok := mc.users[key]
+	if !ok {
+		// as with SQL, updating a missing row isn't an error
+		return model.NoError
+	}
+
+	row.CtPass = user.CtPass
+	row.CtPpic = user.CtPpic
+	row.AToken = user.AToken
+	if len(user.LLogin) > 0 {
+		row.LLogin = util.StripDateStamp(user.LLogin)
+	}
+	if len(user.UValid) > 0 {
+		row.UValid = util.StripDateStamp(user.UValid)
+	}
+	mc.users[key] = row
+
+	return model.NoError
+}
+
+func (mc *memoryClient) UsersByLogin(login string) ([]model.User, model.ServiceError) {
+	if len(login) == 0 {
+		return nil, model.InvalidKeyError.WithCause(model.NoUserIdError)
+	}
+
+	mc.mutex.RLock()
+	defer mc.mutex.RUnlock()
+
+	return usersByLogin(mc.users, nil, login), model.NoError
+}
+
+func (mc *memoryClient) SessionInfo(sess *model.Session) model.ServiceError {
+	if len(sess.SessId) == 0 {
+		return model.InvalidKeyError.WithCause(model.NoSessionIdError)
+	}
+
+	mc.mutex.RLock()
+	defer mc.mutex.RUnlock()
+
+	row, ok := mc.sessions[sess.SessId]
+	if !ok {
+		return model.DbPKeyMissingError
+	}
+	*sess = row
+
+	return model.NoError
+}
+
+func (mc *memoryClient) UserSessions(user *model.User) ([]model.Session, model.ServiceError) {
+	if ok, err := validateUserKey(user); !ok {
+		return nil, model.InvalidKeyError.WithCause(err)
+	}
+
+	mc.mutex.RLock()
+	defer mc.mutex.RUnlock()
+
+	sessions := []model.Session{}
+	for _, sess := range mc.sessions {
+		if sess.OwnedBy(user) {
+			sessions = append(sessions, sess)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].Created.Before(sessions[j].Created)
+	})
+
+	return sessions, model.NoError
+}
+
+func (mc *memoryClient) AddSession(sess *model.Session) model.ServiceError {
+	if ok, err := validateUserKey(sess.Owner()); !ok {
+		return model.InvalidKeyError.WithCause(err)
+	}
+	if len(sess.SessId) == 0 {
+		return model.InvalidKeyError.WithCause(model.NoSessionIdError)
+	}
+
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+
+	if _, ok := mc.sessions[sess.SessId]; ok {
+		return model.DbPKeyError.WithCause(fmt.Errorf("%w: session '%v'", errDuplicateKey, sess.SessId))
+	}
+	if _, ok := mc.users[userKey(sess.Owner())]; !ok {
+		// as with SQL, a session's user must exist
+		return model.DbInsertError.WithCause(fmt.Errorf("no user '%v' of session '%v'", userKey(sess.Owner()), sess.SessId))
+	}
+	mc.sessions[sess.SessId] = *sess
+
+	return model.NoError
+}
+
+func (mc *memoryClient) UpdateSession(sess *model.Session, rthash string) model.ServiceError {
+	if len(sess.SessId) == 0 {
+		return model.InvalidKeyError.WithCause(model.NoSessionIdError)
+	}
+
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+
+	row, ok := mc.sessions[sess.SessId]
+	if !ok || row.RtHash != rthash {
+		return model.InvalidRefreshError
+	}
+
+	row.RtHash = sess.RtHash
+	row.Renewed = sess.Renewed
+	row.Expires = sess.Expires
+	mc.sessions[sess.SessId] = row
+
+	return model.NoError
+}
+
+func (mc *memoryClient) DeleteSession(sess *model.Session) model.ServiceError {
+	if len(sess.SessId) == 0 {
+		return model.InvalidKeyError.WithCause(model.NoSessionIdError)
+	}
+
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+
+	delete(mc.sessions, sess.SessId)
+
+	return model.NoError
+}
+
+func (mc *memoryClient) DeleteUserSessions(user *model.User) model.ServiceError {
+	if ok, err := validateUserKey(user); !ok {
+		return model.InvalidKeyError.WithCause(err)
+	}
+
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+
+	for sessId, sess := range mc.sessions {
+		if sess.OwnedBy(user) {
+			delete(mc.sessions, sessId)
+		}
+	}
+
+	return model.NoError
+}
+
+func (mc *memoryClient) UnvalidatedUsers(before time.Time) ([]model.User, model.ServiceError) {
+	mc.mutex.RLock()
+	defer mc.mutex.RUnlock()
+
+	cutoff := before.UTC().Format(model.FMT_DATETIME_GO)
+	users := []model.User{}
+	for _, row := range mc.users {
+		if len(row.UValid) == 0 && len(row.LLogin) > 0 && row.LLogin < cutoff {
+			users = append(users, model.User{
+				CtUser: row.CtUser,
+				CtProf: row.CtProf,
+				UEmail: row.UEmail,
+				CtPpic: row.CtPpic,
+				LLogin: row.LLogin,
+			})
+		}
+	}
+
+	return users, model.NoError
+}
+
+func (mc *memoryClient) DeleteUnvalidatedUser(user *model.User, before time.Time) (bool, model.ServiceError) {
+	if ok, err := validateUserKey(user); !ok {
+		return false, model.InvalidKeyError.WithCause(err)
+	}
+
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+
+	key := userKey(user)
+	row, ok := mc.users[key]
+	if !ok || len(row.UValid) > 0 || len(row.LLogin) == 0 || row.LLogin >= before.UTC().Format(model.FMT_DATETIME_GO) {
+		return false, model.NoError
+	}
+	mc.deleteUser(key)
+
+	return true, model.NoError
+}
+
+// The context variants fail as the MySQL client's do if the context is
+// already done, and otherwise ignore it.
+
+func (mc *memoryClient) UserInfoContext(ctx context.Context, user *model.User) model.ServiceError {
+	if serr := contextError(ctx); serr.IsError() {
+		return serr
+	}
+	return mc.UserInfo(user)
+}
+
+func (mc *memoryClient) AddUserContext(ctx context.Context, user *model.User) model.ServiceError {
+	if serr := contextError(ctx); serr.IsError() {
+		return serr
+	}
+	return mc.AddUser(user)
+}
+
+func (mc *memoryClient) DeleteUserContext(ctx context.Context, user *model.User) model.ServiceError {
+	if serr := contextError(ctx); serr.IsError() {
+		return serr
+	}
+	return mc.DeleteUser(user)
+}
+
+func (mc *memoryClient) UpdateUserContext(ctx context.Context, user *model.User) model.ServiceError {
+	if serr := contextError(ctx); serr.IsError() {
+		return serr
+	}
+	return mc.UpdateUser(user)
+}
+
+func (mc *memoryClient) UsersByLoginContext(ctx context.Context, login string) ([]model.User, model.ServiceError) {
+	if serr := contextError(ctx); serr.IsError() {
+		return nil, serr
+	}
+	return mc.UsersByLogin(login)
+}
+
+func (mc *memoryClient) SessionInfoContext(ctx context.Context, sess *model.Session) model.ServiceError {
+	if serr := contextError(ctx); serr.IsError() {
+		return serr
+	}
+	return mc.SessionInfo(sess)
+}
+
+func (mc *memoryClient) UserSessionsContext(ctx context.Context, user *model.User) ([]model.Session, model.ServiceError) {
+	if serr := contextError(ctx); serr.IsError() {
+		return nil, serr
+	}
+	return mc.UserSessions(user)
+}
+
+func (mc *memoryClient) AddSessionContext(ctx context.Context, sess *model.Session) model.ServiceError {
+	if serr := contextError(ctx); serr.IsError() {
+		return serr
+	}
+	return mc.AddSession(sess)
+}
+
+func (mc *memoryClient) UpdateSessionContext(ctx context.Context, sess *model.Session, rthash string) model.ServiceError {
+	if serr := contextError(ctx); serr.IsError() {
+		return serr
+	}
+	return mc.UpdateSession(sess, rthash)
+}
+
+func (mc *memoryClient) DeleteSessionContext(ctx context.Context, sess *model.Session) model.ServiceError {
+	if serr := contextError(ctx); serr.IsError() {
+		return serr
+	}
+	return mc.DeleteSession(sess)
+}
+
+func (mc *memoryClient) DeleteUserSessionsContext(ctx context.Context, user *model.User) model.ServiceError {
+	if serr := contextError(ctx); serr.IsError() {
+		return serr
+	}
+	return mc.DeleteUserSessions(user)
+}
+
+func (mc *memoryClient) UnvalidatedUsersContext(ctx context.Context, before time.Time) ([]model.User, model.ServiceError) {
+	if serr := contextError(ctx); serr.IsError() {
+		return nil, serr
+	}
+	return mc.UnvalidatedUsers(before)
+}
+
+func (mc *memoryClient) DeleteUnvalidatedUserContext(ctx context.Context, user *model.User, before time.Time) (bool, model.ServiceError) {
+	if serr := contextError(ctx); serr.IsError() {
+		return false, serr
+	}
+	return mc.DeleteUnvalidatedUser(user, before)
+}
+
+func (mc *memoryClient) BeginContext(ctx context.Context) (UserTx, model.ServiceError) {
+	if serr := contextError(ctx); serr.IsError() {
+		return nil, serr
+	}
+
+	mc.mutex.RLock()
+	defer mc.mutex.RUnlock()
+
+	return &memoryTx{ctx: ctx, mc: mc, seq: mc.seq}, model.NoError
+}
+
+// memoryTx is a transaction of the in-memory user database. Its users are
+// added when it's committed, and are then checked for duplicates again, as
+// a concurrent transaction may have added them since. As with a serializable
+// SQL transaction, it fails with DbConflictError if a concurrent transaction
+// has since added users it queried for.
+type memoryTx struct {
+	ctx    context.Context
+	mc     *memoryClient
+	seq    uint64 // sequence number of the last user added when begun
+	logins []string
+	users  []model.User
+	done   bool
+}
+
+func (mtx *memoryTx) AddUser(user *model.User) model.ServiceError {
+	if serr := contextError(mtx.ctx); serr.IsError() {
+		return serr
+	}
+	if serr := validateUserRow(user); serr.IsError() {
+		return serr
+	}
+
+	mtx.mc.mutex.RLock()
+	defer mtx.mc.mutex.RUnlock()
+
+	if len(user.UserId) == 0 {
+		user.UserId = uuid.New().String()
+	}
+	if serr := duplicateUser(mtx.mc.users, mtx.users, user); serr.IsError() {
+		return serr
+	}
+	mtx.users = append(mtx.users, *user)
+
+	return model.NoError
+}
+
+func (mtx *memoryTx) UsersByLogin(login string) ([]model.User, model.ServiceError) {
+	if serr := contextError(mtx.ctx); serr.IsError() {
+		return nil, serr
+	}
+	if len(login) == 0 {
+		return nil, model.InvalidKeyError.WithCause(model.NoUserIdError)
+	}
+
+	mtx.mc.mutex.RLock()
+	defer mtx.mc.mutex.RUnlock()
+
+	mtx.logins = append(mtx.logins, login)
+	return usersByLogin(mtx.mc.users, mtx.users, login), model.NoError
+}
+
+func (mtx *memoryTx) Commit() model.ServiceError {
+	if mtx.done {
+		return model.DbTransactionError.WithCause(fmt.Errorf("transaction has already been committed or rolled back"))
+	}
+	mtx.done = true
+	if serr := contextError(mtx.ctx); serr.IsError() {
+		return serr
+	}
+
+	mtx.mc.mutex.Lock()
+	defer mtx.mc.mutex.Unlock()
+
+	for _, login := range mtx.logins {
+		for _, row := range usersByLogin(mtx.mc.users, nil, login) {
+			if mtx.mc.added[userKey(&row)] > mtx.seq {
+				return model.DbConflictError.WithCause(fmt.Errorf("user '%v' added by a concurrent transaction", userKey(&row)))
+			}
+		}
+	}
+	for i := range mtx.users {
+		if serr := duplicateUser(mtx.mc.users, nil, &mtx.users[i]); serr.IsError() {
+			return serr
+		}
+	}
+	for i := range mtx.users {
+		mtx.mc.insertUser(&mtx.users[i])
+	}
+
+	return model.NoError
+}
+
+func (mtx *memoryTx) Rollback() model.ServiceError {
+	mtx.done = true
+	mtx.users = nil
+
+	return model.NoError
+}
+
+func (mc *memoryClient) HostUrl() string {
+	return MEMORY_HOST_URL
+}
+
+func (mc *memoryClient) Close() {
+	// records are kept for the life of the process
+}
+
+func newMemoryClient() *memoryClient {
+	mc := new(memoryClient)
+	mc.users = make(map[memoryKey]model.User)
+	mc.sessions = make(map[string]model.Session)
+	mc.added = make(map[memoryKey]uint64)
+
+	return mc
+}
+
+func sharedMemoryClient() *memoryClient {
+	memClientOnce.Do(func() {
+		memClient = newMemoryClient()
+	})
+
+	return memClient
+}
+
+// validateUserRow validates the referenced user's key and timestamps as the
+// MySQL client does.
+func validateUserRow(user *model.User) model.ServiceError {
+	if ok, err := validateUserKey(user); !ok {
+		return model.InvalidKeyError.WithCause(err)
+	}
+
+	for _, colVal := range []string{user.LLogin, user.UValid} {
+		if len(colVal) > 0 {
+			if _, err := strconv.Atoi(util.StripDateStamp(colVal)); err != nil {
+				return model.DatetimeError.WithCause(err)
+			}
+		}
+	}
+
+	return model.NoError
+}
+
+// duplicateUser returns DbPKeyError if the referenced user's key, or user ID,
+// is already among the given rows, or pending rows of a transaction, as the
+// primary key and the unique uid index of the SQL schemas do.
+func duplicateUser(rows map[memoryKey]model.User, pending []model.User, user *model.User) model.ServiceError {
+	key := userKey(user)
+	if _, ok := rows[key]; ok {
+		return model.DbPKeyError.WithCause(fmt.Errorf("%w: user '%v'", errDuplicateKey, key))
+	}
+	for _, row := range rows {
+		if row.UserId == user.UserId {
+			return model.DbPKeyError.WithCause(fmt.Errorf("%w: user ID '%v'", errDuplicateKey, user.UserId))
+		}
+	}
+	for i := range pending {
+		if userKey(&pending[i]) == key || pending[i].UserId == user.UserId {
+			return model.DbPKeyError.WithCause(fmt.Errorf("%w: user '%v'", errDuplicateKey, key))
+		}
+	}
+
+	return model.NoError
+}
+
+// usersByLogin returns the given rows, and pending rows of a transaction,
+// whose login identifier, e-mail address, or user ID is the given login,
+// ordered by key.
+func usersByLogin(rows map[memoryKey]model.User, pending []model.User, login string) []model.User {
+	users := []model.User{}
+	add := func(row model.User) {
+		if row.CtUser == login || row.UEmail == login || row.UserId == login {
+			users = append(users, row)
+		}
+	}
+	for _, row := range rows {
+		add(row)
+	}
+	for _, row := range pending {
+		add(row)
+	}
+	sort.Slice(users, func(i, j int) bool {
+		return userKey(&users[i]).less(userKey(&users[j]))
+	})
+
+	return users
+}
+
+// memoryKey is the primary key of a user row. Its fields are kept apart,
+// rather than joined, since they may contain any separator.
+type memoryKey struct {
+	ctuser, ctprof, uemail string
+}
+
+func userKey(user *model.User) memoryKey {
+	return memoryKey{user.CtUser, user.CtProf, user.UEmail}
+}
+
+// less orders keys as the SQL clients do, by ctuser, ctprof, and uemail.
+func (key memoryKey) less(other memoryKey) bool {
+	switch {
+	case key.ctuser != other.ctuser:
+		return key.ctuser < other.ctuser
+	case key.ctprof != other.ctprof:
+		return key.ctprof < other.ctprof
+	}
+
+	return key.uemail < other.uemail
+}
+
+func (key memoryKey) String() string {
+	return fmt.Sprintf("%v/%v/%v", key.ctuser, key.ctprof, key.uemail)
+}
+
+// contextError returns the error of a database request made with the given
+// context if the context is done, or NoError otherwise.
+func contextError(ctx context.Context) model.ServiceError {
+	if err := ctx.Err(); err != nil {
+		return dbError(model.DbQueryError, err)
+	}
+
+	return model.NoError
+}
@@ -0,0 +1,30 @@
+package auth
+
+import (
+	"testing"
+
+	"Cloudtacts/pkg/model"
+)
+
+func TestMemoryUserKey(t *testing.T) {
+	mc := newMemoryClient()
+
+	// keys whose fields joined by '/' are the same
+	users := []*model.User{
+		{UserId: "uid1", CtUser: "pendracon1/Pendracon1", CtPass: "H:0", CtProf: "Pendracon", UEmail: "pendracon1@example.com"},
+		{UserId: "uid2", CtUser: "pendracon1", CtPass: "H:0", CtProf: "Pendracon1/Pendracon", UEmail: "pendracon1@example.com"},
+	}
+	for _, user := range users {
+		if serr := mc.AddUser(user); serr.IsError() {
+			t.Fatalf("Error adding user %v/%v: %v", user.CtUser, user.CtProf, serr)
+		}
+	}
+
+	if serr := mc.DeleteUser(users[0]); serr.IsError() {
+		t.Fatalf("Error deleting user: %v", serr)
+	}
+	quser := &model.User{CtUser: users[1].CtUser, CtProf: users[1].CtProf, UEmail: users[1].UEmail}
+	if serr := mc.UserInfo(quser); serr.IsError() || quser.UserId != users[1].UserId {
+		t.Errorf("Expected user %v kept after deleting user %v, got %v: %v", users[1].UserId, users[0].UserId, quser.UserId, serr)
+	}
+}
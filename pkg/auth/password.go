@@ -0,0 +1,78 @@
+package auth
+
+import (
+	"fmt"
+
+	"Cloudtacts/pkg/config"
+	"Cloudtacts/pkg/model"
+	"Cloudtacts/pkg/util"
+)
+
+// PasswordOptions returns the configured password hashing options. Options
+// not configured, or configured with invalid values, are given their
+// defaults (see model.DefaultPasswordOptions).
+func PasswordOptions(cfg *config.Config) model.PasswordOptions {
+	opts := model.DefaultPasswordOptions
+
+	switch algorithm := cfg.ValueOfWithDefault(model.KEY_USERDB_PWD_ALGORITHM, opts.Algorithm); algorithm {
+	case model.PWD_ALGORITHM_ARGON2ID, model.PWD_ALGORITHM_BCRYPT:
+		opts.Algorithm = algorithm
+	default:
+		util.LogIt("Cloudtacts", fmt.Sprintf("Unknown password algorithm '%v', using '%v'.", algorithm, opts.Algorithm))
+	}
+
+	if ival, err := cfg.Int(model.KEY_USERDB_PWD_ARGON_TIME); err == nil {
+		opts.ArgonTime = uint32(ival)
+	}
+	if ival, err := cfg.Int(model.KEY_USERDB_PWD_ARGON_MEM); err == nil {
+		opts.ArgonMemory = uint32(ival)
+	}
+	if ival, err := cfg.Int(model.KEY_USERDB_PWD_ARGON_THRD); err == nil {
+		opts.ArgonThreads = uint8(ival)
+	}
+	if ival, err := cfg.Int(model.KEY_USERDB_PWD_BCRYPT_COST); err == nil {
+		opts.BcryptCost = ival
+	}
+
+	return opts
+}
+
+// HashUserPwd replaces the referenced user's password, as received from a
+// client, with its salted hash using the configured hashing options. The
+// password is hashed even if tagged as a hash (see model.IsPasswordHash): a
+// client can't store a hash of its own making.
+func HashUserPwd(cfg *config.Config, user *model.User) model.ServiceError {
+	hpass, err := model.HashPassword(user.CtPass, PasswordOptions(cfg))
+	if err != nil {
+		return model.SystemError.WithCause(err)
+	}
+	user.CtPass = hpass
+
+	return model.NoError
+}
+
+// VerifyUserPwd returns true if the given text password matches the
+// referenced user's stored password hash, as queried from the database. When
+// matched and the stored hash is legacy or was produced with other than the
+// configured hashing options then the user's password is re-hashed in place
+// and rehashed is returned true; the caller is responsible for updating the
+// user's database record.
+func VerifyUserPwd(cfg *config.Config, user *model.User, text string) (ok bool, rehashed bool) {
+	ok, err := model.VerifyPassword(user.CtPass, text)
+	if err != nil {
+		util.LogIt("Cloudtacts", fmt.Sprintf("Error verifying password of user %v/%v: %v", user.CtUser, user.CtProf, err))
+		return false, false
+	}
+
+	opts := PasswordOptions(cfg)
+	if ok && model.PasswordNeedsRehash(user.CtPass, opts) {
+		if hpass, err := model.HashPassword(text, opts); err == nil {
+			user.CtPass = hpass
+			rehashed = true
+		} else {
+			util.LogIt("Cloudtacts", fmt.Sprintf("Error re-hashing password of user %v/%v: %v", user.CtUser, user.CtProf, err))
+		}
+	}
+
+	return ok, rehashed
+}
@@ -0,0 +1,181 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"Cloudtacts/pkg/config"
+	"Cloudtacts/pkg/model"
+	"Cloudtacts/pkg/util"
+)
+
+const (
+	// Default period between health checks of the pooled client's database
+	// connections (30 seconds).
+	PING_INTERVAL = 30 * time.Second
+)
+
+var errPoolClosed = errors.New("user database pool is closed")
+
+// DBPool is a process-wide user database client, opened lazily on first use
+// and shared by all requests. The client's connections are health checked
+// in the background when borrowed, at most once per ping interval; a failed
+// check is logged, and the client's connections re-established as they're
+// used (see database/sql), so the client is never closed under its
+// borrowers.
+type DBPool struct {
+	cfg      *config.Config
+	interval time.Duration
+	open     func() (UserDBClient, model.ServiceError)
+
+	// held while opening the client, so it's opened once
+	opening sync.Mutex
+
+	mu       sync.Mutex
+	client   UserDBClient
+	checked  time.Time
+	checking bool
+	closed   bool
+}
+
+// pinger is implemented by user database clients with connections to check.
+type pinger interface {
+	Ping() error
+}
+
+// pooledClient is a client borrowed from a DBPool. Closing it returns it to
+// the pool rather than closing its connections.
+type pooledClient struct {
+	UserDBClient
+}
+
+func (pc pooledClient) Close() {}
+
+// NewDBPool returns a pool of the user database at the given host, port, and
+// database name. The database isn't connected to until the pool's client is
+// first borrowed.
+func NewDBPool(cfg *config.Config, host, port, database string) *DBPool {
+	pool := &DBPool{cfg: cfg, interval: PingInterval(cfg)}
+	pool.open = func() (UserDBClient, model.ServiceError) {
+		return GetDbClient(cfg, host, port, database)
+	}
+
+	return pool
+}
+
+// PingInterval returns the configured period between health checks of the
+// user database pool.
+func PingInterval(cfg *config.Config) time.Duration {
+	if dval, err := cfg.Duration(model.KEY_USERDB_PING_INTERVAL); err == nil {
+		return dval
+	}
+
+	return PING_INTERVAL
+}
+
+// Client borrows the pool's client, opening it if not yet open, and starts
+// its health check if due. The borrowed client's Close function doesn't
+// close the pool's client (see Close).
+func (pool *DBPool) Client() (UserDBClient, model.ServiceError) {
+	pool.mu.Lock()
+	if pool.closed {
+		pool.mu.Unlock()
+		return nil, model.DbClientError.WithCause(errPoolClosed)
+	}
+	client := pool.client
+	due := client != nil && !pool.checking && time.Since(pool.checked) >= pool.interval
+	if due {
+		pool.checking = true
+	}
+	pool.mu.Unlock()
+
+	if due {
+		go pool.check(client)
+	}
+
+	if client == nil {
+		var serr model.ServiceError
+		if client, serr = pool.connect(); serr.IsError() {
+			return nil, serr
+		}
+	}
+
+	return pooledClient{client}, model.NoError
+}
+
+// connect opens the pool's client, unless opened concurrently, and verifies
+// it reaches the database. Borrowers wait for the client being opened, but
+// not for each other's health checks.
+func (pool *DBPool) connect() (UserDBClient, model.ServiceError) {
+	pool.opening.Lock()
+	defer pool.opening.Unlock()
+
+	pool.mu.Lock()
+	client, closed := pool.client, pool.closed
+	pool.mu.Unlock()
+	if closed {
+		return nil, model.DbClientError.WithCause(errPoolClosed)
+	}
+	if client != nil {
+		return client, model.NoError
+	}
+
+	client, serr := pool.open()
+	if serr.IsError() {
+		return nil, serr
+	}
+	if err := ping(client); err != nil {
+		client.Close()
+		return nil, model.DbOpenError.WithCause(err)
+	}
+
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	if pool.closed {
+		client.Close()
+		return nil, model.DbClientError.WithCause(errPoolClosed)
+	}
+	pool.client = client
+	pool.checked = time.Now()
+	traceIt(pool.cfg, fmt.Sprintf("Opened user database pool of %v.", client.HostUrl()))
+
+	return client, model.NoError
+}
+
+// check health checks the given client of the pool.
+func (pool *DBPool) check(client UserDBClient) {
+	err := ping(client)
+
+	pool.mu.Lock()
+	pool.checking = false
+	pool.checked = time.Now()
+	pool.mu.Unlock()
+
+	if err != nil {
+		util.LogIt("Cloudtacts", fmt.Sprintf("User database health check failed, connections are re-established as used: %v", err))
+	}
+}
+
+// Close closes the pool's client, if open, e.g. when its process shuts down.
+// The pool can't be borrowed from once closed.
+func (pool *DBPool) Close() {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	if pool.client != nil {
+		pool.client.Close()
+		pool.client = nil
+	}
+	pool.closed = true
+}
+
+func ping(client UserDBClient) error {
+	if p, ok := client.(pinger); ok {
+		return p.Ping()
+	}
+
+	return nil
+}
@@ -0,0 +1,179 @@
+package auth
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"Cloudtacts/pkg/model"
+)
+
+// pingClient is an in-memory client with failing or blocked health checks on
+// demand.
+type pingClient struct {
+	*memoryClient
+	mu     sync.Mutex
+	down   bool
+	block  chan struct{}
+	pings  int
+	closed bool
+}
+
+func (pc *pingClient) Ping() error {
+	pc.mu.Lock()
+	pc.pings++
+	down, block := pc.down, pc.block
+	pc.mu.Unlock()
+
+	if block != nil {
+		<-block
+	}
+	if down {
+		return errors.New("connection refused")
+	}
+	return nil
+}
+
+func (pc *pingClient) Close() {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	pc.closed = true
+}
+
+func (pc *pingClient) state() (pings int, closed bool) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	return pc.pings, pc.closed
+}
+
+// waitChecked waits for the pool's running health check, if any, to finish.
+func waitChecked(t *testing.T, pool *DBPool) {
+	for i := 0; i < 200; i++ {
+		pool.mu.Lock()
+		checking := pool.checking
+		pool.mu.Unlock()
+		if !checking {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatal("Pool's health check didn't finish.")
+}
+
+func TestDBPoolReuse(t *testing.T) {
+	opened := 0
+	pool := &DBPool{cfg: cfg, interval: time.Hour}
+	pool.open = func() (UserDBClient, model.ServiceError) {
+		opened++
+		return &pingClient{memoryClient: newMemoryClient()}, model.NoError
+	}
+
+	if opened != 0 {
+		t.Fatal("Pool opened before first use.")
+	}
+
+	for i := 0; i < 3; i++ {
+		uc, serr := pool.Client()
+		if serr.IsError() {
+			t.Fatalf("Error borrowing client: %v", serr)
+		}
+		uc.Close()
+	}
+	if opened != 1 {
+		t.Errorf("Expected pool to open its client once, opened %d times.", opened)
+	}
+	if _, closed := pool.client.(*pingClient).state(); closed {
+		t.Error("Returning a borrowed client closed the pool's client.")
+	}
+
+	pool.Close()
+	if _, serr := pool.Client(); serr.Code != model.DbClientError.Code {
+		t.Errorf("Expected %v borrowing from closed pool, got: %v", model.DbClientError, serr)
+	}
+}
+
+func TestDBPoolHealthCheck(t *testing.T) {
+	var clients []*pingClient
+	pool := &DBPool{cfg: cfg, interval: 0}
+	pool.open = func() (UserDBClient, model.ServiceError) {
+		pc := &pingClient{memoryClient: newMemoryClient()}
+		clients = append(clients, pc)
+		return pc, model.NoError
+	}
+	defer pool.Close()
+
+	if _, serr := pool.Client(); serr.IsError() {
+		t.Fatalf("Error borrowing client: %v", serr)
+	}
+
+	clients[0].mu.Lock()
+	clients[0].down = true
+	clients[0].mu.Unlock()
+	for i := 0; i < 2; i++ {
+		if _, serr := pool.Client(); serr.IsError() {
+			t.Fatalf("Error borrowing client failing its health check: %v", serr)
+		}
+		waitChecked(t, pool)
+	}
+	if pings, closed := clients[0].state(); len(clients) != 1 || closed || pings != 3 {
+		t.Errorf("Expected client checked 3 times and kept open, got %d ping(s) of %d client(s), closed %v.", pings, len(clients), closed)
+	}
+}
+
+func TestDBPoolSlowHealthCheck(t *testing.T) {
+	pc := &pingClient{memoryClient: newMemoryClient()}
+	pool := &DBPool{cfg: cfg, interval: 0}
+	pool.open = func() (UserDBClient, model.ServiceError) {
+		return pc, model.NoError
+	}
+	defer pool.Close()
+
+	if _, serr := pool.Client(); serr.IsError() {
+		t.Fatalf("Error borrowing client: %v", serr)
+	}
+
+	block := make(chan struct{})
+	pc.mu.Lock()
+	pc.block = block
+	pc.mu.Unlock()
+
+	borrowed := make(chan model.ServiceError)
+	go func() {
+		for i := 0; i < 3; i++ {
+			_, serr := pool.Client()
+			borrowed <- serr
+		}
+	}()
+	for i := 0; i < 3; i++ {
+		select {
+		case serr := <-borrowed:
+			if serr.IsError() {
+				t.Fatalf("Error borrowing client: %v", serr)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Borrowing blocked on the pool's health check.")
+		}
+	}
+
+	close(block)
+	waitChecked(t, pool)
+	if pings, _ := pc.state(); pings != 2 {
+		t.Errorf("Expected one health check running at a time, got %d ping(s).", pings)
+	}
+}
+
+func TestDBPoolOpenFailure(t *testing.T) {
+	pool := &DBPool{cfg: cfg, interval: 0}
+	pool.open = func() (UserDBClient, model.ServiceError) {
+		return &pingClient{memoryClient: newMemoryClient(), down: true}, model.NoError
+	}
+	defer pool.Close()
+
+	if _, serr := pool.Client(); serr.Code != model.DbOpenError.Code {
+		t.Errorf("Expected %v borrowing unreachable client, got: %v", model.DbOpenError, serr)
+	}
+	if pool.client != nil {
+		t.Error("Pool kept unreachable client.")
+	}
+}
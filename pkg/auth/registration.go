@@ -0,0 +1,272 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"Cloudtacts/pkg/config"
+	"Cloudtacts/pkg/model"
+	"Cloudtacts/pkg/storage"
+	"Cloudtacts/pkg/util"
+)
+
+const (
+	SELECT_UNVALIDATED_USERS string = "SELECT ctuser, ctprof, uemail, ctppic, llogin FROM `user` WHERE uvalid IS NULL AND llogin < ?"
+	DELETE_UNVALIDATED_USER  string = "DELETE FROM `user` WHERE ctuser = ? AND ctprof = ? AND uemail = ? AND uvalid IS NULL AND llogin < ?"
+
+	// Default period in which a new user must confirm their registration (15
+	// minutes).
+	VALIDATION_WINDOW = 15 * time.Minute
+
+	// Attempts of a registration conflicting with concurrent ones
+	REGISTER_ATTEMPTS = 3
+)
+
+func (uc *userClient) UnvalidatedUsers(before time.Time) ([]model.User, model.ServiceError) {
+	return uc.UnvalidatedUsersContext(context.Background(), before)
+}
+
+func (uc *userClient) UnvalidatedUsersContext(ctx context.Context, before time.Time) ([]model.User, model.ServiceError) {
+	ctx, cancel := uc.queryContext(ctx)
+	defer cancel()
+
+	rows, err := uc.conn.QueryContext(ctx, uc.dialect.Rebind(SELECT_UNVALIDATED_USERS), before.UTC())
+	if err != nil {
+		return nil, dbError(model.DbQueryError, err)
+	}
+	defer rows.Close()
+
+	users := []model.User{}
+	for rows.Next() {
+		var user model.User
+		var ctppic, llogin []byte
+		if err = rows.Scan(&user.CtUser, &user.CtProf, &user.UEmail, &ctppic, &llogin); err != nil {
+			return nil, dbError(model.DbScanError, err)
+		}
+		user.CtPpic = string(ctppic)
+		user.LLogin = string(llogin)
+		users = append(users, user)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, dbError(model.DbResultsError, err)
+	}
+
+	return users, model.NoError
+}
+
+func (uc *userClient) DeleteUnvalidatedUser(user *model.User, before time.Time) (bool, model.ServiceError) {
+	return uc.DeleteUnvalidatedUserContext(context.Background(), user, before)
+}
+
+func (uc *userClient) DeleteUnvalidatedUserContext(ctx context.Context, user *model.User, before time.Time) (bool, model.ServiceError) {
+	if ok, err := validateUserKey(user); !ok {
+		return false, model.InvalidKeyError.WithCause(err)
+	}
+
+	count, serr := execCount(ctx, uc, uc.conn, DELETE_UNVALIDATED_USER, user.CtUser, user.CtProf, user.UEmail, before.UTC())
+	return count > 0, serr
+}
+
+// ValidationWindow returns the configured period in which a new user must
+// confirm their registration.
+func ValidationWindow(cfg *config.Config) time.Duration {
+	if dval, err := cfg.Duration(model.KEY_USERDB_VALIDATION_WINDOW); err == nil {
+		return dval
+	}
+
+	return VALIDATION_WINDOW
+}
+
+// RegisterUser adds the referenced new user to the database with a new
+// activation token and its registration time as its last login, and saves
+// its Base64 encoded profile image, if any, to the given object store. The
+// user is inserted in a transaction committed only once the image is saved,
+// so a duplicate user is rejected before its image is saved, and the image
+// is deleted if the transaction then fails to commit. The user's image is
+// replaced with its tagged object key. The store is only used if the user
+// has an image to save. A user violating the configured uniqueness policy
+// (see UniquenessPolicyOf) is rejected with DbPKeyError as a duplicate; the
+// transaction is serializable, so concurrent registrations can't both pass
+// the policy, and is retried up to REGISTER_ATTEMPTS times when it conflicts
+// with another. A user with an invalid e-mail address is rejected with
+// InvalidKeyError.
+func RegisterUser(ctx context.Context, cfg *config.Config, uc UserDBClient, store storage.ObjectStore, user *model.User) model.ServiceError {
+	var image []byte
+	var imageKey string
+
+	if ok, err := validateEmail(user.UEmail); !ok {
+		return model.InvalidKeyError.WithCause(err)
+	}
+
+	policy, serr := UniquenessPolicyOf(cfg)
+	if serr.IsError() {
+		return serr
+	}
+
+	if len(user.CtPpic) > 0 && !user.HasProfilePicKey() {
+		if image, serr = storage.DecodeProfilePic(user); serr.IsError() {
+			return serr
+		}
+	}
+
+	// the user ID is the server's to give: a client's could be another user's
+	// login (see SELECT_USERS_BY_LOGIN), or key another user's image
+	nuser := user.Clone()
+	nuser.UserId = uuid.New().String()
+	nuser.LLogin = time.Now().UTC().Format(model.FMT_DATETIME_GO)
+	nuser.AToken = uuid.New().String()
+	if image != nil {
+		imageKey = storage.ProfilePicKey(nuser)
+		nuser.CtPpic = fmt.Sprintf("%v%v", model.OBJK_TAG, imageKey)
+	}
+
+	for attempt := 1; ; attempt++ {
+		serr = registerUser(ctx, uc, store, policy, nuser, image, imageKey)
+		if serr.Code != model.DbConflictError.Code || attempt == REGISTER_ATTEMPTS {
+			break
+		}
+		util.LogIt("Cloudtacts", fmt.Sprintf("Retrying registration of user %v/%v: %v", nuser.CtUser, nuser.CtProf, serr))
+	}
+	if serr.IsError() {
+		return serr
+	}
+
+	user.UserId = nuser.UserId
+	user.CtPpic = nuser.CtPpic
+	user.LLogin = nuser.LLogin
+	user.AToken = nuser.AToken
+
+	return model.NoError
+}
+
+// registerUser adds the referenced new user, checked against the given
+// uniqueness policy, in a transaction committed once the given image, if
+// any, is saved with the given key.
+func registerUser(ctx context.Context, uc UserDBClient, store storage.ObjectStore, policy UniquenessPolicy, user *model.User, image []byte, imageKey string) model.ServiceError {
+	tx, serr := uc.BeginContext(ctx)
+	if serr.IsError() {
+		return serr
+	}
+	defer tx.Rollback()
+
+	if serr = policy.check(tx, user); serr.IsError() {
+		return serr
+	}
+	if serr = tx.AddUser(user); serr.IsError() {
+		return serr
+	}
+
+	if image != nil {
+		if serr = store.SaveObject(imageKey, image); serr.IsError() {
+			util.LogIt("Cloudtacts", fmt.Sprintf("Failed to save pic to object storage: %v", serr))
+			return serr
+		}
+	}
+
+	if serr = tx.Commit(); serr.IsError() {
+		if image != nil {
+			// compensate for the saved image, which no user now references
+			if derr := store.DeleteObject(imageKey); derr.IsError() {
+				util.LogIt("Cloudtacts", fmt.Sprintf("Error deleting profile image %v of failed registration: %v", imageKey, derr))
+			}
+		}
+		return serr
+	}
+
+	return model.NoError
+}
+
+// RemoveUserData deletes the referenced user's information, including its
+// profile image in object storage, from the database. The database requests
+// are cancelled when the given context is done.
+func RemoveUserData(ctx context.Context, cfg *config.Config, uc UserDBClient, user *model.User) model.ServiceError {
+	var serr model.ServiceError
+
+	quser := user.Clone()
+	serr = uc.UserInfoContext(ctx, quser)
+
+	if !serr.IsError() {
+		if len(quser.CtPpic) > 0 {
+			quser.CtImgt = util.ImageFileType(quser.CtPpic)
+		}
+
+		if quser.HasProfilePicKey() {
+			_, serr = storage.DeleteProfilePic(cfg, quser)
+			if serr.IsError() {
+				util.LogIt("Cloudtacts", fmt.Sprintf("Error attempting to delete profile image: %v", serr))
+			}
+		}
+	}
+
+	if !serr.IsError() {
+		serr = uc.DeleteUserContext(ctx, user)
+	}
+
+	return serr
+}
+
+// RemoveUnvalidatedUser deletes the referenced user's information, as queried
+// from the database, if the user still hasn't confirmed their registration
+// and was added before the given time, and then its profile image in object
+// storage. Returns true if the user was removed; a user confirming their
+// registration concurrently is kept, along with their image.
+func RemoveUnvalidatedUser(ctx context.Context, cfg *config.Config, uc UserDBClient, user *model.User, before time.Time) (bool, model.ServiceError) {
+	removed, serr := uc.DeleteUnvalidatedUserContext(ctx, user, before)
+	if serr.IsError() || !removed {
+		return false, serr
+	}
+
+	if user.HasProfilePicKey() {
+		if _, serr = storage.DeleteProfilePic(cfg, user); serr.IsError() {
+			// the user's gone, the image is only orphaned
+			util.LogIt("Cloudtacts", fmt.Sprintf("Error deleting profile image of removed user %v/%v: %v", user.CtUser, user.CtProf, serr))
+		}
+	}
+
+	return true, model.NoError
+}
+
+// SweepUnvalidatedUsers removes the data of new users who haven't confirmed
+// their registration within the given window, and returns the users
+// removed. In dry run mode, the users are returned but not removed. Users
+// which fail to be removed are logged and skipped, as are users who confirm
+// their registration during the sweep. The sweep stops when the given
+// context is done.
+func SweepUnvalidatedUsers(ctx context.Context, cfg *config.Config, uc UserDBClient, window time.Duration, dryRun bool) ([]model.User, model.ServiceError) {
+	before := time.Now().UTC().Add(-window)
+	users, serr := uc.UnvalidatedUsersContext(ctx, before)
+	if serr.IsError() {
+		return nil, serr
+	}
+
+	removed := []model.User{}
+	for i := range users {
+		if serr = contextError(ctx); serr.IsError() {
+			return removed, serr
+		}
+
+		user := &users[i]
+		if dryRun {
+			util.LogIt("Cloudtacts", fmt.Sprintf("Would remove unvalidated user %v/%v <%v> added on %v.", user.CtUser, user.CtProf, user.UEmail, user.LLogin))
+			removed = append(removed, *user)
+			continue
+		}
+
+		ok, serr := RemoveUnvalidatedUser(ctx, cfg, uc, user, before)
+		if serr.IsError() {
+			util.LogIt("Cloudtacts", fmt.Sprintf("Error removing unvalidated user %v/%v <%v>: %v", user.CtUser, user.CtProf, user.UEmail, serr))
+			continue
+		}
+		if !ok {
+			util.LogIt("Cloudtacts", fmt.Sprintf("Kept user %v/%v <%v> validated during the sweep.", user.CtUser, user.CtProf, user.UEmail))
+			continue
+		}
+		util.LogIt("Cloudtacts", fmt.Sprintf("Removed unvalidated user %v/%v <%v> added on %v.", user.CtUser, user.CtProf, user.UEmail, user.LLogin))
+		removed = append(removed, *user)
+	}
+
+	return removed, model.NoError
+}
@@ -0,0 +1,342 @@
+package auth
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"Cloudtacts/pkg/model"
+	"Cloudtacts/pkg/storage"
+)
+
+func TestSweepUnvalidatedUsers(t *testing.T) {
+	uc := connect(t)
+	defer uc.Close()
+
+	now := time.Now().UTC()
+	users := []*model.User{
+		// abandoned, recent, and validated registrations
+		{CtUser: "sweep1", CtPass: "H:0", CtProf: "Sweep", UEmail: "sweep1@example.com", LLogin: now.Add(-time.Hour).Format(model.FMT_DATETIME_GO)},
+		{CtUser: "sweep2", CtPass: "H:0", CtProf: "Sweep", UEmail: "sweep2@example.com", LLogin: now.Format(model.FMT_DATETIME_GO)},
+		{CtUser: "sweep3", CtPass: "H:0", CtProf: "Sweep", UEmail: "sweep3@example.com", LLogin: now.Add(-time.Hour).Format(model.FMT_DATETIME_GO), UValid: now.Format(model.FMT_DATETIME_GO)},
+	}
+	for _, user := range users {
+		if serr := uc.AddUser(user); serr.IsError() {
+			t.Fatalf("Error adding user: %v", serr)
+		}
+		if serr := uc.UpdateUser(user); serr.IsError() {
+			t.Fatalf("Error updating user: %v", serr)
+		}
+		defer uc.DeleteUser(user)
+	}
+
+	removed, serr := SweepUnvalidatedUsers(context.Background(), cfg, uc, VALIDATION_WINDOW, true)
+	if serr.IsError() {
+		t.Fatalf("Error sweeping users: %v", serr)
+	}
+	if !sweptOnly(removed, users[0]) {
+		t.Errorf("Expected dry run to report %v, got: %v", users[0].CtUser, removed)
+	}
+	if serr = uc.UserInfo(users[0].Clone()); serr.IsError() {
+		t.Errorf("Dry run removed user: %v", serr)
+	}
+
+	removed, serr = SweepUnvalidatedUsers(context.Background(), cfg, uc, VALIDATION_WINDOW, false)
+	if serr.IsError() {
+		t.Fatalf("Error sweeping users: %v", serr)
+	}
+	if !sweptOnly(removed, users[0]) {
+		t.Errorf("Expected sweep to remove %v, got: %v", users[0].CtUser, removed)
+	}
+	if serr = uc.UserInfo(users[0].Clone()); serr != model.DbPKeyMissingError {
+		t.Errorf("Expected %v querying swept user, got: %v", model.DbPKeyMissingError, serr)
+	}
+	for _, user := range users[1:] {
+		if serr = uc.UserInfo(user.Clone()); serr.IsError() {
+			t.Errorf("Error querying unswept user %v: %v", user.CtUser, serr)
+		}
+	}
+}
+
+func TestRemoveUnvalidatedUser(t *testing.T) {
+	uc := connect(t)
+	defer uc.Close()
+
+	now := time.Now().UTC()
+	user := &model.User{CtUser: "sweep4", CtPass: "H:0", CtProf: "Sweep", UEmail: "sweep4@example.com", LLogin: now.Add(-time.Hour).Format(model.FMT_DATETIME_GO)}
+	if serr := uc.AddUser(user); serr.IsError() {
+		t.Fatalf("Error adding user: %v", serr)
+	}
+	defer uc.DeleteUser(user)
+
+	// queried for a sweep, then validated before it's removed
+	swept, serr := uc.UnvalidatedUsers(now.Add(-VALIDATION_WINDOW))
+	if serr.IsError() || !sweptOnly(swept, user) {
+		t.Fatalf("Expected unvalidated user %v, got %v: %v", user.CtUser, swept, serr)
+	}
+	validated := user.Clone()
+	validated.UValid = now.Format(model.FMT_DATETIME_GO)
+	if serr = uc.UpdateUser(validated); serr.IsError() {
+		t.Fatalf("Error updating user: %v", serr)
+	}
+
+	for _, sweptUser := range swept {
+		if sweptUser.CtProf != user.CtProf {
+			continue
+		}
+		if removed, serr := RemoveUnvalidatedUser(context.Background(), cfg, uc, &sweptUser, now.Add(-VALIDATION_WINDOW)); removed || serr.IsError() {
+			t.Errorf("Expected validated user kept, got removed %v: %v", removed, serr)
+		}
+	}
+	if serr = uc.UserInfo(user.Clone()); serr.IsError() {
+		t.Errorf("Error querying validated user: %v", serr)
+	}
+
+	// not yet expired
+	recent := &model.User{CtUser: "sweep5", CtPass: "H:0", CtProf: "Sweep", UEmail: "sweep5@example.com", LLogin: now.Format(model.FMT_DATETIME_GO)}
+	if serr = uc.AddUser(recent); serr.IsError() {
+		t.Fatalf("Error adding user: %v", serr)
+	}
+	defer uc.DeleteUser(recent)
+	if removed, serr := uc.DeleteUnvalidatedUser(recent, now.Add(-VALIDATION_WINDOW)); removed || serr.IsError() {
+		t.Errorf("Expected recent user kept, got removed %v: %v", removed, serr)
+	}
+}
+
+// sweptOnly returns true if the given swept users are only (among the test
+// users) the referenced user.
+func sweptOnly(swept []model.User, user *model.User) bool {
+	found := false
+	for _, s := range swept {
+		if s.CtProf != user.CtProf {
+			continue
+		}
+		if s.CtUser != user.CtUser {
+			return false
+		}
+		found = true
+	}
+
+	return found
+}
+
+func TestRegisterUser(t *testing.T) {
+	image := []byte("GIF89a")
+	newUser := func() *model.User {
+		return &model.User{CtUser: "reg1", CtPass: "H:0", CtProf: "Register", UEmail: "reg1@example.com",
+			CtPpic: base64.StdEncoding.EncodeToString(image), CtImgt: "gif"}
+	}
+
+	t.Run("registered", func(t *testing.T) {
+		uc, store := newMemoryClient(), newFakeStore()
+
+		user := newUser()
+		if serr := RegisterUser(context.Background(), cfg, uc, store, user); serr.IsError() {
+			t.Fatalf("Error registering user: %v", serr)
+		}
+		imageKey := storage.ProfilePicKey(user)
+		if !strings.HasPrefix(imageKey, user.UserId+"/") || user.CtPpic != model.OBJK_TAG+imageKey || len(user.AToken) == 0 || len(user.LLogin) == 0 {
+			t.Errorf("Expected registered user's image key, token and last login, got: %v", user)
+		}
+
+		quser := newUser()
+		if serr := uc.UserInfo(quser); serr.IsError() {
+			t.Fatalf("Error querying registered user: %v", serr)
+		}
+		if quser.CtPpic != user.CtPpic || quser.AToken != user.AToken || quser.LLogin != user.LLogin {
+			t.Errorf("Expected %v in database, got: %v", user, quser)
+		}
+		if string(store.objects[imageKey]) != string(image) {
+			t.Errorf("Expected image saved as %v, got: %v", imageKey, store.objects)
+		}
+	})
+
+	t.Run("duplicate", func(t *testing.T) {
+		uc, store := newMemoryClient(), newFakeStore()
+
+		existing := newUser()
+		existing.UserId = "f1d2c3b4-0000-4000-8000-000000000001"
+		imageKey := storage.ProfilePicKey(existing)
+		existing.CtPpic = model.OBJK_TAG + imageKey
+		if serr := uc.AddUser(existing); serr.IsError() {
+			t.Fatalf("Error adding user: %v", serr)
+		}
+		store.objects[imageKey] = []byte("existing")
+
+		if serr := RegisterUser(context.Background(), cfg, uc, store, newUser()); serr.Code != model.DbPKeyError.Code {
+			t.Errorf("Expected %v registering existing user, got: %v", model.DbPKeyError, serr)
+		}
+		if string(store.objects[imageKey]) != "existing" || len(store.deleted) > 0 {
+			t.Errorf("Expected existing user's image untouched, got: %v, deleted %v", store.objects, store.deleted)
+		}
+	})
+
+	t.Run("user ID ignored", func(t *testing.T) {
+		uc := newMemoryClient()
+
+		victim := &model.User{CtUser: "victim1", CtPass: "H:0", CtProf: "Home", UEmail: "victim1@example.com"}
+		if serr := RegisterUser(context.Background(), cfg, uc, nil, victim); serr.IsError() {
+			t.Fatalf("Error registering user: %v", serr)
+		}
+		user := &model.User{UserId: victim.UEmail, CtUser: "reg1", CtPass: "H:0", CtProf: "Register", UEmail: "reg1@example.com"}
+		if serr := RegisterUser(context.Background(), cfg, uc, nil, user); serr.IsError() {
+			t.Fatalf("Error registering user: %v", serr)
+		}
+		if user.UserId == victim.UEmail || len(user.UserId) == 0 {
+			t.Errorf("Expected a new user ID, got: %v", user.UserId)
+		}
+		if users, serr := uc.UsersByLogin(victim.UEmail); serr.IsError() || len(users) != 1 || users[0].UserId != victim.UserId {
+			t.Errorf("Expected the victim's login only, got %v: %v", users, serr)
+		}
+
+		// as with SQL's unique uid index
+		dup := &model.User{UserId: victim.UserId, CtUser: "reg2", CtPass: "H:0", CtProf: "Register", UEmail: "reg2@example.com"}
+		if serr := uc.AddUser(dup); serr.Code != model.DbPKeyError.Code {
+			t.Errorf("Expected %v adding a duplicate user ID, got: %v", model.DbPKeyError, serr)
+		}
+		tx, _ := uc.BeginContext(context.Background())
+		defer tx.Rollback()
+		if serr := tx.AddUser(dup); serr.Code != model.DbPKeyError.Code {
+			t.Errorf("Expected %v adding a duplicate user ID in a transaction, got: %v", model.DbPKeyError, serr)
+		}
+	})
+
+	t.Run("image not saved", func(t *testing.T) {
+		uc, store := newMemoryClient(), newFakeStore()
+		store.saveErr = model.CloudStorageError.WithCause(fmt.Errorf("bucket unavailable"))
+
+		if serr := RegisterUser(context.Background(), cfg, uc, store, newUser()); serr.Code != model.CloudStorageError.Code {
+			t.Errorf("Expected %v, got: %v", model.CloudStorageError, serr)
+		}
+		if serr := uc.UserInfo(newUser()); serr != model.DbPKeyMissingError {
+			t.Errorf("Expected no user after failed registration, got: %v", serr)
+		}
+	})
+
+	t.Run("not committed", func(t *testing.T) {
+		uc, store := &commitFailingClient{newMemoryClient()}, newFakeStore()
+
+		if serr := RegisterUser(context.Background(), cfg, uc, store, newUser()); serr.Code != model.DbTransactionError.Code {
+			t.Errorf("Expected %v, got: %v", model.DbTransactionError, serr)
+		}
+		if serr := uc.UserInfo(newUser()); serr != model.DbPKeyMissingError {
+			t.Errorf("Expected no user after failed registration, got: %v", serr)
+		}
+		if len(store.objects) > 0 || len(store.deleted) != 1 {
+			t.Errorf("Expected the saved image deleted, got: %v, deleted %v", store.objects, store.deleted)
+		}
+	})
+
+	t.Run("shared login and profile not committed", func(t *testing.T) {
+		memory, store := newMemoryClient(), newFakeStore()
+
+		// the collective policy admits users sharing a login and profile
+		existing := newUser()
+		if serr := RegisterUser(context.Background(), cfg, memory, store, existing); serr.IsError() {
+			t.Fatalf("Error registering user: %v", serr)
+		}
+		imageKey := strings.TrimPrefix(existing.CtPpic, model.OBJK_TAG)
+
+		user := newUser()
+		user.UEmail = "reg1@example.org"
+		user.CtPpic = base64.StdEncoding.EncodeToString([]byte("GIF89a other"))
+		if serr := RegisterUser(context.Background(), cfg, &commitFailingClient{memory}, store, user); serr.Code != model.DbTransactionError.Code {
+			t.Errorf("Expected %v, got: %v", model.DbTransactionError, serr)
+		}
+		if string(store.objects[imageKey]) != string(image) || len(store.deleted) != 1 || store.deleted[0] == imageKey {
+			t.Errorf("Expected existing user's image %v untouched, got: %v, deleted %v", imageKey, store.objects, store.deleted)
+		}
+	})
+
+	t.Run("invalid e-mail address", func(t *testing.T) {
+		for _, uemail := range []string{"reg1", "Reg <reg1@example.com>", "reg1@example.com\r\nBcc: other@example.com"} {
+			uc, store := newMemoryClient(), newFakeStore()
+
+			user := newUser()
+			user.UEmail = uemail
+			if serr := RegisterUser(context.Background(), cfg, uc, store, user); serr.Code != model.InvalidKeyError.Code || serr.Cause != model.BadEmailAddressError {
+				t.Errorf("Expected %v of e-mail address %q, got: %v", model.InvalidKeyError, uemail, serr)
+			}
+			if len(store.objects) != 0 {
+				t.Errorf("Expected no image saved of e-mail address %q, got: %v", uemail, store.objects)
+			}
+		}
+	})
+
+	t.Run("invalid image", func(t *testing.T) {
+		uc, store := newMemoryClient(), newFakeStore()
+
+		user := newUser()
+		user.CtPpic = "not Base64!"
+		if serr := RegisterUser(context.Background(), cfg, uc, store, user); serr.Code != model.ImageDecodingError.Code {
+			t.Errorf("Expected %v, got: %v", model.ImageDecodingError, serr)
+		}
+		if serr := uc.UserInfo(newUser()); serr != model.DbPKeyMissingError {
+			t.Errorf("Expected no user after failed registration, got: %v", serr)
+		}
+	})
+}
+
+// fakeStore is an in-memory object store which records deleted objects, and
+// fails to save objects when given a save error.
+type fakeStore struct {
+	objects map[string][]byte
+	deleted []string
+	saveErr model.ServiceError
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{objects: map[string][]byte{}}
+}
+
+func (fs *fakeStore) SaveObject(key string, data []byte) model.ServiceError {
+	if fs.saveErr.IsError() {
+		return fs.saveErr
+	}
+	fs.objects[key] = data
+	return model.NoError
+}
+
+func (fs *fakeStore) ReadObject(key string) ([]byte, model.ServiceError) {
+	return fs.objects[key], model.NoError
+}
+
+func (fs *fakeStore) DeleteObject(key string) model.ServiceError {
+	delete(fs.objects, key)
+	fs.deleted = append(fs.deleted, key)
+	return model.NoError
+}
+
+func (fs *fakeStore) ObjectExists(key string) (bool, model.ServiceError) {
+	_, ok := fs.objects[key]
+	return ok, model.NoError
+}
+
+func (fs *fakeStore) StoreUrl() string {
+	return "fake"
+}
+
+func (fs *fakeStore) Close() {}
+
+// commitFailingClient is an in-memory user database whose transactions fail
+// to commit.
+type commitFailingClient struct {
+	*memoryClient
+}
+
+func (cc *commitFailingClient) BeginContext(ctx context.Context) (UserTx, model.ServiceError) {
+	tx, serr := cc.memoryClient.BeginContext(ctx)
+	return &commitFailingTx{tx}, serr
+}
+
+type commitFailingTx struct {
+	UserTx
+}
+
+func (ftx *commitFailingTx) Commit() model.ServiceError {
+	ftx.UserTx.Rollback()
+	return model.DbTransactionError.WithCause(fmt.Errorf("connection lost"))
+}
@@ -0,0 +1,337 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"Cloudtacts/pkg/config"
+	"Cloudtacts/pkg/model"
+	"Cloudtacts/pkg/util"
+)
+
+const (
+	SELECT_SESSION_INFO  string = "SELECT ctuser, ctprof, uemail, rthash, device, created, renewed, expires FROM session WHERE sessid = ?"
+	SELECT_USER_SESSIONS string = "SELECT sessid, rthash, device, created, renewed, expires FROM session WHERE ctuser = ? AND ctprof = ? AND uemail = ? ORDER BY created"
+	INSERT_SESSION_STMT  string = "INSERT INTO session (sessid, ctuser, ctprof, uemail, rthash, device, created, renewed, expires) VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?)"
+	UPDATE_SESSION_STMT  string = "UPDATE session SET rthash = ?, renewed = ?, expires = ? WHERE sessid = ? AND rthash = ?"
+	DELETE_SESSION_STMT  string = "DELETE FROM session WHERE sessid = ?"
+	DELETE_USER_SESSIONS string = "DELETE FROM session WHERE ctuser = ? AND ctprof = ? AND uemail = ?"
+
+	// Default lifetime of a user session's refresh token (30 days).
+	SESSION_LIFETIME = 30 * 24 * time.Hour
+
+	SESSION_SECRET_LEN = 32
+	SESSION_DEVICE_LEN = 255
+)
+
+func (uc *userClient) SessionInfo(sess *model.Session) model.ServiceError {
+	return uc.SessionInfoContext(context.Background(), sess)
+}
+
+func (uc *userClient) SessionInfoContext(ctx context.Context, sess *model.Session) model.ServiceError {
+	if len(sess.SessId) == 0 {
+		return model.InvalidKeyError.WithCause(model.NoSessionIdError)
+	}
+
+	ctx, cancel := uc.queryContext(ctx)
+	defer cancel()
+
+	var device sql.NullString
+	err := uc.conn.QueryRowContext(ctx, uc.dialect.Rebind(SELECT_SESSION_INFO), sess.SessId).Scan(&sess.CtUser, &sess.CtProf, &sess.UEmail,
+		&sess.RtHash, &device, &sess.Created, &sess.Renewed, &sess.Expires)
+	switch {
+	case err == sql.ErrNoRows:
+		return model.DbPKeyMissingError
+	case err != nil:
+		return dbError(model.DbQueryError, err)
+	}
+	sess.Device = device.String
+
+	return model.NoError
+}
+
+func (uc *userClient) UserSessions(user *model.User) ([]model.Session, model.ServiceError) {
+	return uc.UserSessionsContext(context.Background(), user)
+}
+
+func (uc *userClient) UserSessionsContext(ctx context.Context, user *model.User) ([]model.Session, model.ServiceError) {
+	if ok, err := validateUserKey(user); !ok {
+		return nil, model.InvalidKeyError.WithCause(err)
+	}
+
+	ctx, cancel := uc.queryContext(ctx)
+	defer cancel()
+
+	rows, err := uc.conn.QueryContext(ctx, uc.dialect.Rebind(SELECT_USER_SESSIONS), user.CtUser, user.CtProf, user.UEmail)
+	if err != nil {
+		return nil, dbError(model.DbQueryError, err)
+	}
+	defer rows.Close()
+
+	sessions := []model.Session{}
+	for rows.Next() {
+		sess := model.Session{CtUser: user.CtUser, CtProf: user.CtProf, UEmail: user.UEmail}
+		var device sql.NullString
+		if err = rows.Scan(&sess.SessId, &sess.RtHash, &device, &sess.Created, &sess.Renewed, &sess.Expires); err != nil {
+			return nil, dbError(model.DbScanError, err)
+		}
+		sess.Device = device.String
+		sessions = append(sessions, sess)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, dbError(model.DbResultsError, err)
+	}
+
+	return sessions, model.NoError
+}
+
+func (uc *userClient) AddSession(sess *model.Session) model.ServiceError {
+	return uc.AddSessionContext(context.Background(), sess)
+}
+
+func (uc *userClient) AddSessionContext(ctx context.Context, sess *model.Session) model.ServiceError {
+	if ok, err := validateUserKey(sess.Owner()); !ok {
+		return model.InvalidKeyError.WithCause(err)
+	}
+	if len(sess.SessId) == 0 {
+		return model.InvalidKeyError.WithCause(model.NoSessionIdError)
+	}
+
+	serr := execStatement(ctx, uc, INSERT_SESSION_STMT, sess.SessId, sess.CtUser, sess.CtProf, sess.UEmail, sess.RtHash,
+		sess.Device, sess.Created, sess.Renewed, sess.Expires)
+	if serr.IsError() && serr.Code == model.DbExecuteError.Code {
+		serr = model.DbInsertError.WithCause(serr.Cause)
+	}
+
+	return serr
+}
+
+func (uc *userClient) UpdateSession(sess *model.Session, rthash string) model.ServiceError {
+	return uc.UpdateSessionContext(context.Background(), sess, rthash)
+}
+
+func (uc *userClient) UpdateSessionContext(ctx context.Context, sess *model.Session, rthash string) model.ServiceError {
+	if len(sess.SessId) == 0 {
+		return model.InvalidKeyError.WithCause(model.NoSessionIdError)
+	}
+
+	count, serr := execCount(ctx, uc, uc.conn, UPDATE_SESSION_STMT, sess.RtHash, sess.Renewed, sess.Expires, sess.SessId, rthash)
+	if !serr.IsError() && count == 0 {
+		return model.InvalidRefreshError
+	}
+
+	return serr
+}
+
+func (uc *userClient) DeleteSession(sess *model.Session) model.ServiceError {
+	return uc.DeleteSessionContext(context.Background(), sess)
+}
+
+func (uc *userClient) DeleteSessionContext(ctx context.Context, sess *model.Session) model.ServiceError {
+	if len(sess.SessId) == 0 {
+		return model.InvalidKeyError.WithCause(model.NoSessionIdError)
+	}
+
+	return execStatement(ctx, uc, DELETE_SESSION_STMT, sess.SessId)
+}
+
+func (uc *userClient) DeleteUserSessions(user *model.User) model.ServiceError {
+	return uc.DeleteUserSessionsContext(context.Background(), user)
+}
+
+func (uc *userClient) DeleteUserSessionsContext(ctx context.Context, user *model.User) model.ServiceError {
+	if ok, err := validateUserKey(user); !ok {
+		return model.InvalidKeyError.WithCause(err)
+	}
+
+	return execStatement(ctx, uc, DELETE_USER_SESSIONS, user.CtUser, user.CtProf, user.UEmail)
+}
+
+// NewSession returns a new session of the referenced user on the given
+// device along with its refresh token. The session expires after the
+// configured session lifetime unless renewed.
+func NewSession(cfg *config.Config, user *model.User, device string) (*model.Session, string, model.ServiceError) {
+	if ok, err := validateUserKey(user); !ok {
+		return nil, "", model.InvalidKeyError.WithCause(err)
+	}
+
+	if len(device) > SESSION_DEVICE_LEN {
+		device = device[:SESSION_DEVICE_LEN]
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	sess := &model.Session{
+		SessId:  uuid.New().String(),
+		CtUser:  user.CtUser,
+		CtProf:  user.CtProf,
+		UEmail:  user.UEmail,
+		Device:  device,
+		Created: now,
+	}
+
+	token, serr := RenewSession(cfg, sess)
+	if serr.IsError() {
+		return nil, "", serr
+	}
+
+	return sess, token, model.NoError
+}
+
+// RenewSession replaces the referenced session's refresh token with a new
+// one, returned, and extends the session's expiry. Refresh tokens are single
+// use: the session's previous refresh token is no longer valid.
+func RenewSession(cfg *config.Config, sess *model.Session) (string, model.ServiceError) {
+	secret := make([]byte, SESSION_SECRET_LEN)
+	if _, err := rand.Read(secret); err != nil {
+		return "", model.SystemError.WithCause(err)
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(secret)
+
+	sess.RtHash = model.TextDigestOf(encoded)
+	sess.Renewed = time.Now().UTC().Truncate(time.Second)
+	sess.Expires = sess.Renewed.Add(SessionLifetime(cfg))
+
+	return model.RefreshToken(sess.SessId, encoded), model.NoError
+}
+
+// VerifySession verifies the given refresh token secret is that of the
+// referenced session, and that the session belongs to the referenced user
+// and hasn't expired.
+func VerifySession(sess *model.Session, user *model.User, secret string) model.ServiceError {
+	if !sess.OwnedBy(user) || subtle.ConstantTimeCompare([]byte(model.TextDigestOf(secret)), []byte(sess.RtHash)) != 1 {
+		return model.InvalidRefreshError
+	}
+	if sess.IsExpired(time.Now().UTC()) {
+		return model.ExpiredSessionError
+	}
+
+	return model.NoError
+}
+
+// RefreshSession renews the referenced user's session of the given refresh
+// token, returning the session and its new refresh token. Expired sessions
+// are deleted, and sessions whose refresh token is reused, including by a
+// concurrent refresh, are revoked: a previously used refresh token may have
+// been stolen. If a session can't be revoked, the revocation's error is
+// returned, so the client can retry rather than assume it's revoked.
+func RefreshSession(ctx context.Context, cfg *config.Config, uc UserDBClient, user *model.User, rtoken string) (*model.Session, string, model.ServiceError) {
+	sessId, secret, ok := model.ParseRefreshToken(rtoken)
+	if !ok {
+		return nil, "", model.InvalidRefreshError
+	}
+
+	sess := &model.Session{SessId: sessId}
+	if serr := uc.SessionInfoContext(ctx, sess); serr.IsError() {
+		if serr.Code == model.DbPKeyMissingError.Code {
+			// unknown or revoked session
+			serr = model.InvalidRefreshError
+		}
+		return nil, "", serr
+	}
+
+	serr := VerifySession(sess, user, secret)
+	switch {
+	case serr == model.ExpiredSessionError:
+		if derr := uc.DeleteSessionContext(ctx, sess); derr.IsError() {
+			util.LogIt("Cloudtacts", fmt.Sprintf("Error deleting expired session %v: %v", sess.SessId, derr))
+		}
+		return nil, "", serr
+	case serr.IsError() && sess.OwnedBy(user):
+		if rerr := revokeSession(ctx, uc, sess); rerr.IsError() {
+			return nil, "", rerr
+		}
+		return nil, "", serr
+	case serr.IsError():
+		return nil, "", serr
+	}
+
+	rthash := sess.RtHash
+	refresh, serr := RenewSession(cfg, sess)
+	if serr.IsError() {
+		return nil, "", serr
+	}
+	if serr = uc.UpdateSessionContext(ctx, sess, rthash); serr.IsError() {
+		if serr == model.InvalidRefreshError {
+			// renewed concurrently with the same refresh token
+			if rerr := revokeSession(ctx, uc, sess); rerr.IsError() {
+				return nil, "", rerr
+			}
+		}
+		return nil, "", serr
+	}
+
+	return sess, refresh, model.NoError
+}
+
+// revokeSession deletes the referenced session on refresh token reuse, and
+// logs the error if it can't.
+func revokeSession(ctx context.Context, uc UserDBClient, sess *model.Session) model.ServiceError {
+	util.LogIt("Cloudtacts", fmt.Sprintf("Revoking session %v on refresh token reuse.", sess.SessId))
+	serr := uc.DeleteSessionContext(ctx, sess)
+	if serr.IsError() {
+		util.LogIt("Cloudtacts", fmt.Sprintf("Error revoking session %v: %v", sess.SessId, serr))
+	}
+
+	return serr
+}
+
+// SessionLifetime returns the configured lifetime of user sessions.
+func SessionLifetime(cfg *config.Config) time.Duration {
+	if dval, err := cfg.Duration(model.KEY_USERDB_SESSION_LIFETIME); err == nil {
+		return dval
+	}
+
+	return SESSION_LIFETIME
+}
+
+func execStatement(ctx context.Context, uc *userClient, query string, args ...any) model.ServiceError {
+	return execIn(ctx, uc, uc.conn, query, args...)
+}
+
+// preparer prepares statements of a database connection (sql.DB) or
+// transaction (sql.Tx).
+type preparer interface {
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+}
+
+// execIn executes the given statement with the given connection or
+// transaction.
+func execIn(ctx context.Context, uc *userClient, conn preparer, query string, args ...any) model.ServiceError {
+	_, serr := execCount(ctx, uc, conn, query, args...)
+	return serr
+}
+
+// execCount executes the given statement with the given connection or
+// transaction, and returns the number of rows it affected.
+func execCount(ctx context.Context, uc *userClient, conn preparer, query string, args ...any) (int64, model.ServiceError) {
+	ctx, cancel := uc.queryContext(ctx)
+	defer cancel()
+
+	stmt, err := conn.PrepareContext(ctx, uc.dialect.Rebind(query))
+	if err != nil {
+		return 0, dbError(model.DbPrepareError, err)
+	}
+	defer stmt.Close()
+
+	result, err := stmt.ExecContext(ctx, args...)
+	if err != nil {
+		if uc.dialect.IsDuplicate(err) {
+			return 0, model.DbPKeyError.WithCause(err)
+		}
+		return 0, dbError(model.DbExecuteError, err)
+	}
+
+	count, err := result.RowsAffected()
+	if err != nil {
+		return 0, dbError(model.DbResultsError, err)
+	}
+
+	return count, model.NoError
+}
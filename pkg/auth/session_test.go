@@ -0,0 +1,221 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"Cloudtacts/pkg/model"
+)
+
+func TestUserSessions(t *testing.T) {
+	uc := connect(t)
+	defer uc.Close()
+
+	user := testData.Users[0].Clone()
+	defer uc.DeleteUserSessions(user)
+
+	// one session per device
+	sess1, _, serr := NewSession(cfg, user, "device1")
+	if serr.IsError() {
+		t.Fatalf("Error creating session: %v", serr)
+	}
+	sess2, _, _ := NewSession(cfg, user, "device2")
+	for _, sess := range []*model.Session{sess1, sess2} {
+		if serr = uc.AddSession(sess); serr.IsError() {
+			t.Fatalf("Error adding session: %v", serr)
+		}
+	}
+
+	sessions, serr := uc.UserSessions(user)
+	if serr.IsError() {
+		t.Fatalf("Error listing sessions: %v", serr)
+	}
+	if len(sessions) != 2 {
+		t.Fatalf("Expected 2 sessions, got: %v", sessions)
+	}
+
+	qsess := &model.Session{SessId: sess1.SessId}
+	if serr = uc.SessionInfo(qsess); serr.IsError() {
+		t.Fatalf("Error querying session: %v", serr)
+	}
+	if !qsess.OwnedBy(user) || qsess.Device != "device1" || qsess.RtHash != sess1.RtHash || !qsess.Expires.Equal(sess1.Expires) {
+		t.Errorf("Queried session doesn't match added session: %v", qsess)
+	}
+
+	// revoking one session leaves the other
+	if serr = uc.DeleteSession(sess1); serr.IsError() {
+		t.Errorf("Error deleting session: %v", serr)
+	}
+	if serr = uc.SessionInfo(&model.Session{SessId: sess1.SessId}); serr != model.DbPKeyMissingError {
+		t.Errorf("Expected %v querying deleted session, got: %v", model.DbPKeyMissingError, serr)
+	}
+	if sessions, _ = uc.UserSessions(user); len(sessions) != 1 || sessions[0].SessId != sess2.SessId {
+		t.Errorf("Expected remaining session %v, got: %v", sess2.SessId, sessions)
+	}
+
+	if serr = uc.DeleteUserSessions(user); serr.IsError() {
+		t.Errorf("Error deleting user sessions: %v", serr)
+	}
+	if sessions, _ = uc.UserSessions(user); len(sessions) != 0 {
+		t.Errorf("Expected no sessions, got: %v", sessions)
+	}
+}
+
+func TestRenewSession(t *testing.T) {
+	uc := connect(t)
+	defer uc.Close()
+
+	user := testData.Users[0].Clone()
+	defer uc.DeleteUserSessions(user)
+
+	sess, refresh, _ := NewSession(cfg, user, "device1")
+	if serr := uc.AddSession(sess); serr.IsError() {
+		t.Fatalf("Error adding session: %v", serr)
+	}
+
+	sessId, secret, ok := model.ParseRefreshToken(refresh)
+	if !ok || sessId != sess.SessId {
+		t.Fatalf("Malformed refresh token: %v", refresh)
+	}
+	if serr := VerifySession(sess, user, secret); serr.IsError() {
+		t.Errorf("Error verifying session: %v", serr)
+	}
+
+	rthash := sess.RtHash
+	renewed, serr := RenewSession(cfg, sess)
+	if serr.IsError() {
+		t.Fatalf("Error renewing session: %v", serr)
+	}
+	if serr = uc.UpdateSession(sess, rthash); serr.IsError() {
+		t.Fatalf("Error updating session: %v", serr)
+	}
+	if serr = uc.UpdateSession(sess, rthash); serr != model.InvalidRefreshError {
+		t.Errorf("Expected %v updating session renewed since, got: %v", model.InvalidRefreshError, serr)
+	}
+
+	qsess := &model.Session{SessId: sess.SessId}
+	uc.SessionInfo(qsess)
+
+	// refresh tokens are single use
+	if serr = VerifySession(qsess, user, secret); serr != model.InvalidRefreshError {
+		t.Errorf("Expected %v verifying previous refresh token, got: %v", model.InvalidRefreshError, serr)
+	}
+	_, secret, _ = model.ParseRefreshToken(renewed)
+	if serr = VerifySession(qsess, user, secret); serr.IsError() {
+		t.Errorf("Error verifying renewed session: %v", serr)
+	}
+
+	if serr = VerifySession(qsess, testData.Users[1].Clone(), secret); serr != model.InvalidRefreshError {
+		t.Errorf("Expected %v verifying other user's session, got: %v", model.InvalidRefreshError, serr)
+	}
+
+	qsess.Expires = time.Now().UTC().Add(-time.Minute)
+	if serr = VerifySession(qsess, user, secret); serr != model.ExpiredSessionError {
+		t.Errorf("Expected %v verifying expired session, got: %v", model.ExpiredSessionError, serr)
+	}
+}
+
+func TestRefreshSessionConcurrently(t *testing.T) {
+	const refreshes = 8
+
+	uc := connect(t)
+	defer uc.Close()
+
+	user := testData.Users[0].Clone()
+	defer uc.DeleteUserSessions(user)
+
+	sess, refresh, _ := NewSession(cfg, user, "device1")
+	if serr := uc.AddSession(sess); serr.IsError() {
+		t.Fatalf("Error adding session: %v", serr)
+	}
+
+	var wg sync.WaitGroup
+	results := make([]model.ServiceError, refreshes)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, _, results[i] = RefreshSession(context.Background(), cfg, uc, user, refresh)
+		}(i)
+	}
+	wg.Wait()
+
+	refreshed := 0
+	for _, serr := range results {
+		switch serr.Code {
+		case model.NoError.Code:
+			refreshed++
+		case model.InvalidRefreshError.Code:
+		default:
+			t.Errorf("Expected %v of a concurrent refresh, got: %v", model.InvalidRefreshError, serr)
+		}
+	}
+	if refreshed != 1 {
+		t.Errorf("Expected 1 session refreshed, got %d", refreshed)
+	}
+
+	// the refresh token was reused, the session is revoked
+	if serr := uc.SessionInfo(&model.Session{SessId: sess.SessId}); serr != model.DbPKeyMissingError {
+		t.Errorf("Expected %v querying session of reused refresh token, got: %v", model.DbPKeyMissingError, serr)
+	}
+}
+
+// failedDeleteClient is a user database client failing to delete sessions.
+type failedDeleteClient struct {
+	UserDBClient
+}
+
+func (fc failedDeleteClient) DeleteSessionContext(ctx context.Context, sess *model.Session) model.ServiceError {
+	return model.DbExecuteError.WithCause(errors.New("connection reset"))
+}
+
+func TestRefreshSessionRevokeFailure(t *testing.T) {
+	uc := connect(t)
+	defer uc.Close()
+
+	user := testData.Users[0].Clone()
+	defer uc.DeleteUserSessions(user)
+
+	sess, refresh, _ := NewSession(cfg, user, "device1")
+	if serr := uc.AddSession(sess); serr.IsError() {
+		t.Fatalf("Error adding session: %v", serr)
+	}
+	if _, _, serr := RefreshSession(context.Background(), cfg, uc, user, refresh); serr.IsError() {
+		t.Fatalf("Error refreshing session: %v", serr)
+	}
+
+	// reusing the refresh token fails to revoke the session
+	fc := failedDeleteClient{uc}
+	if _, _, serr := RefreshSession(context.Background(), cfg, fc, user, refresh); serr.Code != model.DbExecuteError.Code {
+		t.Errorf("Expected %v of a failed revocation, got: %v", model.DbExecuteError, serr)
+	}
+	if serr := uc.SessionInfo(&model.Session{SessId: sess.SessId}); serr.IsError() {
+		t.Errorf("Expected session kept by failed revocation, got: %v", serr)
+	}
+}
+
+func TestDeleteUserSessions(t *testing.T) {
+	uc := connect(t)
+	defer uc.Close()
+
+	user := &model.User{CtUser: "pendracon9", CtPass: "H:0", CtProf: "Pendracon", UEmail: "pendracon9@example.com"}
+	if serr := uc.AddUser(user); serr.IsError() {
+		t.Fatalf("Error adding user: %v", serr)
+	}
+
+	sess, _, _ := NewSession(cfg, user, "device1")
+	if serr := uc.AddSession(sess); serr.IsError() {
+		t.Fatalf("Error adding session: %v", serr)
+	}
+
+	// sessions are deleted with their user
+	if serr := uc.DeleteUser(user); serr.IsError() {
+		t.Fatalf("Error deleting user: %v", serr)
+	}
+	if serr := uc.SessionInfo(&model.Session{SessId: sess.SessId}); serr != model.DbPKeyMissingError {
+		t.Errorf("Expected %v querying session of deleted user, got: %v", model.DbPKeyMissingError, serr)
+	}
+}
@@ -0,0 +1,253 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+
+	"Cloudtacts/pkg/config"
+	"Cloudtacts/pkg/model"
+	"Cloudtacts/pkg/util"
+)
+
+const (
+	TOKEN_ALGORITHM_HS256 = "HS256"
+	TOKEN_ALGORITHM_RS256 = "RS256"
+	TOKEN_ALGORITHM_EDDSA = "EdDSA"
+
+	// Default lifetime of a user access token (15 minutes). Services verifying
+	// tokens statelessly accept the tokens of revoked sessions until they
+	// expire, so it's kept short.
+	TOKEN_LIFETIME = 15 * time.Minute
+
+	TOKEN_ISSUER = "cloudtacts"
+
+	// HS256 token key used in test mode when no keys are configured. It's
+	// fixed, so the auth and contacts services of a test deployment verify
+	// each other's tokens, across restarts; being public, it's insecure.
+	TEST_TOKEN_KEYS = "test:cloudtacts-test-mode-token-key-not-secret"
+)
+
+// TokenClaims are the claims carried by a user access token. The token's
+// subject is the user's login identifier, its session is the user session
+// (see Session) it was issued for.
+type TokenClaims struct {
+	CtUser string `json:"ctuser"`
+	CtProf string `json:"ctprof"`
+	UEmail string `json:"uemail"`
+	SessId string `json:"sid,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// TokenService issues and statelessly verifies signed user access tokens
+// (JWT). Tokens are signed with the active key and carry its identifier in
+// their 'kid' header. Tokens signed with any other configured key are still
+// verified, allowing keys to be rotated by adding a new key, making it
+// active, and later removing the old key.
+type TokenService struct {
+	method     jwt.SigningMethod
+	keyId      string
+	signKey    interface{}
+	verifyKeys map[string]interface{}
+	lifetime   time.Duration
+	issuer     string
+}
+
+// IssueToken returns a new signed access token for the referenced user's
+// given session along with its claims.
+func (ts *TokenService) IssueToken(user *model.User, sessId string) (string, *TokenClaims, model.ServiceError) {
+	if ok, err := validateUserKey(user); !ok {
+		return "", nil, model.InvalidKeyError.WithCause(err)
+	}
+	if ts.signKey == nil {
+		return "", nil, model.SystemError.WithCause(fmt.Errorf("token key '%v' can't sign tokens", ts.keyId))
+	}
+
+	now := time.Now().UTC()
+	claims := &TokenClaims{
+		CtUser: user.CtUser,
+		CtProf: user.CtProf,
+		UEmail: user.UEmail,
+		SessId: sessId,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.New().String(),
+			Issuer:    ts.issuer,
+			Subject:   user.CtUser,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ts.lifetime)),
+		},
+	}
+
+	token := jwt.NewWithClaims(ts.method, claims)
+	token.Header["kid"] = ts.keyId
+
+	signed, err := token.SignedString(ts.signKey)
+	if err != nil {
+		return "", nil, model.SystemError.WithCause(err)
+	}
+
+	return signed, claims, model.NoError
+}
+
+// VerifyToken verifies the given access token's signature, issuer, and
+// expiry and returns its claims.
+func (ts *TokenService) VerifyToken(token string) (*TokenClaims, model.ServiceError) {
+	if len(token) == 0 {
+		return nil, model.InvalidTokenError
+	}
+
+	claims := new(TokenClaims)
+	_, err := jwt.ParseWithClaims(token, claims, ts.verificationKey,
+		jwt.WithValidMethods([]string{ts.method.Alg()}),
+		jwt.WithIssuer(ts.issuer),
+		jwt.WithExpirationRequired())
+	if err != nil {
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return nil, model.ExpiredTokenError
+		}
+		return nil, model.InvalidTokenError.WithCause(err)
+	}
+
+	return claims, model.NoError
+}
+
+// VerifyUserToken verifies the given access token as with VerifyToken and
+// that it was issued to the referenced user.
+func (ts *TokenService) VerifyUserToken(token string, user *model.User) (*TokenClaims, model.ServiceError) {
+	claims, serr := ts.VerifyToken(token)
+	if serr.IsError() {
+		return nil, serr
+	}
+
+	if claims.CtUser != user.CtUser || claims.CtProf != user.CtProf || claims.UEmail != user.UEmail {
+		return nil, model.InvalidTokenError
+	}
+
+	return claims, model.NoError
+}
+
+// verificationKey returns the key identified by the given token's 'kid'
+// header.
+func (ts *TokenService) verificationKey(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if key, ok := ts.verifyKeys[kid]; ok {
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("unknown token key '%v'", kid)
+}
+
+// NewTokenService returns a token service configured with the token signing
+// algorithm, keys, active key identifier, lifetime, and issuer. Keys are
+// given as a comma separated list of 'kid:key' pairs where key is the shared
+// secret for HS256 or the path of a PEM encoded private (or, verify only,
+// public) key file for RS256 and EdDSA. In test mode, the HS256 key
+// TEST_TOKEN_KEYS is used if no keys are configured.
+func NewTokenService(cfg *config.Config) (*TokenService, model.ServiceError) {
+	algorithm := cfg.ValueOfWithDefault(model.KEY_USERDB_TOKEN_ALGORITHM, TOKEN_ALGORITHM_HS256)
+
+	var keys string
+	if cfg.AssignedValue(model.KEY_USERDB_TOKEN_KEYS) {
+		keys = cfg.ValueOf(model.KEY_USERDB_TOKEN_KEYS)
+	} else if testMode, _ := cfg.Bool(model.KEY_USERDB_TEST_MODE); testMode && algorithm == TOKEN_ALGORITHM_HS256 {
+		keys = TEST_TOKEN_KEYS
+		util.LogIt("Cloudtacts", "Using the insecure test token key in test mode.")
+	} else {
+		return nil, model.SystemError.WithCause(errors.New("no token keys configured"))
+	}
+
+	keySpecs := map[string]string{}
+	keyIds := []string{}
+	for _, spec := range strings.Split(keys, ",") {
+		kid, key, found := strings.Cut(strings.TrimSpace(spec), ":")
+		if !found || len(kid) == 0 || len(key) == 0 {
+			return nil, model.SystemError.WithCause(fmt.Errorf("malformed token key specification '%v'", kid))
+		}
+		keySpecs[kid] = key
+		keyIds = append(keyIds, kid)
+	}
+
+	keyId := keyIds[0]
+	if cfg.AssignedValue(model.KEY_USERDB_TOKEN_KEY_ID) {
+		keyId = cfg.ValueOf(model.KEY_USERDB_TOKEN_KEY_ID)
+	}
+
+	lifetime := TOKEN_LIFETIME
+	if dval, err := cfg.Duration(model.KEY_USERDB_TOKEN_LIFETIME); err == nil {
+		lifetime = dval
+	}
+
+	return newTokenService(algorithm, keyId, keySpecs, lifetime, cfg.ValueOfWithDefault(model.KEY_USERDB_TOKEN_ISSUER, TOKEN_ISSUER))
+}
+
+func newTokenService(algorithm, keyId string, keySpecs map[string]string, lifetime time.Duration, issuer string) (*TokenService, model.ServiceError) {
+	ts := new(TokenService)
+	ts.keyId = keyId
+	ts.lifetime = lifetime
+	ts.issuer = issuer
+	ts.verifyKeys = make(map[string]interface{})
+
+	switch algorithm {
+	case TOKEN_ALGORITHM_HS256:
+		ts.method = jwt.SigningMethodHS256
+	case TOKEN_ALGORITHM_RS256:
+		ts.method = jwt.SigningMethodRS256
+	case TOKEN_ALGORITHM_EDDSA:
+		ts.method = jwt.SigningMethodEdDSA
+	default:
+		return nil, model.SystemError.WithCause(fmt.Errorf("unknown token algorithm '%v'", algorithm))
+	}
+
+	for kid, spec := range keySpecs {
+		signKey, verifyKey, err := parseTokenKey(algorithm, spec)
+		if err != nil {
+			return nil, model.SystemError.WithCause(fmt.Errorf("token key '%v': %w", kid, err))
+		}
+		ts.verifyKeys[kid] = verifyKey
+		if kid == keyId {
+			ts.signKey = signKey
+		}
+	}
+
+	if _, ok := ts.verifyKeys[keyId]; !ok {
+		return nil, model.SystemError.WithCause(fmt.Errorf("active token key '%v' not configured", keyId))
+	}
+
+	return ts, model.NoError
+}
+
+// parseTokenKey returns the signing and verification keys of the given key
+// specification. The signing key is nil for verify only (public) keys.
+func parseTokenKey(algorithm, spec string) (interface{}, interface{}, error) {
+	if algorithm == TOKEN_ALGORITHM_HS256 {
+		return []byte(spec), []byte(spec), nil
+	}
+
+	pem, err := os.ReadFile(spec)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch algorithm {
+	case TOKEN_ALGORITHM_RS256:
+		if key, err := jwt.ParseRSAPrivateKeyFromPEM(pem); err == nil {
+			return key, &key.PublicKey, nil
+		}
+		key, err := jwt.ParseRSAPublicKeyFromPEM(pem)
+		return nil, key, err
+	default:
+		if key, err := jwt.ParseEdPrivateKeyFromPEM(pem); err == nil {
+			if edKey, ok := key.(ed25519.PrivateKey); ok {
+				return edKey, edKey.Public(), nil
+			}
+		}
+		key, err := jwt.ParseEdPublicKeyFromPEM(pem)
+		return nil, key, err
+	}
+}
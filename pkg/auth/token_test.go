@@ -0,0 +1,172 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"Cloudtacts/pkg/model"
+)
+
+func TestNewTokenService(t *testing.T) {
+	// test mode uses the test key when none is configured
+	ts, serr := NewTokenService(cfg)
+	if serr.IsError() {
+		t.Fatalf("Error creating token service: %v", serr)
+	}
+	other, serr := NewTokenService(cfg)
+	if serr.IsError() {
+		t.Fatalf("Error creating token service: %v", serr)
+	}
+
+	user := testData.Users[0].Clone()
+	token, claims, serr := ts.IssueToken(user, "session1")
+	if serr.IsError() {
+		t.Fatalf("Error issuing token: %v", serr)
+	}
+	t.Logf("Issued token %v: %v", claims.ID, token)
+
+	if claims, serr = ts.VerifyUserToken(token, user); serr.IsError() {
+		t.Errorf("Error verifying token: %v", serr)
+	} else if claims.SessId != "session1" {
+		t.Errorf("Expected token session 'session1', got: '%v'", claims.SessId)
+	}
+
+	// e.g. the auth and contacts services, or a restarted service
+	if _, serr = other.VerifyUserToken(token, user); serr.IsError() {
+		t.Errorf("Error verifying token with another test mode service: %v", serr)
+	}
+}
+
+func TestVerifyToken(t *testing.T) {
+	ts := tokenService(t, TOKEN_ALGORITHM_HS256, "k1", map[string]string{"k1": "secret1"}, time.Minute)
+
+	user := testData.Users[0].Clone()
+	token, _, _ := ts.IssueToken(user, "")
+
+	claims, serr := ts.VerifyToken(token)
+	if serr.IsError() {
+		t.Fatalf("Error verifying token: %v", serr)
+	}
+	if claims.CtUser != user.CtUser || claims.CtProf != user.CtProf || claims.UEmail != user.UEmail || claims.Subject != user.CtUser {
+		t.Errorf("Token claims don't match user: %v", claims)
+	}
+
+	other := testData.Users[1].Clone()
+	if _, serr = ts.VerifyUserToken(token, other); serr != model.InvalidTokenError {
+		t.Errorf("Expected invalid token for other user, got: %v", serr)
+	}
+
+	if _, serr = ts.VerifyToken(token[:len(token)-2]); serr.Code != model.InvalidTokenError.Code {
+		t.Errorf("Expected invalid token for bad signature, got: %v", serr)
+	}
+	if _, serr = ts.VerifyToken(""); serr != model.InvalidTokenError {
+		t.Errorf("Expected invalid token for empty token, got: %v", serr)
+	}
+
+	// tokens from another issuer aren't accepted
+	otherTs := tokenService(t, TOKEN_ALGORITHM_HS256, "k1", map[string]string{"k1": "secret1"}, time.Minute)
+	otherTs.issuer = "other"
+	token, _, _ = otherTs.IssueToken(user, "")
+	if _, serr = ts.VerifyToken(token); serr.Code != model.InvalidTokenError.Code {
+		t.Errorf("Expected invalid token for other issuer, got: %v", serr)
+	}
+}
+
+func TestExpiredToken(t *testing.T) {
+	ts := tokenService(t, TOKEN_ALGORITHM_HS256, "k1", map[string]string{"k1": "secret1"}, -time.Minute)
+
+	token, _, _ := ts.IssueToken(testData.Users[0].Clone(), "")
+	if _, serr := ts.VerifyToken(token); serr != model.ExpiredTokenError {
+		t.Errorf("Expected expired token, got: %v", serr)
+	}
+}
+
+func TestTokenKeyRotation(t *testing.T) {
+	user := testData.Users[0].Clone()
+
+	old := tokenService(t, TOKEN_ALGORITHM_HS256, "k1", map[string]string{"k1": "secret1"}, time.Minute)
+	oldToken, _, _ := old.IssueToken(user, "")
+
+	// new active key, old key still accepted
+	rotated := tokenService(t, TOKEN_ALGORITHM_HS256, "k2", map[string]string{"k1": "secret1", "k2": "secret2"}, time.Minute)
+	if _, serr := rotated.VerifyUserToken(oldToken, user); serr.IsError() {
+		t.Errorf("Error verifying token of previous key: %v", serr)
+	}
+	newToken, _, _ := rotated.IssueToken(user, "")
+	if _, serr := rotated.VerifyUserToken(newToken, user); serr.IsError() {
+		t.Errorf("Error verifying token of active key: %v", serr)
+	}
+
+	// old key retired
+	retired := tokenService(t, TOKEN_ALGORITHM_HS256, "k2", map[string]string{"k2": "secret2"}, time.Minute)
+	if _, serr := retired.VerifyUserToken(oldToken, user); serr.Code != model.InvalidTokenError.Code {
+		t.Errorf("Expected invalid token for retired key, got: %v", serr)
+	}
+	if _, serr := old.VerifyUserToken(newToken, user); serr.Code != model.InvalidTokenError.Code {
+		t.Errorf("Expected invalid token for unknown key, got: %v", serr)
+	}
+
+	if _, serr := newTokenService(TOKEN_ALGORITHM_HS256, "k3", map[string]string{"k2": "secret2"}, time.Minute, TOKEN_ISSUER); !serr.IsError() {
+		t.Error("Expected error for unconfigured active key.")
+	}
+}
+
+func TestEdDSAToken(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	privFile := writePEM(t, filepath.Join(dir, "private.pem"), "PRIVATE KEY", priv)
+	pubFile := writePEM(t, filepath.Join(dir, "public.pem"), "PUBLIC KEY", pub)
+
+	user := testData.Users[0].Clone()
+	signer := tokenService(t, TOKEN_ALGORITHM_EDDSA, "ed1", map[string]string{"ed1": privFile}, time.Minute)
+	token, _, serr := signer.IssueToken(user, "")
+	if serr.IsError() {
+		t.Fatalf("Error issuing token: %v", serr)
+	}
+
+	// public key only verifies tokens
+	verifier := tokenService(t, TOKEN_ALGORITHM_EDDSA, "ed1", map[string]string{"ed1": pubFile}, time.Minute)
+	if _, serr = verifier.VerifyUserToken(token, user); serr.IsError() {
+		t.Errorf("Error verifying token: %v", serr)
+	}
+	if _, _, serr = verifier.IssueToken(user, ""); !serr.IsError() {
+		t.Error("Expected error issuing token with public key.")
+	}
+}
+
+func tokenService(t *testing.T, algorithm, keyId string, keySpecs map[string]string, lifetime time.Duration) *TokenService {
+	ts, serr := newTokenService(algorithm, keyId, keySpecs, lifetime, TOKEN_ISSUER)
+	if serr.IsError() {
+		t.Fatalf("Error creating token service: %v", serr)
+	}
+
+	return ts
+}
+
+func writePEM(t *testing.T, filename, blockType string, key interface{}) string {
+	var der []byte
+	var err error
+	if blockType == "PUBLIC KEY" {
+		der, err = x509.MarshalPKIXPublicKey(key)
+	} else {
+		der, err = x509.MarshalPKCS8PrivateKey(key)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = os.WriteFile(filename, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	return filename
+}
@@ -1,12 +1,15 @@
 package auth
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
-	"strconv"
+	"net/mail"
 	"time"
 
 	_ "github.com/go-sql-driver/mysql"
+	"github.com/google/uuid"
 
 	"Cloudtacts/pkg/config"
 	"Cloudtacts/pkg/model"
@@ -14,12 +17,15 @@ import (
 )
 
 const (
-	SELECT_USER_INFO string = "SELECT ctpass, ctppic, atoken, llogin, uvalid FROM user WHERE ctuser = ? AND ctprof = ? AND uemail = ?"
-	INSERT_USER_STMT string = "INSERT INTO user (ctuser, ctpass, ctprof, uemail, ctppic) VALUES(?, ?, ?, ?, ?)"
-	UPDATE_USER_STMT string = "UPDATE user SET ctpass = ?, ctppic = ?, atoken = ? WHERE ctuser = ? AND ctprof = ? AND uemail = ?"
-	DELETE_USER_STMT string = "DELETE FROM user WHERE ctuser = ? AND ctprof = ? AND uemail = ?"
+	SELECT_USER_INFO string = "SELECT ctpass, ctppic, atoken, llogin, uvalid, uid FROM `user` WHERE ctuser = ? AND ctprof = ? AND uemail = ?"
+	INSERT_USER_STMT string = "INSERT INTO `user` (uid, ctuser, ctpass, ctprof, uemail, ctppic, atoken, llogin) VALUES(?, ?, ?, ?, ?, ?, ?, ?)"
+	UPDATE_USER_STMT string = "UPDATE `user` SET ctpass = ?, ctppic = ?, atoken = ? WHERE ctuser = ? AND ctprof = ? AND uemail = ?"
+	DELETE_USER_STMT string = "DELETE FROM `user` WHERE ctuser = ? AND ctprof = ? AND uemail = ?"
 
-	UPDATE_USER_STMT_TMPL string = "UPDATE user SET %v = ? WHERE ctuser = ? AND ctprof = ? AND uemail = ?"
+	UPDATE_USER_STMT_TMPL string = "UPDATE `user` SET %v = ? WHERE ctuser = ? AND ctprof = ? AND uemail = ?"
+
+	// Default timeout of user database requests (10 seconds).
+	QUERY_TIMEOUT = 10 * time.Second
 )
 
 type UserDBClient interface {
@@ -35,6 +41,62 @@ type UserDBClient interface {
 	// Updates the referenced user information in the database.
 	UpdateUser(*model.User) model.ServiceError
 
+	// Returns the users whose login identifier, e-mail address, or user ID is
+	// the given login.
+	UsersByLogin(string) ([]model.User, model.ServiceError)
+
+	// Updates the referenced session instance with information from the
+	// database.
+	SessionInfo(*model.Session) model.ServiceError
+
+	// Returns the sessions of the referenced user.
+	UserSessions(*model.User) ([]model.Session, model.ServiceError)
+
+	// Adds the referenced session to the database.
+	AddSession(*model.Session) model.ServiceError
+
+	// Updates the referenced session's refresh token and expiry in the
+	// database if its refresh token hash is still the given one. Returns
+	// InvalidRefreshError if not, i.e. if the session was renewed, or revoked,
+	// concurrently.
+	UpdateSession(*model.Session, string) model.ServiceError
+
+	// Deletes (revokes) the referenced session from the database.
+	DeleteSession(*model.Session) model.ServiceError
+
+	// Deletes (revokes) all sessions of the referenced user from the database.
+	DeleteUserSessions(*model.User) model.ServiceError
+
+	// Returns the users who haven't validated their registration and were
+	// added (last logged in) before the given time.
+	UnvalidatedUsers(time.Time) ([]model.User, model.ServiceError)
+
+	// Deletes the referenced user information from the database if the user
+	// still hasn't validated their registration and was added before the
+	// given time. Returns true if the user was deleted.
+	DeleteUnvalidatedUser(*model.User, time.Time) (bool, model.ServiceError)
+
+	// Context variants of the above. Database requests are cancelled when the
+	// context is done, or when the client's query timeout passes (see
+	// KEY_USERDB_QUERY_TIMEOUT), returning DbTimeoutError on timeout.
+	UserInfoContext(context.Context, *model.User) model.ServiceError
+	AddUserContext(context.Context, *model.User) model.ServiceError
+	DeleteUserContext(context.Context, *model.User) model.ServiceError
+	UpdateUserContext(context.Context, *model.User) model.ServiceError
+	UsersByLoginContext(context.Context, string) ([]model.User, model.ServiceError)
+	SessionInfoContext(context.Context, *model.Session) model.ServiceError
+	UserSessionsContext(context.Context, *model.User) ([]model.Session, model.ServiceError)
+	AddSessionContext(context.Context, *model.Session) model.ServiceError
+	UpdateSessionContext(context.Context, *model.Session, string) model.ServiceError
+	DeleteSessionContext(context.Context, *model.Session) model.ServiceError
+	DeleteUserSessionsContext(context.Context, *model.User) model.ServiceError
+	UnvalidatedUsersContext(context.Context, time.Time) ([]model.User, model.ServiceError)
+	DeleteUnvalidatedUserContext(context.Context, *model.User, time.Time) (bool, model.ServiceError)
+
+	// Begins a transaction of the database, which is rolled back if the
+	// context is done before it's committed.
+	BeginContext(context.Context) (UserTx, model.ServiceError)
+
 	// Return host URL of the database.
 	HostUrl() string
 
@@ -42,16 +104,43 @@ type UserDBClient interface {
 	Close()
 }
 
+// UserTx is a transaction of the user database. Its changes are discarded
+// unless it's committed. Transactions are serializable: one which conflicts
+// with a concurrent transaction (e.g. both adding users that the other
+// queried for) fails with DbConflictError, and may be retried.
+type UserTx interface {
+	// Adds the referenced user information to the database.
+	AddUser(*model.User) model.ServiceError
+
+	// Returns the users whose login identifier, e-mail address, or user ID is
+	// the given login.
+	UsersByLogin(string) ([]model.User, model.ServiceError)
+
+	// Commits the transaction.
+	Commit() model.ServiceError
+
+	// Rolls back the transaction. Rolling back a committed or rolled back
+	// transaction does nothing.
+	Rollback() model.ServiceError
+}
+
 func (uc *userClient) UserInfo(user *model.User) model.ServiceError {
+	return uc.UserInfoContext(context.Background(), user)
+}
+
+func (uc *userClient) UserInfoContext(ctx context.Context, user *model.User) model.ServiceError {
 	ferr := model.NoError
 
 	if ok, err := validateUserKey(user); !ok {
 		return model.InvalidKeyError.WithCause(err)
 	}
 
-	rows, err := uc.conn.Query(SELECT_USER_INFO, user.CtUser, user.CtProf, user.UEmail)
+	ctx, cancel := uc.queryContext(ctx)
+	defer cancel()
+
+	rows, err := uc.conn.QueryContext(ctx, uc.dialect.Rebind(SELECT_USER_INFO), user.CtUser, user.CtProf, user.UEmail)
 	if err != nil {
-		ferr = model.DbQueryError.WithCause(err)
+		ferr = dbError(model.DbQueryError, err)
 	} else {
 		defer rows.Close()
 
@@ -60,14 +149,16 @@ func (uc *userClient) UserInfo(user *model.User) model.ServiceError {
 			atoken := make([]byte, 20)
 			llogin := make([]byte, 14)
 			uvalid := make([]byte, 14)
-			err := rows.Scan(&user.CtPass, &ctppic, &atoken, &llogin, &uvalid)
+			uid := make([]byte, 36)
+			err := rows.Scan(&user.CtPass, &ctppic, &atoken, &llogin, &uvalid, &uid)
 			if err != nil {
-				ferr = model.DbScanError.WithCause(err)
+				ferr = dbError(model.DbScanError, err)
 			} else {
 				user.CtPpic = string(ctppic[:])
 				user.AToken = string(atoken[:])
 				user.LLogin = string(llogin[:])
 				user.UValid = string(uvalid[:])
+				user.UserId = string(uid[:])
 			}
 		} else {
 			ferr = model.DbPKeyMissingError
@@ -75,7 +166,7 @@ func (uc *userClient) UserInfo(user *model.User) model.ServiceError {
 
 		err = rows.Err()
 		if err != nil {
-			ferr = model.DbResultsError.WithCause(err)
+			ferr = dbError(model.DbResultsError, err)
 		}
 	}
 
@@ -83,112 +174,197 @@ func (uc *userClient) UserInfo(user *model.User) model.ServiceError {
 }
 
 func (uc *userClient) AddUser(user *model.User) model.ServiceError {
-	var ferr = model.ServiceError{}
-
-	if ok, err := validateUserKey(user); !ok {
-		ferr = model.InvalidKeyError.WithCause(err)
-	}
-
-	stmtIns, err := uc.conn.Prepare(INSERT_USER_STMT)
-	if err != nil {
-		ferr = model.DbPrepareError.WithCause(err)
-	}
-	defer stmtIns.Close()
-
-	_, err = stmtIns.Exec(user.CtUser, user.CtPass, user.CtProf, user.UEmail, user.CtPpic)
-	if err != nil {
-		ferr = model.DbInsertError.WithCause(err)
-	}
+	return uc.AddUserContext(context.Background(), user)
+}
 
-	return ferr
+func (uc *userClient) AddUserContext(ctx context.Context, user *model.User) model.ServiceError {
+	return insertUser(ctx, uc, uc.conn, user)
 }
 
 func (uc *userClient) DeleteUser(user *model.User) model.ServiceError {
-	var ferr = model.ServiceError{}
+	return uc.DeleteUserContext(context.Background(), user)
+}
 
+func (uc *userClient) DeleteUserContext(ctx context.Context, user *model.User) model.ServiceError {
 	if ok, err := validateUserKey(user); !ok {
-		ferr = model.InvalidKeyError.WithCause(err)
-	}
-
-	stmtDel, err := uc.conn.Prepare(DELETE_USER_STMT)
-	if err != nil {
-		ferr = model.DbPrepareError.WithCause(err)
-	}
-	defer stmtDel.Close()
-
-	_, err = stmtDel.Exec(user.CtUser, user.CtProf, user.UEmail)
-	if err != nil {
-		ferr = model.DbExecuteError.WithCause(err)
+		return model.InvalidKeyError.WithCause(err)
 	}
 
-	return ferr
+	return execStatement(ctx, uc, DELETE_USER_STMT, user.CtUser, user.CtProf, user.UEmail)
 }
 
 func (uc *userClient) UpdateUser(user *model.User) model.ServiceError {
+	return uc.UpdateUserContext(context.Background(), user)
+}
+
+func (uc *userClient) UpdateUserContext(ctx context.Context, user *model.User) model.ServiceError {
 	var ferr = model.NoError
 
-	var err error
 	if ok, err := validateUserKey(user); !ok {
-		ferr = model.InvalidKeyError.WithCause(err)
+		return model.InvalidKeyError.WithCause(err)
 	}
 
-	var stmtUpd *sql.Stmt
-	if ferr == model.NoError {
-		stmtUpd, err = uc.conn.Prepare(UPDATE_USER_STMT)
-		if err != nil {
-			ferr = model.DbPrepareError.WithCause(err)
-		}
-	}
-
-	if ferr == model.NoError {
-		_, err = stmtUpd.Exec(user.CtPass, user.CtPpic, user.AToken, user.CtUser, user.CtProf, user.UEmail)
-		if err != nil {
-			ferr = model.DbExecuteError.WithCause(err)
-		}
-	}
+	ferr = execStatement(ctx, uc, UPDATE_USER_STMT, user.CtPass, user.CtPpic, user.AToken, user.CtUser, user.CtProf, user.UEmail)
 
 	if ferr == model.NoError && len(user.LLogin) > 0 {
-		ferr = updateDateTimeColumn(user, uc, "llogin", user.LLogin)
+		ferr = updateDateTimeColumn(ctx, user, uc, "llogin", user.LLogin)
 	}
 	if ferr == model.NoError && len(user.UValid) > 0 {
-		ferr = updateDateTimeColumn(user, uc, "uvalid", user.UValid)
+		ferr = updateDateTimeColumn(ctx, user, uc, "uvalid", user.UValid)
 	}
 
 	return ferr
 }
 
+func (uc *userClient) BeginContext(ctx context.Context) (UserTx, model.ServiceError) {
+	tx, err := uc.conn.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		return nil, dbError(model.DbTransactionError, err)
+	}
+
+	return &userTx{ctx: ctx, uc: uc, tx: tx}, model.NoError
+}
+
 func (uc *userClient) HostUrl() string {
 	return uc.hostUrl
 }
 
+// Ping verifies a connection to the database is (or can be) established
+// within the client's query timeout.
+func (uc *userClient) Ping() error {
+	ctx, cancel := uc.queryContext(context.Background())
+	defer cancel()
+
+	return uc.conn.PingContext(ctx)
+}
+
 func (uc *userClient) Close() {
 	uc.conn.Close()
 }
 
-func GetDbClient(cfg *config.Config, host, port, database string) (*userClient, model.ServiceError) {
+// GetDbClient returns a client of the user database at the given host, port,
+// and database name, or a client of the in-memory user database when
+// configured for test mode (see KEY_USERDB_TEST_MODE).
+func GetDbClient(cfg *config.Config, host, port, database string) (UserDBClient, model.ServiceError) {
 	var serr model.ServiceError
 
+	if testMode, _ := cfg.Bool(model.KEY_USERDB_TEST_MODE); testMode {
+		traceIt(cfg, "DB client using in-memory user database.")
+		return sharedMemoryClient(), model.NoError
+	}
+
 	util.LogIt("Cloudtacts", fmt.Sprintf("Getting client for host '%v', port '%v', database '%v'...", host, port, database))
 
 	hostUrl := fmt.Sprintf("%v:%v", host, port)
 	appDbClient := new(userClient)
 	appDbClient.hostUrl = hostUrl
+	appDbClient.timeout = QueryTimeout(cfg)
 	serr = initClient(cfg, appDbClient, database)
 
 	return appDbClient, serr
 }
 
+// OpenDB opens the user database of the configured dialect at the given host,
+// port, and database name, e.g. to migrate its schema (see package migrate).
+// Unlike GetDbClient, it ignores test mode.
+func OpenDB(cfg *config.Config, host, port, database string) (*sql.DB, model.ServiceError) {
+	uc := &userClient{hostUrl: fmt.Sprintf("%v:%v", host, port)}
+	if serr := initClient(cfg, uc, database); serr.IsError() {
+		return nil, serr
+	}
+
+	return uc.conn, model.NoError
+}
+
+type userTx struct {
+	ctx context.Context
+	uc  *userClient
+	tx  *sql.Tx
+}
+
+func (utx *userTx) AddUser(user *model.User) model.ServiceError {
+	return utx.conflictError(insertUser(utx.ctx, utx.uc, utx.tx, user))
+}
+
+func (utx *userTx) UsersByLogin(login string) ([]model.User, model.ServiceError) {
+	users, serr := queryUsers(utx.ctx, utx.uc, utx.tx, login)
+	return users, utx.conflictError(serr)
+}
+
+func (utx *userTx) Commit() model.ServiceError {
+	if err := utx.tx.Commit(); err != nil {
+		return utx.conflictError(dbError(model.DbTransactionError, err))
+	}
+
+	return model.NoError
+}
+
+// conflictError returns DbConflictError if the given error of a request in
+// the transaction is the dialect's error of a conflict with a concurrent
+// transaction, or the given error otherwise.
+func (utx *userTx) conflictError(serr model.ServiceError) model.ServiceError {
+	if serr.IsError() && utx.uc.dialect.IsConflict(serr.Cause) {
+		return model.DbConflictError.WithCause(serr.Cause)
+	}
+
+	return serr
+}
+
+func (utx *userTx) Rollback() model.ServiceError {
+	if err := utx.tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+		return dbError(model.DbTransactionError, err)
+	}
+
+	return model.NoError
+}
+
 type userClient struct {
 	hostUrl string
 	conn    *sql.DB
+	dialect *Dialect
+	timeout time.Duration
+}
+
+// QueryTimeout returns the configured timeout of user database requests, or
+// zero if requests aren't timed out.
+func QueryTimeout(cfg *config.Config) time.Duration {
+	if dval, err := cfg.Duration(model.KEY_USERDB_QUERY_TIMEOUT); err == nil {
+		return dval
+	}
+
+	return QUERY_TIMEOUT
+}
+
+// queryContext returns the given context bounded by the client's query
+// timeout, if any, and its cancel function.
+func (uc *userClient) queryContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if uc.timeout > 0 {
+		return context.WithTimeout(ctx, uc.timeout)
+	}
+
+	return context.WithCancel(ctx)
+}
+
+// dbError returns the given error with the given cause, or DbTimeoutError if
+// the cause is the passing of a request's deadline.
+func dbError(serr model.ServiceError, err error) model.ServiceError {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return model.DbTimeoutError.WithCause(err)
+	}
+
+	return serr.WithCause(err)
 }
 
 func initClient(cfg *config.Config, uc *userClient, database string) model.ServiceError {
 	var serr model.ServiceError
 	var err error
 
+	if uc.dialect, serr = DialectOf(cfg); serr.IsError() {
+		return serr
+	}
+
 	if uc.hostUrl != "" {
-		uc.conn, err = sql.Open("mysql", fmt.Sprintf("%v:%v@tcp(%v)/%v?tls=skip-verify&autocommit=true&parseTime=true", cfg.ValueOf(model.KEY_USERDB_LOGIN), cfg.ValueOf(model.KEY_USERDB_PASSWORD), uc.hostUrl, database))
+		uc.conn, err = sql.Open(uc.dialect.Driver, uc.dialect.dsn(cfg, uc.hostUrl, database))
 
 		if err != nil {
 			serr = model.DbOpenError.WithCause(err)
@@ -196,7 +372,7 @@ func initClient(cfg *config.Config, uc *userClient, database string) model.Servi
 			traceIt(cfg, fmt.Sprintf("DB client using user database on host %v.", uc.hostUrl))
 		}
 	} else {
-		uc.conn, err = sql.Open("mysql", fmt.Sprintf("%v:%v@tcp(127.0.0.1:3306)/cloudtacts", cfg.ValueOf(model.KEY_USERDB_LOGIN), cfg.ValueOf(model.KEY_USERDB_PASSWORD)))
+		uc.conn, err = sql.Open(uc.dialect.Driver, uc.dialect.dsn(cfg, "127.0.0.1:3306", "cloudtacts"))
 
 		if err != nil {
 			serr = model.DbOpenError.WithCause(err)
@@ -206,23 +382,23 @@ func initClient(cfg *config.Config, uc *userClient, database string) model.Servi
 	}
 
 	if serr == (model.ServiceError{}) {
-		if ival, err := strconv.Atoi(cfg.ValueOfWithDefault(model.KEY_USERDB_MAX_POOL, "-1")); err == nil {
+		if ival, err := cfg.Int(model.KEY_USERDB_MAX_POOL); err == nil {
 			uc.conn.SetMaxOpenConns(ival)
 		} else {
 			uc.conn.SetMaxOpenConns(0)
 		}
-		if ival, err := strconv.Atoi(cfg.ValueOfWithDefault(model.KEY_USERDB_MAX_IDLE, "2")); err == nil {
+		if ival, err := cfg.Int(model.KEY_USERDB_MAX_IDLE); err == nil {
 			uc.conn.SetMaxIdleConns(ival)
 		} else {
 			uc.conn.SetMaxIdleConns(2)
 		}
-		if ival, err := strconv.Atoi(cfg.ValueOfWithDefault(model.KEY_USERDB_MAX_IDTM, "300")); err == nil {
-			uc.conn.SetConnMaxIdleTime(time.Second * time.Duration(ival))
+		if dval, err := cfg.Duration(model.KEY_USERDB_MAX_IDTM); err == nil {
+			uc.conn.SetConnMaxIdleTime(dval)
 		} else {
 			uc.conn.SetConnMaxIdleTime(time.Second * 300)
 		}
-		if ival, err := strconv.Atoi(cfg.ValueOfWithDefault(model.KEY_USERDB_MAX_LFTM, "30")); err == nil {
-			uc.conn.SetConnMaxLifetime(time.Minute * time.Duration(ival))
+		if dval, err := cfg.Duration(model.KEY_USERDB_MAX_LFTM); err == nil {
+			uc.conn.SetConnMaxLifetime(dval)
 		} else {
 			uc.conn.SetConnMaxLifetime(time.Minute * 30)
 		}
@@ -230,38 +406,47 @@ func initClient(cfg *config.Config, uc *userClient, database string) model.Servi
 		traceIt(cfg, fmt.Sprintf("Initial stats: %v", clientStats(uc)))
 	}
 
-	return (model.ServiceError{})
+	return serr
 }
 
-func updateDateTimeColumn(user *model.User, uc *userClient, colName, colVal string) model.ServiceError {
-	var ferr = model.NoError
+// insertUser inserts the referenced user, including its activation token and
+// last login if any, with the given connection or transaction. The user is
+// given a new user ID if it has none.
+func insertUser(ctx context.Context, uc *userClient, conn preparer, user *model.User) model.ServiceError {
+	if ok, err := validateUserKey(user); !ok {
+		return model.InvalidKeyError.WithCause(err)
+	}
+	if len(user.UserId) == 0 {
+		user.UserId = uuid.New().String()
+	}
 
-	var err error
-	var stmtUpd *sql.Stmt
-	if ferr == model.NoError {
-		stmtUpd, err = uc.conn.Prepare(fmt.Sprintf(UPDATE_USER_STMT_TMPL, colName))
+	var atoken, llogin any
+	if len(user.AToken) > 0 {
+		atoken = user.AToken
+	}
+	if len(user.LLogin) > 0 {
+		dtime, err := time.Parse(model.FMT_DATETIME_GO, util.StripDateStamp(user.LLogin))
 		if err != nil {
-			ferr = model.DbPrepareError.WithCause(err)
+			return model.DatetimeError.WithCause(err)
 		}
+		llogin = dtime
 	}
 
-	dtime := 0
-	if ferr == model.NoError {
-		defer stmtUpd.Close()
-
-		if dtime, err = strconv.Atoi(util.StripDateStamp(colVal)); err != nil {
-			ferr = model.DatetimeError.WithCause(err)
-		}
+	serr := execIn(ctx, uc, conn, INSERT_USER_STMT, user.UserId, user.CtUser, user.CtPass, user.CtProf, user.UEmail, user.CtPpic, atoken, llogin)
+	if serr.IsError() && serr.Code == model.DbExecuteError.Code {
+		serr = model.DbInsertError.WithCause(serr.Cause)
 	}
 
-	if ferr == model.NoError {
-		_, err = stmtUpd.Exec(dtime, user.CtUser, user.CtProf, user.UEmail)
-		if err != nil {
-			ferr = model.DbExecuteError.WithCause(err)
-		}
+	return serr
+}
+
+func updateDateTimeColumn(ctx context.Context, user *model.User, uc *userClient, colName, colVal string) model.ServiceError {
+	dtime, err := time.Parse(model.FMT_DATETIME_GO, util.StripDateStamp(colVal))
+	if err != nil {
+		return model.DatetimeError.WithCause(err)
 	}
 
-	return ferr
+	return execStatement(ctx, uc, fmt.Sprintf(UPDATE_USER_STMT_TMPL, colName), dtime, user.CtUser, user.CtProf, user.UEmail)
 }
 
 func clientStats(uc *userClient) string {
@@ -283,8 +468,19 @@ func validateUserKey(user *model.User) (bool, model.UserError) {
 	return true, model.UserError{}
 }
 
+// validateEmail checks the given e-mail address is a single bare address
+// (RFC 5322), e.g. without line breaks injecting headers into the mail sent
+// to it.
+func validateEmail(uemail string) (bool, model.UserError) {
+	if addr, err := mail.ParseAddress(uemail); err != nil || addr.Address != uemail {
+		return false, model.BadEmailAddressError
+	}
+
+	return true, model.UserError{}
+}
+
 func traceIt(cfg *config.Config, message string) {
-	if cfg.ValueOfWithDefault(model.KEY_USERDB_TEST_MODE, "false") == "true" {
+	if testMode, _ := cfg.Bool(model.KEY_USERDB_TEST_MODE); testMode {
 		util.LogIt("Cloudtacts", message)
 	}
 }
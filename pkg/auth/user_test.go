@@ -1,8 +1,11 @@
 package auth
 
 import (
+	"context"
 	"fmt"
+	"os"
 	"testing"
+	"time"
 
 	"Cloudtacts/pkg/config"
 	"Cloudtacts/pkg/model"
@@ -101,8 +104,8 @@ func TestDelUser(t *testing.T) {
 	}
 	t.Logf("Deleted user: %v", testData.Users[1].CtUser)
 
-	if serr := uc.UserInfo(&userData); serr.IsError() {
-		t.Errorf("Error querying updated user: %v", serr)
+	if serr := uc.UserInfo(&userData); serr != model.DbPKeyMissingError {
+		t.Errorf("Expected %v querying deleted user, got: %v", model.DbPKeyMissingError, serr)
 	}
 	fmt.Printf("Queried back data: %v\n", userData)
 	if userData.CtPpic != "" {
@@ -110,7 +113,52 @@ func TestDelUser(t *testing.T) {
 	}
 }
 
-func connect(t *testing.T) *userClient {
+func TestAddDuplicateUser(t *testing.T) {
+	uc := connect(t)
+	defer uc.Close()
+
+	user := testData.Users[0].Clone()
+	user.CtPass = user.PwdHash(true)
+	if serr := uc.AddUser(user); serr.Code != model.DbPKeyError.Code {
+		t.Errorf("Expected %v adding existing user, got: %v", model.DbPKeyError, serr)
+	}
+}
+
+func TestMissingUserInfo(t *testing.T) {
+	uc := connect(t)
+	defer uc.Close()
+
+	userData := model.User{
+		CtUser: "nobody",
+		CtProf: "Nobody",
+		UEmail: "nobody@example.com",
+	}
+	if serr := uc.UserInfo(&userData); serr != model.DbPKeyMissingError {
+		t.Errorf("Expected %v querying missing user, got: %v", model.DbPKeyMissingError, serr)
+	}
+}
+
+func TestUserInfoTimeout(t *testing.T) {
+	uc := connect(t)
+	defer uc.Close()
+
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+
+	userData := model.User{
+		CtUser: testData.Users[0].CtUser,
+		CtProf: testData.Users[0].CtProf,
+		UEmail: testData.Users[0].UEmail,
+	}
+	if serr := uc.UserInfoContext(ctx, &userData); serr.Code != model.DbTimeoutError.Code {
+		t.Errorf("Expected %v querying user past deadline, got: %v", model.DbTimeoutError, serr)
+	}
+	if status := model.HttpErrorStatus[model.DbTimeoutError.Code]; status != 504 {
+		t.Errorf("Expected status 504 of %v, got: %d", model.DbTimeoutError, status)
+	}
+}
+
+func connect(t *testing.T) UserDBClient {
 	uc, serr := GetDbClient(cfg, cfg.ValueOf(model.KEY_USERDB_HOST_IP), cfg.ValueOf(model.KEY_USERDB_PORT_NUM), cfg.ValueOf(model.KEY_USERDB_DATABASE))
 	if serr.IsError() {
 		t.Errorf("Error getting DB client: %v", serr)
@@ -119,7 +167,29 @@ func connect(t *testing.T) *userClient {
 	return uc
 }
 
+// seedTestData adds the first test user to the database if not already
+// present.
+func seedTestData() {
+	uc, serr := GetDbClient(cfg, cfg.ValueOf(model.KEY_USERDB_HOST_IP), cfg.ValueOf(model.KEY_USERDB_PORT_NUM), cfg.ValueOf(model.KEY_USERDB_DATABASE))
+	if serr.IsError() {
+		util.LogError("Cloudtacts", "user_test:seedTestData", serr)
+	}
+	defer uc.Close()
+
+	user := testData.Users[0].Clone()
+	user.CtPass = user.PwdHash(true)
+	if serr = uc.AddUser(user); serr.IsError() && serr.Code != model.DbPKeyError.Code {
+		util.LogError("Cloudtacts", "user_test:seedTestData", serr)
+	}
+}
+
 func init() {
+	// Run against the in-memory user database unless told otherwise, e.g.:
+	// CT_USERDB_TEST_MODE=false go test ./pkg/auth
+	if _, ok := os.LookupEnv("CT_USERDB_TEST_MODE"); !ok {
+		os.Setenv("CT_USERDB_TEST_MODE", "true")
+	}
+
 	model.ParserConfigPath = "../../config/parameters_config.json"
 	model.ApplicationConfigPath = "../../config/application.properties"
 	var err error
@@ -134,4 +204,5 @@ func init() {
 	if err != nil {
 		util.LogError("Cloudtacts", "user_test:TestNewUser", err)
 	}
+	seedTestData()
 }
@@ -21,14 +21,54 @@ configuration file has the following structure:
 			"propertyName": "app.config.file",
 			"defaultVal": "../../config/application.properties",
 			"description": "Application configuration properties file."
+		}, {
+			"optionId": "userdbQueryTimeoutId",
+			"cliArgument": "userdbQueryTimeout",
+			"environmentVar": "CT_USERDB_QUERY_TIMEOUT",
+			"propertyName": "user.auth.db.queryTimeout",
+			"defaultVal": "10",
+			"description": "Maximum time in seconds of a user auth database request.",
+			"type": "duration",
+			"min": 0,
+			"unit": "s"
 		}]
 	}
 
+Parameters may describe their values with the optional fields: "type" (one of:
+string, int, bool, duration, list, url; default is string), "required" (or
+"requiredIf", e.g. "userdbTestModeId=false,userdbDialectId!=sqlite"), "min"
+and "max", "enum" (the values allowed), and "unit" (of durations given as a
+number: s, m, h, or d). Typed values are read with the Int, Bool, Duration,
+List, and URL functions, and Validate reports every missing or malformed
+parameter, e.g. at startup.
+
+Parameters may also list the "commands" taking them (all commands if none),
+and the "section" and "comment" of their property in the application
+properties template. Each command's --help usage (see Usage) and the template
+itself (see PropertiesTemplate) are generated from the parser configuration.
+
+CLI options are separated from their values by the "argSeparator" (one of:
+SPACE, EQUALS, COMMA, COLON, SEMI-COLON). Boolean parameters are switches which
+may be given without a value (e.g. --userdbTestMode), the values of list
+parameters given more than once are joined, and any other parameter given more
+than once takes its last value. Arguments which aren't options, and all those
+after a "--" terminator, are positional (see Arguments).
+
+Any parameter value may instead be a reference, resolved once parsing is
+complete, to the contents of a file (e.g. file:/run/secrets/db), the value of
+an environment variable (e.g. env:DB_PASSWORD), or a secret manager secret
+(e.g. secret:projects/cloudtacts/secrets/db/versions/latest). Secrets are
+resolved by the registered SecretResolver (see RegisterSecretResolver), or
+read from the local JSON secrets file stand-in given by --configSecretsFile.
+Values resolved through references are masked like secret parameters'.
+
 Once parser is successfully configured, the package looks for its default
 application configuration file at: ./config/application.properties, relative to
 the project root. Once parsing is complete, the package presents a unified view
 of the application configuration through its AssignedValue, ValueOf, and
-ValueOfWithDefault functions.
+ValueOfWithDefault functions. SourceOf tells where each value was read from:
+a CLI argument, an environment variable, a properties file line, or the
+parameter's default.
 
 The following shows a sample usage:
 
@@ -52,6 +92,7 @@ import (
 	"fmt"
 	"os"
 	"slices"
+	"strings"
 
 	"github.com/magiconair/properties"
 
@@ -65,12 +106,21 @@ type Config struct {
 	// CLI argument prefix switch (e.g. "--")
 	argSwitch string
 
-	// CLI argument separator character (one of: SPACE [' '], COMMA [','], COLON [':'], SEMI-COLON [';'])
+	// CLI argument separator character (one of: SPACE [' '], EQUALS ['='], COMMA [','], COLON [':'], SEMI-COLON [';'])
 	argSeparator uint8
 
+	// Positional CLI arguments
+	arguments []string
+
+	// Usage is requested (--help)
+	helpRequested bool
+
 	// Table of parameters
 	parameters map[string]string
 
+	// Table of parameters' value sources
+	sources map[string]Source
+
 	// internal parser configuration instance
 	parserConfig *model.ParserConfig
 
@@ -126,6 +176,12 @@ func (cfg *Config) AssignedValue(id string) bool {
 	return (len(cfg.parameters[id]) > 0) && (cfg.parameters[id] != model.USER_MUST_PROVIDE)
 }
 
+// Arguments returns the positional CLI arguments, i.e. those which aren't
+// options.
+func (cfg *Config) Arguments() []string {
+	return cfg.arguments
+}
+
 // IsParsed returns true if configuration has been loaded and parsed.
 func (cfg *Config) IsParsed() bool {
 	return cfg.parserLoaded
@@ -154,17 +210,23 @@ func (cfg *Config) Parse() (bool, error) {
 
 		// Set the initial default values with preference for CLI options, followed
 		// by env overrides.
-		options := util.ParseOptions(cfg.argSwitch, cfg.argSeparator, os.Args[1:])
+		options := util.ParseOptions(cfg.argSwitch, cfg.argSeparator, os.Args[1:], cfg.switches()...)
+		cfg.arguments = options.Positional
+		_, cfg.helpRequested = options.Value("help")
+
 		var parmVal string
 		updateProps := []string{}
 		for _, parm := range cfg.parserConfig.Parameters {
 			switch {
-			case len((*options)[parm.CliArgument]) > 0:
-				parmVal = (*options)[parm.CliArgument]
+			case len(cliValue(&parm, options)) > 0:
+				parmVal = cliValue(&parm, options)
+				cfg.sources[parm.OptionId] = Source{Kind: SOURCE_CLI, Name: cfg.argSwitch + parm.CliArgument}
 			case len(os.Getenv(parm.EnvironmentVar)) > 0:
 				parmVal = os.Getenv(parm.EnvironmentVar)
+				cfg.sources[parm.OptionId] = Source{Kind: SOURCE_ENV, Name: parm.EnvironmentVar}
 			case true:
 				parmVal = parm.DefaultVal
+				cfg.sources[parm.OptionId] = Source{Kind: SOURCE_DEFAULT}
 				updateProps = append(updateProps, parm.PropertyName)
 			}
 			cfg.parameters[parm.OptionId] = parmVal
@@ -184,6 +246,12 @@ func (cfg *Config) Parse() (bool, error) {
 			}
 		}
 
+		// Finally, references (e.g. file:/run/secrets/db) are resolved to the
+		// values they refer to.
+		if err := cfg.resolveReferences(context.Background()); err != nil {
+			return false, util.WrappedError(err, "resolveReferences")
+		}
+
 		cfg.parserLoaded = true
 	}
 
@@ -199,32 +267,56 @@ func (cfg *Config) loadConfiguration() error {
 		return util.WrappedError(err, "LoadParserConfig")
 	}
 	cfg.parameters = make(map[string]string)
+	cfg.sources = make(map[string]Source)
 
 	if len(cfg.parserConfig.ArgSwitch) > 0 {
 		cfg.argSwitch = cfg.parserConfig.ArgSwitch
 	}
 
-	if len(cfg.parserConfig.ArgSeparator) > 0 {
-		switch cfg.parserConfig.ArgSeparator {
-		case "EQUALS":
-			cfg.argSeparator = '='
-		case "COMMA":
-			cfg.argSeparator = ','
-		case "COLON":
-			cfg.argSeparator = ':'
-		case "SEMI-COLON":
-			cfg.argSeparator = ';'
-		case "SPACE":
-			// default
-		}
+	switch cfg.parserConfig.ArgSeparator {
+	case "EQUALS":
+		cfg.argSeparator = '='
+	case "COMMA":
+		cfg.argSeparator = ','
+	case "COLON":
+		cfg.argSeparator = ':'
+	case "SEMI-COLON":
+		cfg.argSeparator = ';'
+	case "SPACE", "":
+		cfg.argSeparator = ' '
+	default:
+		return fmt.Errorf("unknown argument separator: %v", cfg.parserConfig.ArgSeparator)
 	}
 
 	return nil
 }
 
+// switches returns the CLI arguments of the boolean parameters, which may be
+// given without a value (e.g. --userdbTestMode), and of the help switch.
+func (cfg *Config) switches() []string {
+	switches := []string{"help"}
+	for _, parm := range cfg.parserConfig.Parameters {
+		if typeOf(&parm) == model.TYPE_BOOL {
+			switches = append(switches, parm.CliArgument)
+		}
+	}
+	return switches
+}
+
+// cliValue returns the value of the referenced parameter given by the parsed
+// CLI options: all the values given of a list parameter, comma separated, or
+// the last value given of any other parameter.
+func cliValue(parm *model.Parameter, options *util.Options) string {
+	if typeOf(parm) == model.TYPE_LIST {
+		return strings.Join(options.Values[parm.CliArgument], ",")
+	}
+	value, _ := options.Value(parm.CliArgument)
+	return value
+}
+
 // loadProperties reads all key=value pair properties from the specified file
 // path and assigns their values to any matching parameters not already
-// assigned a value.
+// assigned a value, recording the file and line of each value assigned.
 func (cfg *Config) loadProperties(filename string, propsList *[]string) (bool, error) {
 	if filename == "" {
 		return false, util.WrappedError(errors.New("file name not provided"), "load properties")
@@ -234,12 +326,19 @@ func (cfg *Config) loadProperties(filename string, propsList *[]string) (bool, e
 	if err != nil {
 		return false, util.WrappedError(err, "load properties")
 	}
+	lines, err := propertyLines(filename)
+	if err != nil {
+		return false, util.WrappedError(err, "load properties")
+	}
 
 	var parmVal string
 	for _, parm := range cfg.parserConfig.Parameters {
 		if slices.Contains(*propsList, parm.PropertyName) {
 			parmVal = configurationValue(props, parm.PropertyName, cfg.parameters[parm.OptionId])
 			cfg.parameters[parm.OptionId] = parmVal
+			if propVal, ok := props.Get(parm.PropertyName); ok && propVal != model.USER_MUST_PROVIDE {
+				cfg.sources[parm.OptionId] = Source{Kind: SOURCE_FILE, Name: parm.PropertyName, File: filename, Line: lines[parm.PropertyName]}
+			}
 			//util.LogIt("", fmt.Sprintf("Updated prop (%v): %v = %v", parm.OptionId, parm.PropertyName, parmVal))
 		}
 	}
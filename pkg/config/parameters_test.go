@@ -2,6 +2,8 @@ package config
 
 import (
 	"fmt"
+	"os"
+	"slices"
 	"testing"
 
 	"Cloudtacts/pkg/model"
@@ -14,6 +16,35 @@ func TestParse(t *testing.T) {
 	t.Logf("Got user.auth.testMode = %v", cfg.ValueOf(model.KEY_USERDB_TEST_MODE))
 }
 
+func TestParseArguments(t *testing.T) {
+	args := os.Args
+	os.Args = []string{args[0], "explain", "--userdbTestMode", "--userdbUniqueness=login", "--userdbPort=1",
+		"--userdbUniqueness=email", "--userdbPort=2", "--", "--userdbDialect=sqlite"}
+	defer func() { os.Args = args }()
+
+	acfg, err := ContextConfig()
+	if err != nil {
+		t.Fatalf("Error parsing configuration: %v", err)
+	}
+
+	for _, test := range []struct {
+		id    string
+		value string
+	}{
+		{model.KEY_USERDB_TEST_MODE, "true"},
+		{model.KEY_USERDB_UNIQUENESS, "login,email"},
+		{model.KEY_USERDB_PORT_NUM, "2"},
+		{model.KEY_USERDB_DIALECT, "mysql"},
+	} {
+		if value := acfg.ValueOf(test.id); value != test.value {
+			t.Errorf("Expected %v = %q, got: %q", test.id, test.value, value)
+		}
+	}
+	if arguments := acfg.Arguments(); !slices.Equal(arguments, []string{"explain", "--userdbDialect=sqlite"}) {
+		t.Errorf("Expected positional arguments [explain --userdbDialect=sqlite], got: %q", arguments)
+	}
+}
+
 func init() {
 	model.ParserConfigPath = "../../config/parameters_config.json"
 	model.ApplicationConfigPath = "../../config/application.properties"
@@ -0,0 +1,139 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"Cloudtacts/pkg/model"
+)
+
+// Prefixes of parameter values which are references to the actual values
+// (see Parse): the contents of a file (e.g. file:/run/secrets/db), the value
+// of an environment variable (e.g. env:DB_PASSWORD), or the latest version of
+// a secret manager secret (e.g.
+// secret:projects/cloudtacts/secrets/db/versions/latest).
+const (
+	REF_FILE   = "file:"
+	REF_ENV    = "env:"
+	REF_SECRET = "secret:"
+)
+
+// SecretResolver resolves secret manager references to the secrets' values.
+type SecretResolver interface {
+	// Returns the value of the secret version with the given resource name
+	// (e.g. projects/cloudtacts/secrets/db/versions/latest).
+	Secret(ctx context.Context, name string) (string, error)
+}
+
+// secretResolver resolves secret references unless a local secrets file is
+// configured (see RegisterSecretResolver).
+var secretResolver SecretResolver
+
+// RegisterSecretResolver sets the resolver of the secret references of
+// configurations parsed from now on, e.g. a secret manager client, unless a
+// local secrets file (--configSecretsFile) is configured.
+func RegisterSecretResolver(resolver SecretResolver) {
+	secretResolver = resolver
+}
+
+// localSecrets is a stand-in for the secret manager for development and
+// testing, read from a local JSON file of an object mapping secret version
+// resource names to values, e.g.:
+//
+//	{"projects/cloudtacts/secrets/db/versions/latest": "f4kePas$"}
+type localSecrets map[string]string
+
+func (ls localSecrets) Secret(ctx context.Context, name string) (string, error) {
+	if value, ok := ls[name]; ok {
+		return value, nil
+	}
+	return "", fmt.Errorf("secret %v not found", name)
+}
+
+func newLocalSecrets(filename string) (localSecrets, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	ls := localSecrets{}
+	if err = json.Unmarshal(data, &ls); err != nil {
+		return nil, fmt.Errorf("malformed secrets file %v: %w", filename, err)
+	}
+	return ls, nil
+}
+
+// resolveReferences replaces the values of the parameters which are
+// references with the values referred to, recording the references as part
+// of the values' sources. The local secrets file parameter is resolved first
+// (with the registered resolver, if a secret reference), so that it can
+// resolve the others.
+func (cfg *Config) resolveReferences(ctx context.Context) error {
+	secretsParm := cfg.parameter(model.KEY_CONFIG_SECRETS)
+	if secretsParm != nil {
+		if err := cfg.resolveReference(ctx, secretsParm, secretResolver); err != nil {
+			return err
+		}
+	}
+
+	resolver := secretResolver
+	if cfg.AssignedValue(model.KEY_CONFIG_SECRETS) {
+		secrets, err := newLocalSecrets(cfg.ValueOf(model.KEY_CONFIG_SECRETS))
+		if err != nil {
+			return fmt.Errorf("%v: %w", cfg.describe(secretsParm), err)
+		}
+		resolver = secrets
+	}
+
+	for i := range cfg.parserConfig.Parameters {
+		parm := &cfg.parserConfig.Parameters[i]
+		if parm != secretsParm {
+			if err := cfg.resolveReference(ctx, parm, resolver); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// resolveReference replaces the value of the referenced parameter with the
+// value referred to if it is a reference, resolving secret references with
+// the given resolver. Errors name the reference, never the value.
+func (cfg *Config) resolveReference(ctx context.Context, parm *model.Parameter, resolver SecretResolver) error {
+	ref := cfg.parameters[parm.OptionId]
+
+	var value string
+	var err error
+	if path, ok := strings.CutPrefix(ref, REF_FILE); ok {
+		var data []byte
+		if data, err = os.ReadFile(path); err == nil {
+			value = strings.TrimRight(string(data), "\r\n")
+		}
+	} else if name, ok := strings.CutPrefix(ref, REF_ENV); ok {
+		if value, ok = os.LookupEnv(name); !ok {
+			err = fmt.Errorf("environment variable %v not set", name)
+		}
+	} else if name, ok := strings.CutPrefix(ref, REF_SECRET); ok {
+		if resolver == nil {
+			err = fmt.Errorf("no secret resolver registered, nor secrets file configured")
+		} else {
+			value, err = resolver.Secret(ctx, name)
+		}
+	} else {
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("%v: unresolved reference %v: %w", cfg.describe(parm), ref, err)
+	}
+	cfg.parameters[parm.OptionId] = value
+	source := cfg.SourceOf(parm.OptionId)
+	source.Reference = ref
+	cfg.sources[parm.OptionId] = source
+
+	return nil
+}
@@ -0,0 +1,127 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"Cloudtacts/pkg/model"
+)
+
+const testSecret = "projects/cloudtacts/secrets/s3/versions/latest"
+
+type testResolver map[string]string
+
+func (tr testResolver) Secret(ctx context.Context, name string) (string, error) {
+	return localSecrets(tr).Secret(ctx, name)
+}
+
+func TestResolveReferences(t *testing.T) {
+	dir := t.TempDir()
+	dbFile := filepath.Join(dir, "db")
+	secretsFile := filepath.Join(dir, "secrets.json")
+	if err := os.WriteFile(dbFile, []byte("f4kePas$\n"), 0600); err != nil {
+		t.Fatalf("Error writing %v: %v", dbFile, err)
+	}
+	if err := os.WriteFile(secretsFile, []byte(`{"`+testSecret+`": "s3cr3t"}`), 0600); err != nil {
+		t.Fatalf("Error writing %v: %v", secretsFile, err)
+	}
+	t.Setenv("CT_USERDB_CREDENTIALS", REF_FILE+dbFile)
+	t.Setenv("CT_USERDB_HOST_IP", REF_ENV+"TEST_DB_HOST")
+	t.Setenv("TEST_DB_HOST", "10.0.0.1")
+	t.Setenv("CT_STORAGE_S3_SECRET_KEY", REF_SECRET+testSecret)
+	t.Setenv("CT_CONFIG_SECRETS_FILE", REF_ENV+"TEST_SECRETS_FILE")
+	t.Setenv("TEST_SECRETS_FILE", secretsFile)
+
+	rcfg, err := ContextConfig()
+	if err != nil {
+		t.Fatalf("Error parsing configuration: %v", err)
+	}
+
+	for _, test := range []struct {
+		id    string
+		value string
+		ref   string
+	}{
+		{model.KEY_USERDB_PASSWORD, "f4kePas$", REF_FILE + dbFile},
+		{model.KEY_USERDB_HOST_IP, "10.0.0.1", REF_ENV + "TEST_DB_HOST"},
+		{model.KEY_STORAGE_S3_SECRET_KEY, "s3cr3t", REF_SECRET + testSecret},
+		{model.KEY_CONFIG_SECRETS, secretsFile, REF_ENV + "TEST_SECRETS_FILE"},
+	} {
+		if value := rcfg.ValueOf(test.id); value != test.value {
+			t.Errorf("Expected %v resolved to %q, got: %q", test.id, test.value, value)
+		}
+		if value := rcfg.DisplayValueOf(test.id); value != MASKED_VALUE {
+			t.Errorf("Expected %v masked, got: %q", test.id, value)
+		}
+		source := rcfg.SourceOf(test.id)
+		if source.Kind != SOURCE_ENV || source.Reference != test.ref || !strings.HasSuffix(source.String(), " via "+test.ref) {
+			t.Errorf("Expected %v from an env variable via %v, got: %v", test.id, test.ref, source)
+		}
+	}
+	if value := rcfg.DisplayValueOf(model.KEY_USERDB_DIALECT); value != "mysql" {
+		t.Errorf("Expected unreferenced %v unmasked, got: %q", model.KEY_USERDB_DIALECT, value)
+	}
+}
+
+func TestRegisterSecretResolver(t *testing.T) {
+	RegisterSecretResolver(testResolver{testSecret: "s3cr3t"})
+	defer RegisterSecretResolver(nil)
+	t.Setenv("CT_STORAGE_S3_SECRET_KEY", REF_SECRET+testSecret)
+
+	rcfg, err := ContextConfig()
+	if err != nil {
+		t.Fatalf("Error parsing configuration: %v", err)
+	}
+	if value := rcfg.ValueOf(model.KEY_STORAGE_S3_SECRET_KEY); value != "s3cr3t" {
+		t.Errorf("Expected the registered resolver's secret, got: %q", value)
+	}
+}
+
+func TestUnresolvedReferences(t *testing.T) {
+	dir := t.TempDir()
+	missing := filepath.Join(dir, "missing")
+	empty := filepath.Join(dir, "secrets.json")
+	if err := os.WriteFile(empty, []byte("{}"), 0600); err != nil {
+		t.Fatalf("Error writing %v: %v", empty, err)
+	}
+	for _, test := range []struct {
+		name string
+		env  map[string]string
+	}{
+		{"missing file", map[string]string{"CT_USERDB_CREDENTIALS": REF_FILE + missing}},
+		{"unset variable", map[string]string{"CT_USERDB_CREDENTIALS": REF_ENV + "TEST_UNSET_VARIABLE"}},
+		{"no resolver", map[string]string{"CT_USERDB_CREDENTIALS": REF_SECRET + testSecret}},
+		{"missing secrets file", map[string]string{"CT_CONFIG_SECRETS_FILE": missing}},
+		{"malformed secrets file", map[string]string{"CT_CONFIG_SECRETS_FILE": model.ParserConfigPath}},
+		{"missing secret", map[string]string{
+			"CT_CONFIG_SECRETS_FILE": empty,
+			"CT_USERDB_CREDENTIALS":  REF_SECRET + testSecret,
+		}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			for name, value := range test.env {
+				t.Setenv(name, value)
+			}
+			if _, err := ContextConfig(); err == nil {
+				t.Errorf("Expected an error resolving %v", test.env)
+			}
+		})
+	}
+}
+
+func TestResolvedValuesNotShown(t *testing.T) {
+	t.Setenv("CT_USERDB_PORT_NUM", REF_ENV+"TEST_DB_PORT")
+	t.Setenv("TEST_DB_PORT", "s3cr3t")
+
+	rcfg, err := ContextConfig()
+	if err != nil {
+		t.Fatalf("Error parsing configuration: %v", err)
+	}
+	err = rcfg.Validate(model.KEY_USERDB_PORT_NUM)
+	if err == nil || strings.Contains(err.Error(), "s3cr3t") || !strings.Contains(err.Error(), MASKED_VALUE) {
+		t.Errorf("Expected an error showing the masked value, got: %v", err)
+	}
+}
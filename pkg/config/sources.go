@@ -0,0 +1,133 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"Cloudtacts/pkg/model"
+)
+
+// Kinds of parameter value sources (see Source)
+const (
+	SOURCE_CLI     = "cli"
+	SOURCE_ENV     = "env"
+	SOURCE_FILE    = "file"
+	SOURCE_DEFAULT = "default"
+
+	// Displayed value of assigned secret parameters, and of those resolved
+	// through references
+	MASKED_VALUE = "********"
+)
+
+// Source describes where a parameter's value was read from: a CLI argument,
+// an environment variable, a line of a properties file, or the parameter's
+// default value, and the reference (e.g. file:/run/secrets/db) it was
+// resolved through, if any.
+type Source struct {
+	Kind      string // one of the SOURCE_* constants
+	Name      string // argument, variable, or property name
+	File      string // properties file path
+	Line      int    // properties file line number
+	Reference string // reference resolved to the value
+}
+
+func (src Source) String() string {
+	var source string
+	switch src.Kind {
+	case SOURCE_CLI:
+		source = fmt.Sprintf("CLI argument %v", src.Name)
+	case SOURCE_ENV:
+		source = fmt.Sprintf("env variable %v", src.Name)
+	case SOURCE_FILE:
+		source = fmt.Sprintf("%v:%d (%v)", src.File, src.Line, src.Name)
+	default:
+		source = SOURCE_DEFAULT
+	}
+	if len(src.Reference) > 0 {
+		source += " via " + src.Reference
+	}
+	return source
+}
+
+// SourceOf returns the source of the given parameter's value.
+func (cfg *Config) SourceOf(id string) Source {
+	if src, ok := cfg.sources[id]; ok {
+		return src
+	}
+	return Source{Kind: SOURCE_DEFAULT}
+}
+
+// Parameters returns the parser configuration of all the parameters, in the
+// order they are configured.
+func (cfg *Config) Parameters() []model.Parameter {
+	if cfg.parserConfig == nil {
+		return nil
+	}
+	return append([]model.Parameter{}, cfg.parserConfig.Parameters...)
+}
+
+// DisplayValueOf returns the value of the given parameter to show, e.g. in
+// logs: the value of a secret parameter, or resolved through a reference, is
+// masked once assigned.
+func (cfg *Config) DisplayValueOf(id string) string {
+	if parm := cfg.parameter(id); parm != nil && cfg.AssignedValue(id) {
+		return cfg.displayValue(parm, cfg.ValueOf(id))
+	}
+	return cfg.ValueOf(id)
+}
+
+// displayValue returns the given value of the referenced parameter to show,
+// masked if the parameter is secret or its value was resolved through a
+// reference.
+func (cfg *Config) displayValue(parm *model.Parameter, value string) string {
+	if parm.Secret || len(cfg.SourceOf(parm.OptionId).Reference) > 0 {
+		return MASKED_VALUE
+	}
+	return value
+}
+
+// propertyLines returns the line number of each property key in the given
+// properties file. A property continued over several lines is on the line
+// where its key is, and a property given more than once on its last line.
+func propertyLines(filename string) (map[string]int, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	lines := map[string]int{}
+	continued := false
+	scanner := bufio.NewScanner(file)
+	for num := 1; scanner.Scan(); num++ {
+		line := strings.TrimLeft(scanner.Text(), " \t\f")
+		if continued {
+			continued = continues(line)
+			continue
+		}
+		if len(line) == 0 || line[0] == '#' || line[0] == '!' {
+			continue
+		}
+		continued = continues(line)
+
+		end := 0
+		for end < len(line) && !strings.ContainsRune("=: \t\f", rune(line[end])) {
+			if line[end] == '\\' {
+				end++
+			}
+			end++
+		}
+		lines[strings.ReplaceAll(line[:min(end, len(line))], "\\", "")] = num
+	}
+
+	return lines, scanner.Err()
+}
+
+// continues returns true if the given properties file line continues on the
+// next line, i.e. ends with an odd number of backslashes.
+func continues(line string) bool {
+	trailing := len(line) - len(strings.TrimRight(line, "\\"))
+	return trailing%2 == 1
+}
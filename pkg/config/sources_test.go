@@ -0,0 +1,89 @@
+package config
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"Cloudtacts/pkg/model"
+)
+
+func TestSourceOf(t *testing.T) {
+	args := os.Args
+	os.Args = []string{args[0], "--userdbDialect=sqlite"}
+	defer func() { os.Args = args }()
+	t.Setenv("CT_USERDB_TEST_MODE", "true")
+	t.Setenv("CT_USERDB_CREDENTIALS", "secret")
+
+	scfg, err := ContextConfig()
+	if err != nil {
+		t.Fatalf("Error parsing configuration: %v", err)
+	}
+
+	for _, test := range []struct {
+		id     string
+		source Source
+		value  string
+	}{
+		{model.KEY_USERDB_DIALECT, Source{Kind: SOURCE_CLI, Name: "--userdbDialect"}, "sqlite"},
+		{model.KEY_USERDB_TEST_MODE, Source{Kind: SOURCE_ENV, Name: "CT_USERDB_TEST_MODE"}, "true"},
+		{model.KEY_USERDB_PASSWORD, Source{Kind: SOURCE_ENV, Name: "CT_USERDB_CREDENTIALS"}, MASKED_VALUE},
+		{model.KEY_USERDB_TOKEN_KEYS, Source{Kind: SOURCE_DEFAULT}, model.USER_MUST_PROVIDE},
+		{model.KEY_CLOUD_PROJECT, Source{Kind: SOURCE_DEFAULT}, model.USER_MUST_PROVIDE},
+	} {
+		if source := scfg.SourceOf(test.id); source != test.source {
+			t.Errorf("Expected source %v of %v, got: %v", test.source, test.id, source)
+		}
+		if value := scfg.DisplayValueOf(test.id); value != test.value {
+			t.Errorf("Expected value %q of %v, got: %q", test.value, test.id, value)
+		}
+	}
+
+	source := scfg.SourceOf(model.KEY_USERDB_HOST_IP)
+	if source.Kind != SOURCE_FILE || source.Name != "user.auth.db.host" || source.File != model.ApplicationConfigPath {
+		t.Fatalf("Expected the host from %v, got: %v", model.ApplicationConfigPath, source)
+	}
+	file, err := os.Open(source.File)
+	if err != nil {
+		t.Fatalf("Error opening %v: %v", source.File, err)
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for num := 1; scanner.Scan() && num <= source.Line; num++ {
+		if num == source.Line && !strings.HasPrefix(scanner.Text(), "user.auth.db.host=") {
+			t.Errorf("Expected the host on line %d, got: %v", num, scanner.Text())
+		}
+	}
+}
+
+func TestPropertyLines(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "test.properties")
+	content := "# comment \\\n" +
+		"a=1\n" +
+		"  b : 2\n" +
+		"! c=3\n" +
+		"d=one, \\\n" +
+		"  e=two\n" +
+		"f\\=g=4\n" +
+		"h 5\n" +
+		"a=6\n"
+	if err := os.WriteFile(filename, []byte(content), 0600); err != nil {
+		t.Fatalf("Error writing %v: %v", filename, err)
+	}
+
+	lines, err := propertyLines(filename)
+	if err != nil {
+		t.Fatalf("Error reading %v: %v", filename, err)
+	}
+	expected := map[string]int{"a": 9, "b": 3, "d": 5, "f=g": 7, "h": 8}
+	if len(lines) != len(expected) {
+		t.Errorf("Expected lines %v, got: %v", expected, lines)
+	}
+	for key, line := range expected {
+		if lines[key] != line {
+			t.Errorf("Expected %v on line %d, got: %d", key, line, lines[key])
+		}
+	}
+}
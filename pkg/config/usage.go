@@ -0,0 +1,156 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"Cloudtacts/pkg/model"
+)
+
+// Header of the properties template (see PropertiesTemplate), given the CLI
+// argument prefix switch.
+const TEMPLATE_HEADER = `## CLI Configuration Template:
+## Any CLI argument equivalent properties can be entered here and loaded by
+## passing parameter %[1]vconfigFile=<filename> to the application.
+##
+## Any corresponding environment variables set in the runtime environment or
+## CLI arguments also passed to the CLI override those added here.
+##
+## Final order of precedence for configuration, from most significant to least,
+## is:
+##
+## 1. CLI argument
+## 2. Environment variable
+## 3. Configuration property
+##
+## **NOTE: arguments, variables, and property keys are CASE SENSITIVE.**
+##
+## This template is generated from the parser configuration (parameters'
+## comments, defaults, and sections included): regenerate it with
+## 'make properties' rather than editing it.
+##
+`
+
+// CommandParameters returns the identifiers of the parameters taken by the
+// given command, in the order they are configured.
+func (cfg *Config) CommandParameters(command string) []string {
+	ids := []string{}
+	for _, parm := range cfg.Parameters() {
+		if len(parm.Commands) == 0 || slices.Contains(parm.Commands, command) {
+			ids = append(ids, parm.OptionId)
+		}
+	}
+	return ids
+}
+
+// Usage writes the usage of the given command, summarized by the given text,
+// to the given writer: the CLI argument of each parameter the command takes,
+// with its description, environment variable, property, and default value.
+func (cfg *Config) Usage(w io.Writer, command string, summary string) error {
+	sep := string(cfg.argSeparator)
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "Usage: %v [%voption%vvalue ...]\n\n", command, cfg.argSwitch, sep)
+	for _, line := range wrap(summary, 79) {
+		fmt.Fprintln(&b, line)
+	}
+
+	fmt.Fprintf(&b, "\nOptions:\n")
+	for _, id := range cfg.CommandParameters(command) {
+		parm := cfg.parameter(id)
+
+		placeholder := typeOf(parm)
+		if len(parm.Enum) > 0 {
+			placeholder = strings.Join(parm.Enum, "|")
+		}
+		fmt.Fprintf(&b, "  %v%v%v<%v>\n", cfg.argSwitch, parm.CliArgument, sep, placeholder)
+		for _, line := range wrap(parm.Description, 71) {
+			fmt.Fprintf(&b, "        %v\n", line)
+		}
+
+		details := []string{"env " + parm.EnvironmentVar, "property " + parm.PropertyName}
+		switch {
+		case parm.Required:
+			details = append(details, "required")
+		case len(parm.RequiredIf) > 0:
+			details = append(details, "required if "+parm.RequiredIf)
+		}
+		if len(parm.DefaultVal) > 0 && parm.DefaultVal != model.USER_MUST_PROVIDE {
+			details = append(details, "default "+parm.DefaultVal)
+		}
+		for _, line := range wrap("("+strings.Join(details, ", ")+")", 71) {
+			fmt.Fprintf(&b, "        %v\n", line)
+		}
+	}
+	fmt.Fprintf(&b, "  %vhelp\n        Show this usage.\n", cfg.argSwitch)
+
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// UsageOnHelp writes the usage of the given command, summarized by the given
+// text, to standard output and exits if the command line asks for help
+// (--help, or -h).
+func (cfg *Config) UsageOnHelp(command string, summary string) {
+	if cfg.helpRequested || slices.Contains(cfg.arguments, "-h") {
+		if err := cfg.Usage(os.Stdout, command, summary); err != nil {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+}
+
+// PropertiesTemplate returns the annotated application properties template
+// generated from the parser configuration: each parameter's property set to
+// its default value, commented with the parameter's comment (or
+// description), CLI argument, and environment variable.
+func (cfg *Config) PropertiesTemplate() []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, TEMPLATE_HEADER, cfg.argSwitch)
+
+	for _, parm := range cfg.Parameters() {
+		b.WriteString("\n")
+		if len(parm.Section) > 0 {
+			banner := strings.Repeat("#", len(parm.Section)+8)
+			fmt.Fprintf(&b, "%v\n##  %v  ##\n%v\n", banner, parm.Section, banner)
+		}
+
+		comment := parm.Comment
+		if len(comment) == 0 {
+			comment = wrap(parm.Description, 77)
+		}
+		for _, line := range comment {
+			fmt.Fprintln(&b, strings.TrimRight("# "+line, " "))
+		}
+		fmt.Fprintf(&b, "#\n# Superseded by -\n#   1. CLI parameter: %v%v\n#   2. Env variable:  %v\n#\n",
+			cfg.argSwitch, parm.CliArgument, parm.EnvironmentVar)
+		fmt.Fprintf(&b, "%v=%v\n", parm.PropertyName, parm.DefaultVal)
+	}
+
+	return b.Bytes()
+}
+
+// wrap returns the words of the given text as lines no longer than the
+// given width, unless a single word is.
+func wrap(text string, width int) []string {
+	lines := []string{}
+	line := ""
+	for _, word := range strings.Fields(text) {
+		switch {
+		case len(line) == 0:
+			line = word
+		case len(line)+1+len(word) > width:
+			lines = append(lines, line)
+			line = word
+		default:
+			line += " " + word
+		}
+	}
+	if len(line) > 0 {
+		lines = append(lines, line)
+	}
+	return lines
+}
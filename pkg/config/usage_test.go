@@ -0,0 +1,92 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"Cloudtacts/pkg/model"
+)
+
+func TestPropertiesTemplateDrift(t *testing.T) {
+	committed, err := os.ReadFile(model.ApplicationConfigPath)
+	if err != nil {
+		t.Fatalf("Error reading %v: %v", model.ApplicationConfigPath, err)
+	}
+	if !bytes.Equal(cfg.PropertiesTemplate(), bytes.ReplaceAll(committed, []byte("\r\n"), []byte("\n"))) {
+		t.Errorf("%v is out of date with %v, regenerate it with: make properties", model.ApplicationConfigPath, model.ParserConfigPath)
+	}
+}
+
+func TestCommandParameters(t *testing.T) {
+	for _, parm := range cfg.Parameters() {
+		for _, command := range parm.Commands {
+			if !strings.Contains(strings.Join(cfg.CommandParameters(command), ","), parm.OptionId) {
+				t.Errorf("Expected %v among the parameters of %v", parm.OptionId, command)
+			}
+		}
+	}
+
+	ids := cfg.CommandParameters("migrate")
+	for _, test := range []struct {
+		id    string
+		taken bool
+	}{
+		{model.KEY_CONFIG_FILE, true},
+		{model.KEY_USERDB_DIALECT, true},
+		{model.KEY_MIGRATE_TARGET, true},
+		{model.KEY_USERDB_UNIQUENESS, false},
+		{model.KEY_CLIENT_COMMAND, false},
+	} {
+		if taken := strings.Contains(","+strings.Join(ids, ",")+",", ","+test.id+","); taken != test.taken {
+			t.Errorf("Expected %v taken by migrate %v, got: %v", test.id, test.taken, taken)
+		}
+	}
+}
+
+func TestUsage(t *testing.T) {
+	var b bytes.Buffer
+	if err := cfg.Usage(&b, "migrate", "Migrates the schema of the user database."); err != nil {
+		t.Fatalf("Error writing usage: %v", err)
+	}
+	usage := b.String()
+
+	for _, expected := range []string{
+		"Usage: migrate [--option=value ...]\n\nMigrates the schema of the user database.\n",
+		"  --migrateCommand=<up|down|status>\n        User database schema migration command",
+		"(env CT_MIGRATE_COMMAND, property migrate.command, default status)",
+		"  --migrateTarget=<int>\n",
+		"required if\n        userdbTestModeId=false,userdbDialectId!=sqlite)",
+		"  --help\n",
+	} {
+		if !strings.Contains(usage, expected) {
+			t.Errorf("Expected usage containing %q, got:\n%v", expected, usage)
+		}
+	}
+	if strings.Contains(usage, "--userdbUniqueness") {
+		t.Errorf("Expected no parameters migrate doesn't take, got:\n%v", usage)
+	}
+	for _, line := range strings.Split(usage, "\n") {
+		if len(line) > 80 {
+			t.Errorf("Expected usage lines of at most 80 characters, got: %q", line)
+		}
+	}
+}
+
+func TestWrap(t *testing.T) {
+	for _, test := range []struct {
+		text  string
+		width int
+		lines []string
+	}{
+		{"", 10, []string{}},
+		{"one two three", 10, []string{"one two", "three"}},
+		{"one  two\nthree", 13, []string{"one two three"}},
+		{"a verylongword b", 5, []string{"a", "verylongword", "b"}},
+	} {
+		if lines := wrap(test.text, test.width); strings.Join(lines, "|") != strings.Join(test.lines, "|") || len(lines) != len(test.lines) {
+			t.Errorf("Expected %q wrapped at %d as %q, got: %q", test.text, test.width, test.lines, lines)
+		}
+	}
+}
@@ -0,0 +1,267 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"Cloudtacts/pkg/model"
+)
+
+// Int returns the integer value of the given int parameter, or an error if
+// the parameter is unassigned or its value is malformed or out of range.
+func (cfg *Config) Int(id string) (int, error) {
+	val, err := cfg.typedValue(id, model.TYPE_INT)
+	if err != nil {
+		return 0, err
+	}
+	return int(val.(int64)), nil
+}
+
+// Bool returns the boolean value of the given bool parameter, or an error if
+// the parameter is unassigned or its value is malformed.
+func (cfg *Config) Bool(id string) (bool, error) {
+	val, err := cfg.typedValue(id, model.TYPE_BOOL)
+	if err != nil {
+		return false, err
+	}
+	return val.(bool), nil
+}
+
+// Duration returns the value of the given duration parameter, given either as
+// a number in the parameter's unit (e.g. "30") or as a Go duration (e.g.
+// "1m30s"), or an error if the parameter is unassigned or its value is
+// malformed or out of range.
+func (cfg *Config) Duration(id string) (time.Duration, error) {
+	val, err := cfg.typedValue(id, model.TYPE_DURATION)
+	if err != nil {
+		return 0, err
+	}
+	return val.(time.Duration), nil
+}
+
+// List returns the elements of the given comma separated list parameter, or
+// an error if the parameter is unassigned or an element isn't one of its
+// enumerated values.
+func (cfg *Config) List(id string) ([]string, error) {
+	val, err := cfg.typedValue(id, model.TYPE_LIST)
+	if err != nil {
+		return nil, err
+	}
+	return val.([]string), nil
+}
+
+// URL returns the absolute URL value of the given url parameter, or an error
+// if the parameter is unassigned or its value is malformed.
+func (cfg *Config) URL(id string) (*url.URL, error) {
+	val, err := cfg.typedValue(id, model.TYPE_URL)
+	if err != nil {
+		return nil, err
+	}
+	return val.(*url.URL), nil
+}
+
+// Validate checks the parameters with the given identifiers, or all
+// parameters if none are given, against their parser configuration: required
+// parameters must be assigned a value, and assigned values must be of their
+// parameter's type, within its range, and one of its enumerated values. All
+// the problems found are reported by the returned error.
+func (cfg *Config) Validate(ids ...string) error {
+	if cfg.parserConfig == nil {
+		return errors.New("configuration not parsed")
+	}
+
+	errs := []error{}
+	for _, id := range ids {
+		if cfg.parameter(id) == nil {
+			errs = append(errs, fmt.Errorf("unknown parameter %v", id))
+		}
+	}
+
+	for i := range cfg.parserConfig.Parameters {
+		parm := &cfg.parserConfig.Parameters[i]
+		if len(ids) > 0 && !slices.Contains(ids, parm.OptionId) {
+			continue
+		}
+
+		if !cfg.AssignedValue(parm.OptionId) {
+			if required, err := cfg.required(parm); err != nil {
+				errs = append(errs, err)
+			} else if required {
+				errs = append(errs, fmt.Errorf("%v: required value not provided", cfg.describe(parm)))
+			}
+			continue
+		}
+
+		if _, err := cfg.parseValue(parm, cfg.ValueOf(parm.OptionId)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// parameter returns the parser configuration of the given parameter, or nil
+// if there is no such parameter.
+func (cfg *Config) parameter(id string) *model.Parameter {
+	if cfg.parserConfig == nil {
+		return nil
+	}
+	for i := range cfg.parserConfig.Parameters {
+		if cfg.parserConfig.Parameters[i].OptionId == id {
+			return &cfg.parserConfig.Parameters[i]
+		}
+	}
+	return nil
+}
+
+// typedValue returns the value of the given parameter of the given type,
+// parsed and checked as by parseValue.
+func (cfg *Config) typedValue(id string, typ string) (any, error) {
+	parm := cfg.parameter(id)
+	switch {
+	case parm == nil:
+		return nil, fmt.Errorf("unknown parameter %v", id)
+	case typeOf(parm) != typ:
+		return nil, fmt.Errorf("%v: parameter of type %v, not %v", cfg.describe(parm), typeOf(parm), typ)
+	case !cfg.AssignedValue(id):
+		return nil, fmt.Errorf("%v: value not provided", cfg.describe(parm))
+	}
+
+	return cfg.parseValue(parm, cfg.ValueOf(id))
+}
+
+// parseValue returns the given value of the referenced parameter converted to
+// its type (int64, bool, time.Duration, []string, *url.URL, or string), or an
+// error if the value is malformed, out of range, or not one of the
+// parameter's enumerated values.
+func (cfg *Config) parseValue(parm *model.Parameter, value string) (any, error) {
+	switch typeOf(parm) {
+	case model.TYPE_INT:
+		ival, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%v: '%v' is not an integer", cfg.describe(parm), cfg.displayValue(parm, value))
+		}
+		return ival, cfg.checkRange(parm, ival)
+	case model.TYPE_BOOL:
+		bval, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("%v: '%v' is not a boolean", cfg.describe(parm), cfg.displayValue(parm, value))
+		}
+		return bval, nil
+	case model.TYPE_DURATION:
+		unit, err := unitOf(parm)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %w", cfg.describe(parm), err)
+		}
+		var dval time.Duration
+		if ival, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64); err == nil {
+			dval = time.Duration(ival) * unit
+		} else if dval, err = time.ParseDuration(strings.TrimSpace(value)); err != nil {
+			return nil, fmt.Errorf("%v: '%v' is not a duration", cfg.describe(parm), cfg.displayValue(parm, value))
+		}
+		return dval, cfg.checkRange(parm, int64(dval/unit))
+	case model.TYPE_LIST:
+		list := []string{}
+		for _, elem := range strings.Split(value, ",") {
+			if elem = strings.TrimSpace(elem); len(elem) > 0 {
+				if err := cfg.checkEnum(parm, elem); err != nil {
+					return nil, err
+				}
+				list = append(list, elem)
+			}
+		}
+		return list, nil
+	case model.TYPE_URL:
+		uval, err := url.ParseRequestURI(strings.TrimSpace(value))
+		if err != nil || len(uval.Scheme) == 0 || len(uval.Host) == 0 {
+			return nil, fmt.Errorf("%v: '%v' is not an absolute URL", cfg.describe(parm), cfg.displayValue(parm, value))
+		}
+		return uval, nil
+	case model.TYPE_STRING:
+		return value, cfg.checkEnum(parm, value)
+	}
+
+	return nil, fmt.Errorf("%v: unknown parameter type %v", cfg.describe(parm), parm.Type)
+}
+
+// checkRange returns an error if the given number is outside the range of
+// the referenced parameter.
+func (cfg *Config) checkRange(parm *model.Parameter, n int64) error {
+	switch {
+	case parm.Min != nil && n < *parm.Min:
+		return fmt.Errorf("%v: %v%v is less than the minimum of %v%v", cfg.describe(parm), n, parm.Unit, *parm.Min, parm.Unit)
+	case parm.Max != nil && n > *parm.Max:
+		return fmt.Errorf("%v: %v%v is more than the maximum of %v%v", cfg.describe(parm), n, parm.Unit, *parm.Max, parm.Unit)
+	}
+	return nil
+}
+
+// checkEnum returns an error if the referenced parameter enumerates its
+// values, and the given value (ignoring case) isn't one of them.
+func (cfg *Config) checkEnum(parm *model.Parameter, value string) error {
+	if len(parm.Enum) == 0 {
+		return nil
+	}
+	for _, allowed := range parm.Enum {
+		if strings.EqualFold(value, allowed) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%v: '%v' is not one of: %v", cfg.describe(parm), cfg.displayValue(parm, value), strings.Join(parm.Enum, ", "))
+}
+
+// required returns true if the referenced parameter is required, or if all
+// of its RequiredIf conditions hold.
+func (cfg *Config) required(parm *model.Parameter) (bool, error) {
+	if parm.Required || len(parm.RequiredIf) == 0 {
+		return parm.Required, nil
+	}
+
+	for _, cond := range strings.Split(parm.RequiredIf, ",") {
+		id, value, found := strings.Cut(strings.TrimSpace(cond), "=")
+		id, negated := strings.CutSuffix(id, "!")
+		if !found || cfg.parameter(id) == nil {
+			return false, fmt.Errorf("%v: malformed condition '%v'", cfg.describe(parm), cond)
+		}
+		if strings.EqualFold(cfg.ValueOf(id), value) == negated {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// describe returns the referenced parameter's identifier and the ways it can
+// be assigned a value, for error messages.
+func (cfg *Config) describe(parm *model.Parameter) string {
+	return fmt.Sprintf("parameter %v (%v%v, %v, %v)", parm.OptionId, cfg.argSwitch, parm.CliArgument, parm.EnvironmentVar, parm.PropertyName)
+}
+
+// typeOf returns the type of the referenced parameter, string by default.
+func typeOf(parm *model.Parameter) string {
+	if len(parm.Type) == 0 {
+		return model.TYPE_STRING
+	}
+	return parm.Type
+}
+
+// unitOf returns the unit of the referenced duration parameter, seconds by
+// default.
+func unitOf(parm *model.Parameter) (time.Duration, error) {
+	switch parm.Unit {
+	case "", "s":
+		return time.Second, nil
+	case "m":
+		return time.Minute, nil
+	case "h":
+		return time.Hour, nil
+	case "d":
+		return 24 * time.Hour, nil
+	}
+	return 0, fmt.Errorf("unknown duration unit %v", parm.Unit)
+}
@@ -0,0 +1,118 @@
+package config
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"Cloudtacts/pkg/model"
+)
+
+func TestTypedValues(t *testing.T) {
+	t.Setenv("CT_USERDB_QUERY_TIMEOUT", "1m30s")
+	vcfg, err := ContextConfig()
+	if err != nil {
+		t.Fatalf("Error parsing configuration: %v", err)
+	}
+
+	if port, err := vcfg.Int(model.KEY_USERDB_PORT_NUM); err != nil || port != 3306 {
+		t.Errorf("Expected port 3306, got %v: %v", port, err)
+	}
+	if testMode, err := vcfg.Bool(model.KEY_USERDB_TEST_MODE); err != nil || testMode {
+		t.Errorf("Expected test mode false, got %v: %v", testMode, err)
+	}
+	for _, test := range []struct {
+		id       string
+		duration time.Duration
+	}{
+		{model.KEY_USERDB_MAX_IDTM, 300 * time.Second},
+		{model.KEY_USERDB_MAX_LFTM, 30 * time.Minute},
+		{model.KEY_USERDB_SESSION_LIFETIME, 30 * 24 * time.Hour},
+		{model.KEY_USERDB_QUERY_TIMEOUT, 90 * time.Second},
+	} {
+		if duration, err := vcfg.Duration(test.id); err != nil || duration != test.duration {
+			t.Errorf("Expected %v of %v, got %v: %v", test.duration, test.id, duration, err)
+		}
+	}
+	if list, err := vcfg.List(model.KEY_USERDB_UNIQUENESS); err != nil || !slices.Equal(list, []string{"collective"}) {
+		t.Errorf("Expected uniqueness [collective], got %v: %v", list, err)
+	}
+	if url, err := vcfg.URL(model.KEY_NOTIFY_CONFIRM_URL); err != nil || url.Host != "localhost:8888" {
+		t.Errorf("Expected confirmation URL on localhost:8888, got %v: %v", url, err)
+	}
+
+	if _, err := vcfg.Int(model.KEY_USERDB_DIALECT); err == nil {
+		t.Errorf("Expected an error getting string parameter %v as int", model.KEY_USERDB_DIALECT)
+	}
+	if _, err := vcfg.List(model.KEY_USERDB_TOKEN_KEYS); err == nil {
+		t.Errorf("Expected an error getting unassigned parameter %v", model.KEY_USERDB_TOKEN_KEYS)
+	}
+	if _, err := vcfg.Int("noSuchId"); err == nil {
+		t.Errorf("Expected an error getting unknown parameter")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		env  map[string]string
+		ids  []string
+		errs []string
+	}{
+		{"defaults", nil, []string{model.KEY_USERDB_PORT_NUM, model.KEY_USERDB_MAX_POOL, model.KEY_USERDB_UNIQUENESS}, nil},
+		{"required", nil, []string{model.KEY_USERDB_PASSWORD, model.KEY_USERDB_TOKEN_KEYS},
+			[]string{"userdbCredsId", "userdbTokenKeysId"}},
+		{"required in test mode", map[string]string{"CT_USERDB_TEST_MODE": "true"},
+			[]string{model.KEY_USERDB_PASSWORD, model.KEY_USERDB_TOKEN_KEYS, model.KEY_STORAGE_BUCKET}, nil},
+		{"required by dialect", map[string]string{"CT_USERDB_DIALECT": "sqlite"}, []string{model.KEY_USERDB_PASSWORD}, nil},
+		{"required by store type", map[string]string{"CT_STORAGE_TYPE": "s3"},
+			[]string{model.KEY_STORAGE_BUCKET, model.KEY_STORAGE_S3_ACCESS_KEY, model.KEY_STORAGE_S3_SECRET_KEY},
+			[]string{"storageS3AccessKeyId", "storageS3SecretKeyId"}},
+		{"malformed", map[string]string{
+			"CT_USERDB_PORT_NUM":             "port",
+			"CT_USERDB_UNIQUENESS":           "login,profile",
+			"CT_USERDB_PASSWORD_BCRYPT_COST": "40",
+			"CT_USERDB_TOKEN_LIFETIME":       "0",
+			"CT_STORAGE_TYPE":                "ftp",
+			"CT_STORAGE_S3_USE_SSL":          "yes",
+			"CT_NOTIFY_CONFIRM_URL":          "/ValidateUser",
+		}, []string{
+			model.KEY_USERDB_PORT_NUM, model.KEY_USERDB_UNIQUENESS, model.KEY_USERDB_PWD_BCRYPT_COST, model.KEY_USERDB_TOKEN_LIFETIME,
+			model.KEY_STORAGE_TYPE, model.KEY_STORAGE_S3_USE_SSL, model.KEY_NOTIFY_CONFIRM_URL,
+		}, []string{
+			"'port' is not an integer", "'profile' is not one of", "40 is more than the maximum", "0m is less than the minimum",
+			"'ftp' is not one of", "'yes' is not a boolean", "not an absolute URL",
+		}},
+		{"unknown", nil, []string{"noSuchId"}, []string{"unknown parameter noSuchId"}},
+		{"all", nil, nil, []string{"commandId", "inputId", "userdbCredsId", "userdbTokenKeysId", "storageBucketNameId"}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			for name, value := range test.env {
+				t.Setenv(name, value)
+			}
+			vcfg, err := ContextConfig()
+			if err != nil {
+				t.Fatalf("Error parsing configuration: %v", err)
+			}
+
+			err = vcfg.Validate(test.ids...)
+			if err == nil {
+				if len(test.errs) > 0 {
+					t.Fatalf("Expected errors %v, got none", test.errs)
+				}
+				return
+			}
+
+			errs := err.(interface{ Unwrap() []error }).Unwrap()
+			if len(errs) != len(test.errs) {
+				t.Fatalf("Expected %d error(s), got: %v", len(test.errs), err)
+			}
+			for i, want := range test.errs {
+				if !strings.Contains(errs[i].Error(), want) {
+					t.Errorf("Expected error containing %q, got: %v", want, errs[i])
+				}
+			}
+		})
+	}
+}
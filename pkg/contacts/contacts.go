@@ -0,0 +1,122 @@
+// Package contacts provides access to users' contact records kept in the
+// application's document store.
+package contacts
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"Cloudtacts/pkg/config"
+	"Cloudtacts/pkg/model"
+	"Cloudtacts/pkg/util"
+)
+
+const (
+	STORE_TYPE_MEMORY = "memory"
+)
+
+// ContactStore accesses users' contact records. Its methods are given the
+// context of the request they serve, e.g. to be cancelled with it.
+type ContactStore interface {
+	// Returns all contacts owned by the referenced user.
+	ListContacts(context.Context, *model.User) ([]model.Contact, model.ServiceError)
+
+	// Returns the contact with the given identifier owned by the referenced
+	// user.
+	GetContact(context.Context, *model.User, string) (*model.Contact, model.ServiceError)
+
+	// Adds the referenced contact to those owned by the referenced user. The
+	// contact is assigned a new identifier if it doesn't already have one.
+	AddContact(context.Context, *model.User, *model.Contact) model.ServiceError
+
+	// Updates the referenced contact owned by the referenced user.
+	UpdateContact(context.Context, *model.User, *model.Contact) model.ServiceError
+
+	// Deletes the contact with the given identifier owned by the referenced
+	// user.
+	DeleteContact(context.Context, *model.User, string) model.ServiceError
+
+	// Releases the store. Connections shared by the process stay open.
+	Close()
+}
+
+// GetContactStore returns the contact store implementation selected by the
+// configured store type.
+func GetContactStore(cfg *config.Config) (ContactStore, model.ServiceError) {
+	storeType := strings.ToLower(cfg.ValueOfWithDefault(model.KEY_CONTACTS_STORE_TYPE, STORE_TYPE_FIRESTORE))
+
+	switch storeType {
+	case STORE_TYPE_FIRESTORE:
+		fs, serr := newFirestoreStore(cfg)
+		if serr.IsError() {
+			return nil, serr
+		}
+		return fs, model.NoError
+	case STORE_TYPE_MEMORY:
+		traceIt(cfg, "Contact store using in-memory records.")
+		return sharedMemoryStore(), model.NoError
+	default:
+		util.LogIt("Cloudtacts", fmt.Sprintf("Unknown contact store type: '%v'", storeType))
+		return nil, model.ContactsStoreError.WithCause(fmt.Errorf("unknown store type '%v'", storeType))
+	}
+}
+
+// NewContactId returns a new unique contact identifier.
+func NewContactId() string {
+	return strings.ReplaceAll(uuid.New().String(), "-", "")
+}
+
+// ownerKey returns the document store key of the given user's contact
+// records.
+func ownerKey(user *model.User) string {
+	return fmt.Sprintf("%v/%v", user.CtUser, user.CtProf)
+}
+
+// validateOwner checks the given contact list owner's key, whose ctuser and
+// ctprof are document path segments (see firestoreStore) and can't contain
+// a '/'.
+func validateOwner(user *model.User) (bool, model.UserError) {
+	switch {
+	case len(user.CtUser) == 0:
+		return false, model.NoUserIdError
+	case len(user.CtProf) == 0:
+		return false, model.NoProfileIdError
+	case strings.Contains(user.CtUser, "/") || strings.Contains(user.CtProf, "/"):
+		return false, model.BadContactKeyError
+	}
+
+	return true, model.UserError{}
+}
+
+// validateContactId checks the given contact identifier, which is a document
+// path segment.
+func validateContactId(id string) (bool, model.UserError) {
+	switch {
+	case len(id) == 0:
+		return false, model.NoContactIdError
+	case strings.Contains(id, "/"):
+		return false, model.BadContactKeyError
+	}
+
+	return true, model.UserError{}
+}
+
+func validateContact(contact *model.Contact) (bool, model.UserError) {
+	if ok, err := validateContactId(contact.CtId); !ok {
+		return false, err
+	}
+	if !contact.HasName() {
+		return false, model.NoContactNameError
+	}
+
+	return true, model.UserError{}
+}
+
+func traceIt(cfg *config.Config, message string) {
+	if testMode, _ := cfg.Bool(model.KEY_USERDB_TEST_MODE); testMode {
+		util.LogIt("Cloudtacts", message)
+	}
+}
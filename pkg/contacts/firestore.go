@@ -0,0 +1,292 @@
+package contacts
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"Cloudtacts/pkg/config"
+	"Cloudtacts/pkg/model"
+	"Cloudtacts/pkg/util"
+)
+
+const (
+	STORE_TYPE_FIRESTORE = "firestore"
+
+	// Environment variable naming the host:port of a local Firestore emulator.
+	// When set, the Firestore client connects to the emulator without
+	// credentials.
+	FIRESTORE_EMULATOR_HOST = "FIRESTORE_EMULATOR_HOST"
+
+	FIRESTORE_TIMEOUT = time.Second * 10
+)
+
+var (
+	fsClient      *firestore.Client
+	fsClientMutex sync.Mutex
+)
+
+// firestoreStore keeps each user's contacts as JSON documents in a per-user
+// collection with path: {collection}/{ctuser}/{ctprof}/{ctid}. Stores share
+// the process' Firestore client (see sharedFirestoreClient).
+type firestoreStore struct {
+	client     *firestore.Client
+	collection string
+}
+
+func (fs *firestoreStore) ListContacts(ctx context.Context, user *model.User) ([]model.Contact, model.ServiceError) {
+	if ok, err := validateOwner(user); !ok {
+		return nil, model.InvalidKeyError.WithCause(err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, FIRESTORE_TIMEOUT)
+	defer cancel()
+
+	list := []model.Contact{}
+	iter := fs.contacts(user).OrderBy(firestore.DocumentID, firestore.Asc).Documents(ctx)
+	defer iter.Stop()
+	for {
+		snap, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, model.ContactsStoreError.WithCause(err)
+		}
+
+		var contact model.Contact
+		if serr := fromDocument(snap, &contact); serr.IsError() {
+			return nil, serr
+		}
+		list = append(list, contact)
+	}
+
+	return list, model.NoError
+}
+
+func (fs *firestoreStore) GetContact(ctx context.Context, user *model.User, id string) (*model.Contact, model.ServiceError) {
+	if ok, err := validateOwner(user); !ok {
+		return nil, model.InvalidKeyError.WithCause(err)
+	}
+	if ok, err := validateContactId(id); !ok {
+		return nil, model.InvalidContactError.WithCause(err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, FIRESTORE_TIMEOUT)
+	defer cancel()
+
+	snap, err := fs.contacts(user).Doc(id).Get(ctx)
+	if err != nil {
+		return nil, storeError(err)
+	}
+
+	contact := new(model.Contact)
+	if serr := fromDocument(snap, contact); serr.IsError() {
+		return nil, serr
+	}
+
+	return contact, model.NoError
+}
+
+func (fs *firestoreStore) AddContact(ctx context.Context, user *model.User, contact *model.Contact) model.ServiceError {
+	if ok, err := validateOwner(user); !ok {
+		return model.InvalidKeyError.WithCause(err)
+	}
+	if len(contact.CtId) == 0 {
+		contact.CtId = NewContactId()
+	}
+	if ok, err := validateContact(contact); !ok {
+		return model.InvalidContactError.WithCause(err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, FIRESTORE_TIMEOUT)
+	defer cancel()
+
+	contact.Created = time.Now().UTC().Format(model.FMT_DATETIME_GO)
+	contact.Updated = contact.Created
+	doc, serr := toDocument(contact)
+	if serr.IsError() {
+		return serr
+	}
+
+	if _, err := fs.contacts(user).Doc(contact.CtId).Create(ctx, doc); err != nil {
+		return storeError(err)
+	}
+
+	return model.NoError
+}
+
+func (fs *firestoreStore) UpdateContact(ctx context.Context, user *model.User, contact *model.Contact) model.ServiceError {
+	if ok, err := validateOwner(user); !ok {
+		return model.InvalidKeyError.WithCause(err)
+	}
+	if ok, err := validateContact(contact); !ok {
+		return model.InvalidContactError.WithCause(err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, FIRESTORE_TIMEOUT)
+	defer cancel()
+
+	ref := fs.contacts(user).Doc(contact.CtId)
+	err := fs.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		snap, err := tx.Get(ref)
+		if err != nil {
+			return err
+		}
+
+		var current model.Contact
+		if serr := fromDocument(snap, &current); serr.IsError() {
+			return serr
+		}
+		contact.Created = current.Created
+		contact.Updated = time.Now().UTC().Format(model.FMT_DATETIME_GO)
+
+		doc, serr := toDocument(contact)
+		if serr.IsError() {
+			return serr
+		}
+
+		return tx.Set(ref, doc)
+	})
+	if err != nil {
+		return storeError(err)
+	}
+
+	return model.NoError
+}
+
+func (fs *firestoreStore) DeleteContact(ctx context.Context, user *model.User, id string) model.ServiceError {
+	if ok, err := validateOwner(user); !ok {
+		return model.InvalidKeyError.WithCause(err)
+	}
+	if ok, err := validateContactId(id); !ok {
+		return model.InvalidContactError.WithCause(err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, FIRESTORE_TIMEOUT)
+	defer cancel()
+
+	if _, err := fs.contacts(user).Doc(id).Delete(ctx, firestore.Exists); err != nil {
+		return storeError(err)
+	}
+
+	return model.NoError
+}
+
+// Close leaves the shared Firestore client open for the store's successors.
+func (fs *firestoreStore) Close() {
+}
+
+// contacts returns the collection of contacts owned by the given user.
+func (fs *firestoreStore) contacts(user *model.User) *firestore.CollectionRef {
+	return fs.client.Collection(fs.collection).Doc(user.CtUser).Collection(user.CtProf)
+}
+
+// newFirestoreStore returns a store of the configured root collection, which
+// must be a single path segment: a collection reference of a path with a '/'
+// is nil.
+func newFirestoreStore(cfg *config.Config) (*firestoreStore, model.ServiceError) {
+	collection := cfg.ValueOfWithDefault(model.KEY_CONTACTS_COLLECTION, "contacts")
+	if len(collection) == 0 || strings.Contains(collection, "/") {
+		return nil, model.ContactsStoreError.WithCause(fmt.Errorf("invalid collection name '%v'", collection))
+	}
+
+	client, serr := sharedFirestoreClient(cfg)
+	if serr.IsError() {
+		return nil, serr
+	}
+
+	fs := new(firestoreStore)
+	fs.client = client
+	fs.collection = collection
+
+	return fs, model.NoError
+}
+
+// sharedFirestoreClient returns the process' Firestore client, opening it on
+// first use. The client is safe for concurrent use and outlives the requests
+// using it; it's opened again on the next use if opening it fails.
+func sharedFirestoreClient(cfg *config.Config) (*firestore.Client, model.ServiceError) {
+	fsClientMutex.Lock()
+	defer fsClientMutex.Unlock()
+
+	if fsClient != nil {
+		return fsClient, model.NoError
+	}
+
+	projectId := firestore.DetectProjectID
+	if cfg.AssignedValue(model.KEY_CLOUD_PROJECT) {
+		projectId = cfg.ValueOf(model.KEY_CLOUD_PROJECT)
+	}
+
+	client, err := firestore.NewClient(context.Background(), projectId)
+	if err != nil {
+		serr := model.ContactsStoreError.WithCause(err)
+		util.LogIt("Cloudtacts", fmt.Sprintf("Failed to create firestore client: %v", serr))
+		return nil, serr
+	}
+
+	if host := os.Getenv(FIRESTORE_EMULATOR_HOST); len(host) > 0 {
+		traceIt(cfg, fmt.Sprintf("Contact store using firestore emulator at %v.", host))
+	} else {
+		traceIt(cfg, fmt.Sprintf("Contact store using firestore for project %v.", projectId))
+	}
+	fsClient = client
+
+	return fsClient, model.NoError
+}
+
+// toDocument converts the given contact to its JSON document representation.
+func toDocument(contact *model.Contact) (map[string]interface{}, model.ServiceError) {
+	doc := make(map[string]interface{})
+
+	bbuff, err := json.Marshal(contact)
+	if err == nil {
+		err = json.Unmarshal(bbuff, &doc)
+	}
+	if err != nil {
+		return nil, model.ContactsStoreError.WithCause(err)
+	}
+
+	return doc, model.NoError
+}
+
+// fromDocument converts the given document snapshot to a contact instance.
+func fromDocument(snap *firestore.DocumentSnapshot, contact *model.Contact) model.ServiceError {
+	bbuff, err := json.Marshal(snap.Data())
+	if err == nil {
+		err = json.Unmarshal(bbuff, contact)
+	}
+	if err != nil {
+		return model.ContactsStoreError.WithCause(err)
+	}
+	contact.CtId = snap.Ref.ID
+
+	return model.NoError
+}
+
+// storeError maps the given Firestore error to its corresponding service
+// error.
+func storeError(err error) model.ServiceError {
+	if serr, ok := err.(model.ServiceError); ok {
+		return serr
+	}
+
+	switch status.Code(err) {
+	case codes.NotFound:
+		return model.ContactMissingError
+	case codes.AlreadyExists:
+		return model.ContactExistsError
+	default:
+		return model.ContactsStoreError.WithCause(err)
+	}
+}
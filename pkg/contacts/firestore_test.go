@@ -0,0 +1,114 @@
+package contacts
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"cloud.google.com/go/firestore"
+
+	"Cloudtacts/pkg/config"
+	"Cloudtacts/pkg/model"
+)
+
+// Firestore integration tests run only against a local emulator, e.g.:
+//
+//	FIRESTORE_EMULATOR_HOST=localhost:8200 go test ./pkg/contacts
+func TestFirestoreContacts(t *testing.T) {
+	if len(os.Getenv(FIRESTORE_EMULATOR_HOST)) == 0 {
+		t.Skipf("%v not set, skipping firestore emulator tests.", FIRESTORE_EMULATOR_HOST)
+	}
+
+	ctx := context.Background()
+	client, err := firestore.NewClient(ctx, "cloudtacts-dev")
+	if err != nil {
+		t.Fatalf("Error creating firestore client: %v", err)
+	}
+	defer client.Close()
+	fs := &firestoreStore{client: client, collection: "contacts-test"}
+
+	contact := model.Contact{FName: "Dark", LName: "Cleric", CEmail: "cleric@example.com"}
+	if serr := fs.AddContact(ctx, owner, &contact); serr.IsError() {
+		t.Fatalf("Error adding contact: %v", serr)
+	}
+	defer fs.DeleteContact(ctx, owner, contact.CtId)
+
+	if serr := fs.AddContact(ctx, owner, &contact); serr != model.ContactExistsError {
+		t.Errorf("Expected %v adding duplicate contact, got: %v", model.ContactExistsError, serr)
+	}
+
+	contact.CPhone = "555-0100"
+	if serr := fs.UpdateContact(ctx, owner, &contact); serr.IsError() {
+		t.Fatalf("Error updating contact: %v", serr)
+	}
+
+	qcontact, serr := fs.GetContact(ctx, owner, contact.CtId)
+	if serr.IsError() {
+		t.Fatalf("Error querying contact: %v", serr)
+	}
+	if *qcontact != contact {
+		t.Errorf("Queried contact %v doesn't match updated contact %v.", qcontact, &contact)
+	}
+
+	list, serr := fs.ListContacts(ctx, owner)
+	if serr.IsError() {
+		t.Fatalf("Error listing contacts: %v", serr)
+	}
+	if len(list) == 0 {
+		t.Error("Added contact not returned in list.")
+	}
+
+	if serr := fs.DeleteContact(ctx, owner, contact.CtId); serr.IsError() {
+		t.Errorf("Error deleting contact: %v", serr)
+	}
+	if serr := fs.DeleteContact(ctx, owner, contact.CtId); serr != model.ContactMissingError {
+		t.Errorf("Expected %v deleting missing contact, got: %v", model.ContactMissingError, serr)
+	}
+}
+
+func TestSharedFirestoreClient(t *testing.T) {
+	model.ParserConfigPath = "../../config/parameters_config.json"
+	model.ApplicationConfigPath = "../../config/application.properties"
+
+	// the emulator client connects on first use, none is needed here
+	t.Setenv(FIRESTORE_EMULATOR_HOST, "localhost:0")
+	t.Setenv("CT_CONTACTS_STORE_TYPE", STORE_TYPE_FIRESTORE)
+	t.Setenv("CT_CLOUD_PROJECT_ID", "cloudtacts-dev")
+	fcfg, err := config.ContextConfig()
+	if err != nil {
+		t.Fatalf("Error parsing configuration: %v", err)
+	}
+
+	var stores []ContactStore
+	for i := 0; i < 2; i++ {
+		cs, serr := GetContactStore(fcfg)
+		if serr.IsError() {
+			t.Fatalf("Error getting contact store: %v", serr)
+		}
+		cs.Close()
+		stores = append(stores, cs)
+	}
+
+	// the client stays open for the store's successors
+	if stores[0].(*firestoreStore).client != stores[1].(*firestoreStore).client {
+		t.Error("Expected contact stores to share the process' firestore client.")
+	}
+}
+
+func TestFirestoreCollectionName(t *testing.T) {
+	model.ParserConfigPath = "../../config/parameters_config.json"
+	model.ApplicationConfigPath = "../../config/application.properties"
+
+	t.Setenv(FIRESTORE_EMULATOR_HOST, "localhost:0")
+	t.Setenv("CT_CONTACTS_STORE_TYPE", STORE_TYPE_FIRESTORE)
+	t.Setenv("CT_CLOUD_PROJECT_ID", "cloudtacts-dev")
+	t.Setenv("CT_CONTACTS_COLLECTION", "contacts/other")
+	fcfg, err := config.ContextConfig()
+	if err != nil {
+		t.Fatalf("Error parsing configuration: %v", err)
+	}
+
+	if _, serr := GetContactStore(fcfg); serr.Code != model.ContactsStoreError.Code {
+		t.Errorf("Expected %v of a collection name with a '/', got: %v", model.ContactsStoreError, serr)
+	}
+}
@@ -0,0 +1,151 @@
+package contacts
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"Cloudtacts/pkg/model"
+)
+
+var (
+	memStore     *memoryStore
+	memStoreOnce sync.Once
+)
+
+// memoryStore keeps contact records in process memory. It's intended for
+// testing and local development only; records are lost on exit.
+type memoryStore struct {
+	mutex  sync.RWMutex
+	owners map[string]map[string]model.Contact
+}
+
+func (ms *memoryStore) ListContacts(_ context.Context, user *model.User) ([]model.Contact, model.ServiceError) {
+	if ok, err := validateOwner(user); !ok {
+		return nil, model.InvalidKeyError.WithCause(err)
+	}
+
+	ms.mutex.RLock()
+	defer ms.mutex.RUnlock()
+
+	list := make([]model.Contact, 0, len(ms.owners[ownerKey(user)]))
+	for _, contact := range ms.owners[ownerKey(user)] {
+		list = append(list, contact)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].CtId < list[j].CtId
+	})
+
+	return list, model.NoError
+}
+
+func (ms *memoryStore) GetContact(_ context.Context, user *model.User, id string) (*model.Contact, model.ServiceError) {
+	if ok, err := validateOwner(user); !ok {
+		return nil, model.InvalidKeyError.WithCause(err)
+	}
+	if ok, err := validateContactId(id); !ok {
+		return nil, model.InvalidContactError.WithCause(err)
+	}
+
+	ms.mutex.RLock()
+	defer ms.mutex.RUnlock()
+
+	if contact, ok := ms.owners[ownerKey(user)][id]; ok {
+		return contact.Clone(), model.NoError
+	}
+
+	return nil, model.ContactMissingError
+}
+
+func (ms *memoryStore) AddContact(_ context.Context, user *model.User, contact *model.Contact) model.ServiceError {
+	if ok, err := validateOwner(user); !ok {
+		return model.InvalidKeyError.WithCause(err)
+	}
+	if len(contact.CtId) == 0 {
+		contact.CtId = NewContactId()
+	}
+	if ok, err := validateContact(contact); !ok {
+		return model.InvalidContactError.WithCause(err)
+	}
+
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	key := ownerKey(user)
+	if _, ok := ms.owners[key]; !ok {
+		ms.owners[key] = make(map[string]model.Contact)
+	}
+	if _, ok := ms.owners[key][contact.CtId]; ok {
+		return model.ContactExistsError
+	}
+
+	contact.Created = time.Now().UTC().Format(model.FMT_DATETIME_GO)
+	contact.Updated = contact.Created
+	ms.owners[key][contact.CtId] = *contact
+
+	return model.NoError
+}
+
+func (ms *memoryStore) UpdateContact(_ context.Context, user *model.User, contact *model.Contact) model.ServiceError {
+	if ok, err := validateOwner(user); !ok {
+		return model.InvalidKeyError.WithCause(err)
+	}
+	if ok, err := validateContact(contact); !ok {
+		return model.InvalidContactError.WithCause(err)
+	}
+
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	key := ownerKey(user)
+	current, ok := ms.owners[key][contact.CtId]
+	if !ok {
+		return model.ContactMissingError
+	}
+
+	contact.Created = current.Created
+	contact.Updated = time.Now().UTC().Format(model.FMT_DATETIME_GO)
+	ms.owners[key][contact.CtId] = *contact
+
+	return model.NoError
+}
+
+func (ms *memoryStore) DeleteContact(_ context.Context, user *model.User, id string) model.ServiceError {
+	if ok, err := validateOwner(user); !ok {
+		return model.InvalidKeyError.WithCause(err)
+	}
+	if ok, err := validateContactId(id); !ok {
+		return model.InvalidContactError.WithCause(err)
+	}
+
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	key := ownerKey(user)
+	if _, ok := ms.owners[key][id]; !ok {
+		return model.ContactMissingError
+	}
+	delete(ms.owners[key], id)
+
+	return model.NoError
+}
+
+func (ms *memoryStore) Close() {
+	// records are kept for the life of the process
+}
+
+func newMemoryStore() *memoryStore {
+	ms := new(memoryStore)
+	ms.owners = make(map[string]map[string]model.Contact)
+
+	return ms
+}
+
+func sharedMemoryStore() *memoryStore {
+	memStoreOnce.Do(func() {
+		memStore = newMemoryStore()
+	})
+
+	return memStore
+}
@@ -0,0 +1,123 @@
+package contacts
+
+import (
+	"context"
+	"testing"
+
+	"Cloudtacts/pkg/model"
+)
+
+var owner = &model.User{CtUser: "pendracon1", CtProf: "Pendracon1", UEmail: "pendracon1@gmail.com"}
+
+func TestAddContact(t *testing.T) {
+	cs := newMemoryStore()
+
+	contact := model.Contact{FName: "Dark", LName: "Cleric", CEmail: "cleric@example.com"}
+	if serr := cs.AddContact(context.Background(), owner, &contact); serr.IsError() {
+		t.Fatalf("Error adding contact: %v", serr)
+	}
+	if len(contact.CtId) == 0 {
+		t.Error("Added contact wasn't assigned an identifier.")
+	}
+
+	if serr := cs.AddContact(context.Background(), owner, &contact); serr != model.ContactExistsError {
+		t.Errorf("Expected %v adding duplicate contact, got: %v", model.ContactExistsError, serr)
+	}
+
+	qcontact, serr := cs.GetContact(context.Background(), owner, contact.CtId)
+	if serr.IsError() {
+		t.Fatalf("Error querying added contact: %v", serr)
+	}
+	if *qcontact != contact {
+		t.Errorf("Queried contact %v doesn't match added contact %v.", qcontact, &contact)
+	}
+}
+
+func TestUpdateContact(t *testing.T) {
+	cs := newMemoryStore()
+
+	contact := model.Contact{FName: "Dark", LName: "Cleric"}
+	if serr := cs.AddContact(context.Background(), owner, &contact); serr.IsError() {
+		t.Fatalf("Error adding contact: %v", serr)
+	}
+
+	contact.CPhone = "555-0100"
+	if serr := cs.UpdateContact(context.Background(), owner, &contact); serr.IsError() {
+		t.Fatalf("Error updating contact: %v", serr)
+	}
+
+	qcontact, serr := cs.GetContact(context.Background(), owner, contact.CtId)
+	if serr.IsError() {
+		t.Fatalf("Error querying updated contact: %v", serr)
+	}
+	if qcontact.CPhone != "555-0100" {
+		t.Error("Updated data not returned in query.")
+	}
+
+	missing := model.Contact{CtId: NewContactId(), FName: "Nobody"}
+	if serr := cs.UpdateContact(context.Background(), owner, &missing); serr != model.ContactMissingError {
+		t.Errorf("Expected %v updating missing contact, got: %v", model.ContactMissingError, serr)
+	}
+}
+
+func TestListAndDeleteContacts(t *testing.T) {
+	cs := newMemoryStore()
+
+	for _, name := range []string{"Chimp", "Dark", "Pdx"} {
+		if serr := cs.AddContact(context.Background(), owner, &model.Contact{FName: name}); serr.IsError() {
+			t.Fatalf("Error adding contact: %v", serr)
+		}
+	}
+
+	other := &model.User{CtUser: "pendraconx", CtProf: "PendraconX"}
+	if serr := cs.AddContact(context.Background(), other, &model.Contact{FName: "Other"}); serr.IsError() {
+		t.Fatalf("Error adding contact: %v", serr)
+	}
+
+	list, serr := cs.ListContacts(context.Background(), owner)
+	if serr.IsError() {
+		t.Fatalf("Error listing contacts: %v", serr)
+	}
+	if len(list) != 3 {
+		t.Fatalf("Expected 3 contacts, got %d.", len(list))
+	}
+
+	if serr := cs.DeleteContact(context.Background(), owner, list[0].CtId); serr.IsError() {
+		t.Errorf("Error deleting contact: %v", serr)
+	}
+	if _, serr := cs.GetContact(context.Background(), owner, list[0].CtId); serr != model.ContactMissingError {
+		t.Errorf("Expected %v querying deleted contact, got: %v", model.ContactMissingError, serr)
+	}
+	if serr := cs.DeleteContact(context.Background(), owner, list[0].CtId); serr != model.ContactMissingError {
+		t.Errorf("Expected %v deleting missing contact, got: %v", model.ContactMissingError, serr)
+	}
+}
+
+func TestInvalidOwner(t *testing.T) {
+	cs := newMemoryStore()
+
+	if _, serr := cs.ListContacts(context.Background(), &model.User{CtUser: "pendracon1"}); serr.Code != model.InvalidKeyError.Code {
+		t.Errorf("Expected %v listing without profile, got: %v", model.InvalidKeyError, serr)
+	}
+
+	for _, user := range []*model.User{
+		{CtUser: "pendracon1/other", CtProf: "Pendracon1"},
+		{CtUser: "pendracon1", CtProf: "Pendracon1/other"},
+	} {
+		if _, serr := cs.ListContacts(context.Background(), user); serr.Code != model.InvalidKeyError.Code || serr.Cause != model.BadContactKeyError {
+			t.Errorf("Expected %v listing contacts of %v/%v, got: %v", model.InvalidKeyError, user.CtUser, user.CtProf, serr)
+		}
+	}
+}
+
+func TestInvalidContactId(t *testing.T) {
+	cs := newMemoryStore()
+
+	if _, serr := cs.GetContact(context.Background(), owner, "contacts/other"); serr.Code != model.InvalidContactError.Code {
+		t.Errorf("Expected %v getting contact with a '/' in its identifier, got: %v", model.InvalidContactError, serr)
+	}
+	contact := model.Contact{CtId: "contacts/other", FName: "Dark"}
+	if serr := cs.AddContact(context.Background(), owner, &contact); serr.Code != model.InvalidContactError.Code {
+		t.Errorf("Expected %v adding contact with a '/' in its identifier, got: %v", model.InvalidContactError, serr)
+	}
+}
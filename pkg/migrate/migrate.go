@@ -0,0 +1,348 @@
+/*
+Package migrate applies and reverts the versioned schema migrations of the user
+database. The migrations of each SQL dialect (see auth.Dialect) are embedded
+from ./migrations/<dialect>, one pair of files per version named:
+<version>_<name>.up.sql and <version>_<name>.down.sql, e.g.:
+
+	mysql/002_widen_ctpass.up.sql
+	mysql/002_widen_ctpass.down.sql
+
+Each dialect has the same versions, making the same changes.
+
+The versions applied to a database are recorded in its schema_version table,
+so applying migrations is idempotent: only pending migrations are applied.
+Each migration and its version record run in one transaction. Note that MySQL
+implicitly commits data definition statements (CREATE, ALTER, DROP), so
+migrations are written to be safely re-run, e.g. with IF [NOT] EXISTS, where
+MySQL allows it; those which can't be say how to recover from a failure.
+PostgreSQL and SQLite run them transactionally.
+*/
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"Cloudtacts/pkg/auth"
+	"Cloudtacts/pkg/model"
+	"Cloudtacts/pkg/util"
+)
+
+const (
+	SELECT_VERSIONS     string = "SELECT version, name, applied FROM schema_version ORDER BY version"
+	INSERT_VERSION_STMT string = "INSERT INTO schema_version (version, name, applied) VALUES(?, ?, ?)"
+	DELETE_VERSION_STMT string = "DELETE FROM schema_version WHERE version = ?"
+
+	MIGRATIONS_DIR = "migrations"
+)
+
+// The schema_version table's definition by dialect
+var createVersionTable = map[string]string{
+	auth.DIALECT_MYSQL:    "CREATE TABLE IF NOT EXISTS schema_version (version INT NOT NULL, name VARCHAR(100) NOT NULL, applied DATETIME NOT NULL, CONSTRAINT PRIMARY KEY (version)) ENGINE=InnoDB",
+	auth.DIALECT_POSTGRES: "CREATE TABLE IF NOT EXISTS schema_version (version INT NOT NULL, name VARCHAR(100) NOT NULL, applied TIMESTAMP NOT NULL, PRIMARY KEY (version))",
+	auth.DIALECT_SQLITE:   "CREATE TABLE IF NOT EXISTS schema_version (version INT NOT NULL, name VARCHAR(100) NOT NULL, applied DATETIME NOT NULL, PRIMARY KEY (version))",
+}
+
+//go:embed migrations
+var migrationFiles embed.FS
+
+var migrationFileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Migration is a versioned change of the user database schema, with the
+// scripts applying (Up) and reverting (Down) it.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+func (mig Migration) String() string {
+	return fmt.Sprintf("%03d_%v", mig.Version, mig.Name)
+}
+
+// Status is a migration's status in a database. Migrations recorded in the
+// database but unknown to this build have no scripts.
+type Status struct {
+	Migration
+	Applied time.Time // zero if pending
+}
+
+func (st Status) IsApplied() bool {
+	return !st.Applied.IsZero()
+}
+
+// Migrator migrates the schema of a user database.
+type Migrator struct {
+	db         *sql.DB
+	dialect    *auth.Dialect
+	migrations []Migration
+}
+
+// NewMigrator returns a migrator of the given database's schema with the
+// embedded migrations of the database's dialect.
+func NewMigrator(db *sql.DB, dialect *auth.Dialect) (*Migrator, model.ServiceError) {
+	migrations, err := loadMigrations(migrationFiles, path.Join(MIGRATIONS_DIR, dialect.Name))
+	if err != nil {
+		return nil, model.DbMigrationError.WithCause(err)
+	}
+
+	return &Migrator{db: db, dialect: dialect, migrations: migrations}, model.NoError
+}
+
+// Migrations returns the migrator's migrations, ordered by version.
+func (m *Migrator) Migrations() []Migration {
+	return m.migrations
+}
+
+// Latest returns the version of the latest migration, or zero if there are
+// none.
+func (m *Migrator) Latest() int {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Status returns the status of the migrations, ordered by version, including
+// those applied to the database but unknown to the migrator.
+func (m *Migrator) Status(ctx context.Context) ([]Status, model.ServiceError) {
+	applied, serr := m.applied(ctx)
+	if serr.IsError() {
+		return nil, serr
+	}
+
+	status := []Status{}
+	for _, mig := range m.migrations {
+		st := Status{Migration: mig}
+		if app, ok := applied[mig.Version]; ok {
+			st.Applied = app.Applied
+			delete(applied, mig.Version)
+		}
+		status = append(status, st)
+	}
+	for _, app := range applied {
+		status = append(status, app)
+	}
+	sort.Slice(status, func(i, j int) bool {
+		return status[i].Version < status[j].Version
+	})
+
+	return status, model.NoError
+}
+
+// Version returns the version of the latest migration applied to the
+// database, or zero if none are.
+func (m *Migrator) Version(ctx context.Context) (int, model.ServiceError) {
+	status, serr := m.Status(ctx)
+	if serr.IsError() {
+		return 0, serr
+	}
+
+	version := 0
+	for _, st := range status {
+		if st.IsApplied() {
+			version = st.Version
+		}
+	}
+
+	return version, model.NoError
+}
+
+// Up applies the pending migrations up to and including the given version in
+// version order, and returns the migrations applied. Migrating stops at the
+// first migration which fails.
+func (m *Migrator) Up(ctx context.Context, target int) ([]Migration, model.ServiceError) {
+	applied, serr := m.applied(ctx)
+	if serr.IsError() {
+		return nil, serr
+	}
+
+	done := []Migration{}
+	for _, mig := range m.migrations {
+		if mig.Version > target {
+			break
+		}
+		if _, ok := applied[mig.Version]; ok {
+			continue
+		}
+
+		if serr = m.run(ctx, mig, mig.Up, INSERT_VERSION_STMT, mig.Version, mig.Name, time.Now().UTC()); serr.IsError() {
+			return done, serr
+		}
+		util.LogIt("Cloudtacts", fmt.Sprintf("Applied migration %v.", mig))
+		done = append(done, mig)
+	}
+
+	return done, model.NoError
+}
+
+// Down reverts the applied migrations after the given version in reverse
+// version order, and returns the migrations reverted. Migrating stops at the
+// first migration which fails, or which is unknown to the migrator.
+func (m *Migrator) Down(ctx context.Context, target int) ([]Migration, model.ServiceError) {
+	status, serr := m.Status(ctx)
+	if serr.IsError() {
+		return nil, serr
+	}
+
+	done := []Migration{}
+	for i := len(status) - 1; i >= 0 && status[i].Version > target; i-- {
+		mig := status[i].Migration
+		if !status[i].IsApplied() {
+			continue
+		}
+		if len(mig.Down) == 0 {
+			return done, model.DbMigrationError.WithCause(fmt.Errorf("no script to revert migration %v", mig))
+		}
+
+		if serr = m.run(ctx, mig, mig.Down, DELETE_VERSION_STMT, mig.Version); serr.IsError() {
+			return done, serr
+		}
+		util.LogIt("Cloudtacts", fmt.Sprintf("Reverted migration %v.", mig))
+		done = append(done, mig)
+	}
+
+	return done, model.NoError
+}
+
+// applied returns the migrations applied to the database by version,
+// creating the schema_version table if not yet created.
+func (m *Migrator) applied(ctx context.Context) (map[int]Status, model.ServiceError) {
+	if _, err := m.db.ExecContext(ctx, createVersionTable[m.dialect.Name]); err != nil {
+		return nil, model.DbMigrationError.WithCause(err)
+	}
+
+	rows, err := m.db.QueryContext(ctx, m.dialect.Rebind(SELECT_VERSIONS))
+	if err != nil {
+		return nil, model.DbQueryError.WithCause(err)
+	}
+	defer rows.Close()
+
+	applied := map[int]Status{}
+	for rows.Next() {
+		var st Status
+		if err = rows.Scan(&st.Version, &st.Name, &st.Applied); err != nil {
+			return nil, model.DbScanError.WithCause(err)
+		}
+		applied[st.Version] = st
+	}
+	if err = rows.Err(); err != nil {
+		return nil, model.DbResultsError.WithCause(err)
+	}
+
+	return applied, model.NoError
+}
+
+// run executes the given migration script, and then the given statement
+// recording the migration's version, in one transaction.
+func (m *Migrator) run(ctx context.Context, mig Migration, script, record string, args ...any) model.ServiceError {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return model.DbMigrationError.WithCause(err)
+	}
+	defer tx.Rollback()
+
+	for _, stmt := range statements(script) {
+		if _, err = tx.ExecContext(ctx, stmt); err != nil {
+			return model.DbMigrationError.WithCause(fmt.Errorf("migration %v: %w", mig, err))
+		}
+	}
+	if _, err = tx.ExecContext(ctx, m.dialect.Rebind(record), args...); err != nil {
+		return model.DbMigrationError.WithCause(fmt.Errorf("migration %v: %w", mig, err))
+	}
+	if err = tx.Commit(); err != nil {
+		return model.DbMigrationError.WithCause(fmt.Errorf("migration %v: %w", mig, err))
+	}
+
+	return model.NoError
+}
+
+// loadMigrations returns the migrations in the given directory of the given
+// file system, ordered by version. Each migration must have both an up and a
+// down script, and a version unique among the migrations.
+func loadMigrations(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, entry := range entries {
+		match := migrationFileName.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			return nil, fmt.Errorf("invalid migration file name: %v", entry.Name())
+		}
+
+		version, _ := strconv.Atoi(match[1])
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = mig
+		} else if mig.Name != match[2] {
+			return nil, fmt.Errorf("duplicate migration version %v: %v, %v", version, mig.Name, match[2])
+		}
+
+		script, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		if match[3] == "up" {
+			mig.Up = string(script)
+		} else {
+			mig.Down = string(script)
+		}
+	}
+
+	migrations := []Migration{}
+	for _, mig := range byVersion {
+		if len(mig.Up) == 0 || len(mig.Down) == 0 {
+			return nil, fmt.Errorf("migration %v is missing its up or down script", mig)
+		}
+		migrations = append(migrations, *mig)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// statements returns the statements of the given script, separated by
+// semicolons at the ends of lines. Comment lines (starting with "--") are
+// dropped.
+func statements(script string) []string {
+	stmts := []string{}
+	var stmt strings.Builder
+	for _, line := range strings.Split(script, "\n") {
+		line = strings.TrimRight(line, "\r \t")
+		if strings.HasPrefix(strings.TrimSpace(line), "--") {
+			continue
+		}
+
+		end := strings.HasSuffix(line, ";")
+		stmt.WriteString(strings.TrimSuffix(line, ";"))
+		stmt.WriteString("\n")
+		if end {
+			if s := strings.TrimSpace(stmt.String()); len(s) > 0 {
+				stmts = append(stmts, s)
+			}
+			stmt.Reset()
+		}
+	}
+	if s := strings.TrimSpace(stmt.String()); len(s) > 0 {
+		stmts = append(stmts, s)
+	}
+
+	return stmts
+}
@@ -0,0 +1,446 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"Cloudtacts/pkg/auth"
+	"Cloudtacts/pkg/config"
+	"Cloudtacts/pkg/model"
+	"Cloudtacts/pkg/util"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	var names []string
+	for _, dialect := range []string{auth.DIALECT_MYSQL, auth.DIALECT_POSTGRES, auth.DIALECT_SQLITE} {
+		migrations, err := loadMigrations(migrationFiles, path.Join(MIGRATIONS_DIR, dialect))
+		if err != nil {
+			t.Fatalf("Error loading embedded %v migrations: %v", dialect, err)
+		}
+
+		dnames := []string{}
+		for i, mig := range migrations {
+			if mig.Version != i+1 {
+				t.Errorf("Expected %v migration version %d, got: %v", dialect, i+1, mig)
+			}
+			dnames = append(dnames, mig.String())
+		}
+		if names == nil {
+			names = dnames
+		} else if strings.Join(dnames, ",") != strings.Join(names, ",") {
+			t.Errorf("Expected %v migrations %v, got: %v", dialect, names, dnames)
+		}
+	}
+
+	invalid := map[string]fstest.MapFS{
+		"bad name":     {"migrations/1_first.sql": {Data: []byte("SELECT 1;")}},
+		"missing down": {"migrations/001_first.up.sql": {Data: []byte("SELECT 1;")}},
+		"duplicate": {
+			"migrations/001_first.up.sql":    {Data: []byte("SELECT 1;")},
+			"migrations/001_first.down.sql":  {Data: []byte("SELECT 1;")},
+			"migrations/001_second.up.sql":   {Data: []byte("SELECT 1;")},
+			"migrations/001_second.down.sql": {Data: []byte("SELECT 1;")},
+		},
+	}
+	for name, fsys := range invalid {
+		if _, err := loadMigrations(fsys, MIGRATIONS_DIR); err == nil {
+			t.Errorf("Expected error loading migrations with %v.", name)
+		}
+	}
+}
+
+func TestStatements(t *testing.T) {
+	script := "-- Create and fill table.\r\nCREATE TABLE t\r\n(\r\n\tc INT\r\n);\r\n\r\nINSERT INTO t VALUES(1);\r\nINSERT INTO t VALUES(2)"
+	want := []string{"CREATE TABLE t\n(\n\tc INT\n)", "INSERT INTO t VALUES(1)", "INSERT INTO t VALUES(2)"}
+
+	got := statements(script)
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("Expected statements %q, got: %q", want, got)
+	}
+}
+
+func TestMigrateUpDown(t *testing.T) {
+	ctx := context.Background()
+	db, fdb := openFakeDB(t)
+	dialect, _ := auth.DialectOf(cfg)
+	m, serr := NewMigrator(db, dialect)
+	if serr.IsError() {
+		t.Fatalf("Error creating migrator: %v", serr)
+	}
+
+	done, serr := m.Up(ctx, m.Latest())
+	if serr.IsError() || len(done) != len(m.Migrations()) {
+		t.Fatalf("Expected %d migrations applied, got %d: %v", len(m.Migrations()), len(done), serr)
+	}
+	if done, serr = m.Up(ctx, m.Latest()); serr.IsError() || len(done) != 0 {
+		t.Errorf("Expected reapplying migrations to do nothing, applied %d: %v", len(done), serr)
+	}
+	if version, _ := m.Version(ctx); version != m.Latest() {
+		t.Errorf("Expected version %d, got: %d", m.Latest(), version)
+	}
+
+	executed := len(fdb.executed)
+	if done, serr = m.Down(ctx, m.Latest()-1); serr.IsError() || len(done) != 1 || done[0].Version != m.Latest() {
+		t.Fatalf("Expected latest migration reverted, got %v: %v", done, serr)
+	}
+	if len(fdb.executed) == executed {
+		t.Error("Reverting migration executed no statements.")
+	}
+	status, _ := m.Status(ctx)
+	if last := status[len(status)-1]; last.IsApplied() {
+		t.Errorf("Expected %v pending after reverting it.", last.Migration)
+	}
+
+	if done, serr = m.Down(ctx, 0); serr.IsError() || len(done) != m.Latest()-1 {
+		t.Errorf("Expected remaining migrations reverted, got %v: %v", done, serr)
+	}
+	if version, _ := m.Version(ctx); version != 0 {
+		t.Errorf("Expected version 0, got: %d", version)
+	}
+}
+
+func TestMigrateFailure(t *testing.T) {
+	ctx := context.Background()
+	db, fdb := openFakeDB(t)
+	dialect, _ := auth.DialectOf(cfg)
+	m := &Migrator{db: db, dialect: dialect, migrations: []Migration{
+		{Version: 1, Name: "first", Up: "CREATE TABLE t (c INT);", Down: "DROP TABLE t;"},
+		{Version: 2, Name: "second", Up: "FAIL;", Down: "SELECT 1;"},
+		{Version: 3, Name: "third", Up: "SELECT 1;", Down: "SELECT 1;"},
+	}}
+
+	done, serr := m.Up(ctx, m.Latest())
+	if serr.Code != model.DbMigrationError.Code || len(done) != 1 {
+		t.Errorf("Expected %v after one migration, got %v: %v", model.DbMigrationError, done, serr)
+	}
+	if versions := fdb.versionList(); len(versions) != 1 || versions[0] != 1 {
+		t.Errorf("Expected only version 1 recorded, got: %v", versions)
+	}
+
+	fdb.versions[9] = time.Now()
+	if _, serr = m.Down(ctx, 0); serr.Code != model.DbMigrationError.Code {
+		t.Errorf("Expected %v reverting unknown migration, got: %v", model.DbMigrationError, serr)
+	}
+}
+
+func TestMigrateSQLite(t *testing.T) {
+	ctx := context.Background()
+	scfg, dialect, m := migrateSQLite(t)
+	database := scfg.ValueOf(model.KEY_USERDB_DATABASE)
+
+	// the migrated schema serves the user database client
+	uc, serr := auth.GetDbClient(scfg, "", "", database)
+	if serr.IsError() {
+		t.Fatalf("Error getting DB client: %v", serr)
+	}
+	defer uc.Close()
+
+	user := &model.User{CtUser: "sqlite1", CtPass: "H:0", CtProf: "SQLite", UEmail: "sqlite1@example.com"}
+	if serr = uc.AddUser(user); serr.IsError() {
+		t.Fatalf("Error adding user: %v", serr)
+	}
+	if serr = uc.AddUser(user); serr.Code != model.DbPKeyError.Code || !dialect.IsDuplicate(serr.Cause) {
+		t.Errorf("Expected %v of a constraint violation adding existing user, got: %v", model.DbPKeyError, serr)
+	}
+	if serr = uc.AddUser(&model.User{UserId: user.UserId, CtUser: "sqlite2", CtPass: "H:0", CtProf: "SQLite", UEmail: "sqlite2@example.com"}); serr.Code != model.DbPKeyError.Code {
+		t.Errorf("Expected %v adding user with existing user ID, got: %v", model.DbPKeyError, serr)
+	}
+
+	user.LLogin = time.Now().UTC().Add(-time.Hour).Format(model.FMT_DATETIME_GO)
+	if serr = uc.UpdateUser(user); serr.IsError() {
+		t.Fatalf("Error updating user: %v", serr)
+	}
+	if users, serr := uc.UnvalidatedUsers(time.Now()); serr.IsError() || len(users) != 1 {
+		t.Errorf("Expected 1 unvalidated user, got %v: %v", users, serr)
+	}
+	if removed, serr := uc.DeleteUnvalidatedUser(user, time.Now().Add(-2*time.Hour)); removed || serr.IsError() {
+		t.Errorf("Expected user added since kept, got removed %v: %v", removed, serr)
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	sess := &model.Session{SessId: "6d2e8a1c-7e3f-4a70-9a84-1b2c3d4e5f60", CtUser: user.CtUser, CtProf: user.CtProf, UEmail: user.UEmail,
+		RtHash: strings.Repeat("0", 64), Created: now, Renewed: now, Expires: now.Add(time.Hour)}
+	if serr = uc.AddSession(sess); serr.IsError() {
+		t.Fatalf("Error adding session: %v", serr)
+	}
+	if serr = uc.AddSession(sess); serr.Code != model.DbPKeyError.Code {
+		t.Errorf("Expected %v adding existing session, got: %v", model.DbPKeyError, serr)
+	}
+	if serr = uc.DeleteUser(user); serr.IsError() {
+		t.Fatalf("Error deleting user: %v", serr)
+	}
+	if sessions, _ := uc.UserSessions(user); len(sessions) != 0 {
+		t.Errorf("Expected user's sessions deleted with the user, got: %v", sessions)
+	}
+
+	// users added in a transaction are only kept if it's committed
+	tx, serr := uc.BeginContext(ctx)
+	if serr.IsError() {
+		t.Fatalf("Error beginning transaction: %v", serr)
+	}
+	if serr = tx.AddUser(user); serr.IsError() {
+		t.Fatalf("Error adding user in transaction: %v", serr)
+	}
+	if serr = tx.Rollback(); serr.IsError() {
+		t.Fatalf("Error rolling back transaction: %v", serr)
+	}
+	if serr = uc.UserInfo(user.Clone()); serr != model.DbPKeyMissingError {
+		t.Errorf("Expected %v querying rolled back user, got: %v", model.DbPKeyMissingError, serr)
+	}
+	if serr = auth.RegisterUser(ctx, scfg, uc, nil, user); serr.IsError() {
+		t.Fatalf("Error registering user: %v", serr)
+	}
+	quser := user.Clone()
+	if serr = uc.UserInfo(quser); serr.IsError() || quser.AToken != user.AToken || len(quser.LLogin) == 0 {
+		t.Errorf("Expected registered user %v, got %v: %v", user, quser, serr)
+	}
+	if users, serr := uc.UsersByLogin(user.UEmail); serr.IsError() || len(users) != 1 || users[0].UserId != user.UserId {
+		t.Errorf("Expected user %v by e-mail address, got %v: %v", user.UserId, users, serr)
+	}
+
+	if removed, serr := uc.DeleteUnvalidatedUser(user, time.Now().Add(time.Minute)); !removed || serr.IsError() {
+		t.Errorf("Expected unvalidated user removed, got removed %v: %v", removed, serr)
+	}
+
+	if done, serr := m.Down(ctx, 0); serr.IsError() || len(done) != len(m.Migrations()) {
+		t.Errorf("Expected %d migrations reverted, got %d: %v", len(m.Migrations()), len(done), serr)
+	}
+}
+
+func TestSQLiteConcurrency(t *testing.T) {
+	const clients = 8
+
+	t.Setenv("CT_USERDB_UNIQUENESS", "login")
+	scfg, _, _ := migrateSQLite(t)
+	uc, serr := auth.GetDbClient(scfg, "", "", scfg.ValueOf(model.KEY_USERDB_DATABASE))
+	if serr.IsError() {
+		t.Fatalf("Error getting DB client: %v", serr)
+	}
+	defer uc.Close()
+
+	// registrations with the same login ID are serialized, one is admitted
+	var wg sync.WaitGroup
+	results := make([]model.ServiceError, clients)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			user := &model.User{CtUser: "race1", CtPass: "H:0", CtProf: fmt.Sprintf("Profile%d", i), UEmail: fmt.Sprintf("race%d@example.com", i)}
+			results[i] = auth.RegisterUser(context.Background(), scfg, uc, nil, user)
+		}(i)
+	}
+	wg.Wait()
+
+	if registered := countResults(t, results, model.DbPKeyError); registered != 1 {
+		t.Errorf("Expected 1 user registered, got %d", registered)
+	}
+	users, serr := uc.UsersByLogin("race1")
+	if serr.IsError() || len(users) != 1 {
+		t.Fatalf("Expected 1 user by login, got %v: %v", users, serr)
+	}
+
+	// refreshes with the same refresh token are conditional, one is renewed
+	user := &users[0]
+	sess, refresh, serr := auth.NewSession(scfg, user, "device1")
+	if serr.IsError() {
+		t.Fatalf("Error creating session: %v", serr)
+	}
+	if serr = uc.AddSession(sess); serr.IsError() {
+		t.Fatalf("Error adding session: %v", serr)
+	}
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, _, results[i] = auth.RefreshSession(context.Background(), scfg, uc, user, refresh)
+		}(i)
+	}
+	wg.Wait()
+
+	if refreshed := countResults(t, results, model.InvalidRefreshError); refreshed != 1 {
+		t.Errorf("Expected 1 session refreshed, got %d", refreshed)
+	}
+	if serr = uc.SessionInfo(&model.Session{SessId: sess.SessId}); serr.Code != model.DbPKeyMissingError.Code {
+		t.Errorf("Expected %v querying session of reused refresh token, got: %v", model.DbPKeyMissingError, serr)
+	}
+}
+
+// migrateSQLite migrates a SQLite user database in a temporary file to the
+// latest schema version, returning its configuration, dialect, and migrator.
+func migrateSQLite(t *testing.T) (*config.Config, *auth.Dialect, *Migrator) {
+	t.Setenv("CT_USERDB_TEST_MODE", "false")
+	t.Setenv("CT_USERDB_DIALECT", auth.DIALECT_SQLITE)
+	t.Setenv("CT_USERDB_DATABASE", filepath.Join(t.TempDir(), "userdb.sqlite"))
+	scfg, err := config.ContextConfig()
+	if err != nil {
+		t.Fatalf("Error parsing configuration: %v", err)
+	}
+
+	dialect, serr := auth.DialectOf(scfg)
+	if serr.IsError() {
+		t.Fatalf("Error getting dialect: %v", serr)
+	}
+	db, serr := auth.OpenDB(scfg, "", "", scfg.ValueOf(model.KEY_USERDB_DATABASE))
+	if serr.IsError() {
+		t.Fatalf("Error opening database: %v", serr)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	m, serr := NewMigrator(db, dialect)
+	if serr.IsError() {
+		t.Fatalf("Error creating migrator: %v", serr)
+	}
+	if done, serr := m.Up(context.Background(), m.Latest()); serr.IsError() || len(done) != len(m.Migrations()) {
+		t.Fatalf("Expected %d migrations applied, got %d: %v", len(m.Migrations()), len(done), serr)
+	}
+
+	return scfg, dialect, m
+}
+
+// countResults returns the number of the given results without error,
+// checking the others are the given expected error.
+func countResults(t *testing.T, results []model.ServiceError, expected model.ServiceError) int {
+	count := 0
+	for _, serr := range results {
+		switch serr.Code {
+		case model.NoError.Code:
+			count++
+		case expected.Code:
+		default:
+			t.Errorf("Expected %v, got: %v", expected, serr)
+		}
+	}
+
+	return count
+}
+
+// fakeDB is a database/sql driver recording the statements executed, and the
+// schema versions recorded, by a migrator. Statements starting with FAIL
+// fail.
+type fakeDB struct {
+	mu       sync.Mutex
+	executed []string
+	versions map[int]time.Time
+}
+
+type fakeDriver struct{}
+type fakeConn struct{ db *fakeDB }
+type fakeTx struct{}
+type fakeStmt struct {
+	db    *fakeDB
+	query string
+}
+type fakeRows struct {
+	versions []int
+	db       *fakeDB
+}
+
+var cfg *config.Config
+var fakeDBs sync.Map
+
+func openFakeDB(t *testing.T) (*sql.DB, *fakeDB) {
+	fdb := &fakeDB{versions: map[int]time.Time{}}
+	fakeDBs.Store(t.Name(), fdb)
+
+	db, err := sql.Open("migratetest", t.Name())
+	if err != nil {
+		t.Fatalf("Error opening fake database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return db, fdb
+}
+
+func (fdb *fakeDB) versionList() []int {
+	versions := []int{}
+	for version := range fdb.versions {
+		versions = append(versions, version)
+	}
+	sort.Ints(versions)
+
+	return versions
+}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) {
+	fdb, _ := fakeDBs.Load(name)
+	return &fakeConn{fdb.(*fakeDB)}, nil
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{c.db, query}, nil
+}
+func (c *fakeConn) Close() error              { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error) { return fakeTx{}, nil }
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	switch s.query {
+	case createVersionTable[auth.DIALECT_MYSQL]:
+	case INSERT_VERSION_STMT:
+		s.db.versions[int(args[0].(int64))] = args[2].(time.Time)
+	case DELETE_VERSION_STMT:
+		delete(s.db.versions, int(args[0].(int64)))
+	default:
+		if strings.HasPrefix(s.query, "FAIL") {
+			return nil, errors.New("statement failed")
+		}
+		s.db.executed = append(s.db.executed, s.query)
+	}
+
+	return driver.RowsAffected(1), nil
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	return &fakeRows{s.db.versionList(), s.db}, nil
+}
+
+func (r *fakeRows) Columns() []string { return []string{"version", "name", "applied"} }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.versions) == 0 {
+		return io.EOF
+	}
+
+	version := r.versions[0]
+	r.versions = r.versions[1:]
+	dest[0] = int64(version)
+	dest[1] = "recorded"
+	dest[2] = r.db.versions[version]
+
+	return nil
+}
+
+func init() {
+	sql.Register("migratetest", fakeDriver{})
+
+	model.ParserConfigPath = "../../config/parameters_config.json"
+	model.ApplicationConfigPath = "../../config/application.properties"
+	var err error
+	if cfg, err = config.ContextConfig(); err != nil {
+		util.LogError("", "migrate_test:init", err)
+	}
+}
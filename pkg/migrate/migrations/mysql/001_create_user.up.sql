@@ -0,0 +1,13 @@
+-- Create user table.
+CREATE TABLE IF NOT EXISTS user
+(
+	ctuser	VARCHAR(20) NOT NULL,
+	ctpass	CHAR(66) NOT NULL,
+	ctprof	VARCHAR(20) NOT NULL,
+	uemail	VARCHAR(50) NOT NULL,
+	ctppic	VARCHAR(52),
+	atoken	CHAR(36),
+	llogin	DATETIME,
+	uvalid	DATETIME,
+	CONSTRAINT PRIMARY KEY (ctuser, ctprof, uemail)
+) ENGINE=InnoDB;
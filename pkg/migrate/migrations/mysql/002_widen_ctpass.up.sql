@@ -0,0 +1,2 @@
+-- Widen ctpass for salted argon2id/bcrypt password hashes.
+ALTER TABLE user MODIFY ctpass VARCHAR(255) NOT NULL;
@@ -0,0 +1,2 @@
+-- Drop the surrogate user ID and the e-mail address index.
+ALTER TABLE user DROP INDEX user_email, DROP INDEX user_uid, DROP COLUMN uid;
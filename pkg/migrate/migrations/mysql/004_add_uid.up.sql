@@ -0,0 +1,7 @@
+-- Add a surrogate user ID, and an index looking up users by e-mail address
+-- (users are looked up by login ID with the primary key). MySQL has no ADD
+-- COLUMN IF NOT EXISTS; if this migration fails, drop the uid column before
+-- re-running it.
+ALTER TABLE user ADD COLUMN uid CHAR(36);
+UPDATE user SET uid = UUID() WHERE uid IS NULL;
+ALTER TABLE user MODIFY uid CHAR(36) NOT NULL, ADD CONSTRAINT user_uid UNIQUE (uid), ADD INDEX user_email (uemail);
@@ -0,0 +1,2 @@
+-- Drop user table (and its users).
+DROP TABLE IF EXISTS "user";
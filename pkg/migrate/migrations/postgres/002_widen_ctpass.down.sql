@@ -0,0 +1,3 @@
+-- Narrow ctpass to legacy sha-256 digests. Fails while any password is hashed
+-- otherwise (e.g. with argon2id or bcrypt).
+ALTER TABLE "user" ALTER COLUMN ctpass TYPE CHAR(66);
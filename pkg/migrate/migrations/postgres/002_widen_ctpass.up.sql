@@ -0,0 +1,2 @@
+-- Widen ctpass for salted argon2id/bcrypt password hashes.
+ALTER TABLE "user" ALTER COLUMN ctpass TYPE VARCHAR(255);
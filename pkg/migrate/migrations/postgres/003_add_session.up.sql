@@ -0,0 +1,18 @@
+-- Add session table for multi-device login sessions and refresh tokens.
+CREATE TABLE IF NOT EXISTS session
+(
+	sessid	CHAR(36) NOT NULL,
+	ctuser	VARCHAR(20) NOT NULL,
+	ctprof	VARCHAR(20) NOT NULL,
+	uemail	VARCHAR(50) NOT NULL,
+	rthash	CHAR(64) NOT NULL,
+	device	VARCHAR(255),
+	created	TIMESTAMP NOT NULL,
+	renewed	TIMESTAMP NOT NULL,
+	expires	TIMESTAMP NOT NULL,
+	PRIMARY KEY (sessid),
+	CONSTRAINT session_user_fk FOREIGN KEY (ctuser, ctprof, uemail)
+		REFERENCES "user" (ctuser, ctprof, uemail) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS session_user ON session (ctuser, ctprof, uemail);
@@ -0,0 +1,4 @@
+-- Drop the surrogate user ID and the e-mail address index.
+DROP INDEX IF EXISTS user_email;
+DROP INDEX IF EXISTS user_uid;
+ALTER TABLE "user" DROP COLUMN IF EXISTS uid;
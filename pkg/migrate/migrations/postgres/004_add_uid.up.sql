@@ -0,0 +1,7 @@
+-- Add a surrogate user ID, and an index looking up users by e-mail address
+-- (users are looked up by login ID with the primary key).
+ALTER TABLE "user" ADD COLUMN IF NOT EXISTS uid CHAR(36);
+UPDATE "user" SET uid = gen_random_uuid()::text WHERE uid IS NULL;
+ALTER TABLE "user" ALTER COLUMN uid SET NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS user_uid ON "user" (uid);
+CREATE INDEX IF NOT EXISTS user_email ON "user" (uemail);
@@ -0,0 +1,2 @@
+-- Narrow ctpass to legacy sha-256 digests. SQLite doesn't enforce column
+-- lengths, so there's nothing to change.
@@ -0,0 +1,2 @@
+-- Widen ctpass for salted argon2id/bcrypt password hashes. SQLite doesn't
+-- enforce column lengths, so there's nothing to change.
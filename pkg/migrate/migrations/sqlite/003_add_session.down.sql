@@ -0,0 +1,2 @@
+-- Drop session table (and its sessions).
+DROP TABLE IF EXISTS session;
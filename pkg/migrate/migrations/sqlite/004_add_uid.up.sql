@@ -0,0 +1,7 @@
+-- Add a surrogate user ID, and an index looking up users by e-mail address
+-- (users are looked up by login ID with the primary key). SQLite can't add a
+-- NOT NULL column without a default; new users are always given an ID.
+ALTER TABLE user ADD COLUMN uid CHAR(36);
+UPDATE user SET uid = lower(hex(randomblob(4)) || '-' || hex(randomblob(2)) || '-4' || substr(hex(randomblob(2)), 2) || '-' || substr('89ab', 1 + abs(random()) % 4, 1) || substr(hex(randomblob(2)), 2) || '-' || hex(randomblob(6))) WHERE uid IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS user_uid ON user (uid);
+CREATE INDEX IF NOT EXISTS user_email ON user (uemail);
@@ -5,7 +5,9 @@ package model
 const (
 	FMT_DATETIME_GO = "20060102150405"
 
-	KEY_CONFIG_FILE = "configFileId"
+	KEY_CONFIG_FILE    = "configFileId"
+	KEY_CONFIG_COMMAND = "configCommandId"
+	KEY_CONFIG_SECRETS = "configSecretsFileId"
 
 	KEY_CLIENT_COMMAND     = "commandId"
 	KEY_CLIENT_TOKEN       = "tokenId"
@@ -26,8 +28,13 @@ const (
 	KEY_AUTH_FUNCTION_DEL  = "userdbDeleteUserId"
 	KEY_AUTH_FUNCTION_UPD  = "userdbUpdateUserId"
 	KEY_AUTH_FUNCTION_VAL  = "userdbValidateUserId"
+	KEY_AUTH_FUNCTION_REF  = "userdbRefreshTokenId"
+	KEY_AUTH_FUNCTION_OUT  = "userdbLogoutUserId"
+	KEY_AUTH_FUNCTION_API  = "userdbAuthApiId"
+	KEY_AUTH_API_MODE      = "userdbAuthApiModeId"
 
 	KEY_USERDB_TEST_MODE = "userdbTestModeId"
+	KEY_USERDB_DIALECT   = "userdbDialectId"
 	KEY_USERDB_HOST_IP   = "userdbHostId"
 	KEY_USERDB_PORT_NUM  = "userdbPortId"
 	KEY_USERDB_DATABASE  = "userdbDatabaseId"
@@ -37,5 +44,57 @@ const (
 	KEY_USERDB_MAX_IDLE  = "userdbMaxIdleConnectionsId"
 	KEY_USERDB_MAX_IDTM  = "userdbMaxIdleTimeId"
 	KEY_USERDB_MAX_LFTM  = "userdbMaxLifeTimeId"
-	KEY_STORAGE_BUCKET   = "storageBucketNameId"
+
+	KEY_USERDB_PING_INTERVAL = "userdbPingIntervalId"
+	KEY_USERDB_QUERY_TIMEOUT = "userdbQueryTimeoutId"
+	KEY_USERDB_UNIQUENESS    = "userdbUniquenessId"
+
+	KEY_USERDB_PWD_ALGORITHM   = "userdbPasswordAlgorithmId"
+	KEY_USERDB_PWD_ARGON_TIME  = "userdbPasswordArgonTimeId"
+	KEY_USERDB_PWD_ARGON_MEM   = "userdbPasswordArgonMemoryId"
+	KEY_USERDB_PWD_ARGON_THRD  = "userdbPasswordArgonThreadsId"
+	KEY_USERDB_PWD_BCRYPT_COST = "userdbPasswordBcryptCostId"
+
+	KEY_USERDB_TOKEN_ALGORITHM = "userdbTokenAlgorithmId"
+	KEY_USERDB_TOKEN_KEYS      = "userdbTokenKeysId"
+	KEY_USERDB_TOKEN_KEY_ID    = "userdbTokenKeyId"
+	KEY_USERDB_TOKEN_LIFETIME  = "userdbTokenLifetimeId"
+	KEY_USERDB_TOKEN_ISSUER    = "userdbTokenIssuerId"
+
+	KEY_USERDB_SESSION_LIFETIME = "userdbSessionLifetimeId"
+
+	KEY_USERDB_VALIDATION_WINDOW = "userdbValidationWindowId"
+
+	KEY_STORAGE_BUCKET        = "storageBucketNameId"
+	KEY_STORAGE_TYPE          = "storageTypeId"
+	KEY_STORAGE_LOCAL_PATH    = "storageLocalPathId"
+	KEY_STORAGE_S3_BUCKET     = "storageS3BucketNameId"
+	KEY_STORAGE_S3_ENDPOINT   = "storageS3EndpointId"
+	KEY_STORAGE_S3_ACCESS_KEY = "storageS3AccessKeyId"
+	KEY_STORAGE_S3_SECRET_KEY = "storageS3SecretKeyId"
+	KEY_STORAGE_S3_USE_SSL    = "storageS3UseSslId"
+	KEY_STORAGE_S3_TIMEOUT    = "storageS3TimeoutId"
+
+	KEY_CONTACTS_STORE_TYPE    = "contactsStoreTypeId"
+	KEY_CONTACTS_COLLECTION    = "contactsCollectionId"
+	KEY_CONTACTS_FUNCTION_LIST = "contactsListContactsId"
+	KEY_CONTACTS_FUNCTION_GET  = "contactsGetContactId"
+	KEY_CONTACTS_FUNCTION_ADD  = "contactsAddContactId"
+	KEY_CONTACTS_FUNCTION_UPD  = "contactsUpdateContactId"
+	KEY_CONTACTS_FUNCTION_DEL  = "contactsDeleteContactId"
+
+	KEY_NOTIFY_MAILER_TYPE   = "notifyMailerTypeId"
+	KEY_NOTIFY_MAIL_FROM     = "notifyMailFromId"
+	KEY_NOTIFY_OUTBOX_PATH   = "notifyOutboxPathId"
+	KEY_NOTIFY_SMTP_HOST     = "notifySmtpHostId"
+	KEY_NOTIFY_SMTP_PORT     = "notifySmtpPortId"
+	KEY_NOTIFY_SMTP_LOGIN    = "notifySmtpLoginId"
+	KEY_NOTIFY_SMTP_PASSWORD = "notifySmtpPasswordId"
+	KEY_NOTIFY_CONFIRM_URL   = "notifyConfirmUrlId"
+
+	KEY_SWEEPER_DRY_RUN  = "sweeperDryRunId"
+	KEY_SWEEPER_INTERVAL = "sweeperIntervalId"
+
+	KEY_MIGRATE_COMMAND = "migrateCommandId"
+	KEY_MIGRATE_TARGET  = "migrateTargetId"
 )
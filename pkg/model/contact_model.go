@@ -0,0 +1,60 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Contact error codes are prefixed "T" (conTact), since "C" is of cloud
+// storage errors (see CloudStorageError).
+var (
+	NoContactIdError   = UserError{"T01", "Contact identifier is empty!", nil}
+	NoContactNameError = UserError{"T02", "Contact name is empty!", nil}
+	BadContactKeyError = UserError{"T03", "Contact key contains a '/'!", nil}
+)
+
+// Contact records related data. A contact list is owned by the user
+// identified by its ctuser, ctprof, and uemail fields.
+type ContactList struct {
+	CtUser   string    `json:"ctuser"`
+	CtProf   string    `json:"ctprof"`
+	UEmail   string    `json:"uemail"`
+	Contacts []Contact `json:"contacts"`
+}
+
+type Contact struct {
+	CtId    string `json:"ctid"`
+	FName   string `json:"fname"`
+	LName   string `json:"lname"`
+	CEmail  string `json:"cemail"`
+	CPhone  string `json:"cphone"`
+	CAddr   string `json:"caddr"`
+	CNotes  string `json:"cnotes"`
+	CtPpic  string `json:"ctppic"`
+	Created string `json:"created"`
+	Updated string `json:"updated"`
+}
+
+// Owner returns a user instance keyed to the owner of the contact list.
+func (cl *ContactList) Owner() *User {
+	return &User{
+		CtUser: cl.CtUser,
+		CtProf: cl.CtProf,
+		UEmail: cl.UEmail,
+	}
+}
+
+func (c *Contact) HasName() bool {
+	return len(strings.TrimSpace(c.FName)) > 0 || len(strings.TrimSpace(c.LName)) > 0
+}
+
+func (c *Contact) String() string {
+	return fmt.Sprintf("%v: %v %v <%v>", c.CtId, c.FName, c.LName, c.CEmail)
+}
+
+func (c *Contact) Clone() *Contact {
+	contact := new(Contact)
+	*contact = *c
+
+	return contact
+}
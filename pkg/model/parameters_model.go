@@ -24,6 +24,16 @@ const (
 	USER_MUST_PROVIDE = "userMustProvide"
 )
 
+// Parameter value types (see Parameter.Type)
+const (
+	TYPE_STRING   = "string"
+	TYPE_INT      = "int"
+	TYPE_BOOL     = "bool"
+	TYPE_DURATION = "duration"
+	TYPE_LIST     = "list"
+	TYPE_URL      = "url"
+)
+
 // ApplicationConfig represents a loaded and parsed application configuration.
 type ApplicationConfig interface {
 	ValueOf(string) string
@@ -38,19 +48,40 @@ type ApplicationConfig interface {
 // 'ArgSeparator' must be one of: "SPACE", "EQUALS", "COMMA", "COLON",
 // "SEMI-COLON" (default is SPACE)
 type ParserConfig struct {
-	ArgSwitch    string      `json: "argSwitch"`
-	ArgSeparator string      `json: "argSeparator"`
-	Parameters   []Parameter `json: "parameters"`
+	ArgSwitch    string      `json:"argSwitch"`
+	ArgSeparator string      `json:"argSeparator"`
+	Parameters   []Parameter `json:"parameters"`
 }
 
-// Parameter represents applicaiton options.
+// Parameter represents applicaiton options. Commands lists the commands
+// taking the parameter (all commands if none), Section titles the section of
+// the properties template starting with the parameter, and Comment comments
+// its property in the template (its description if none). Secret parameters'
+// values are masked when shown. The optional fields describe the parameter's
+// value: its type (one of the TYPE_* constants, default is string), whether
+// it is required, either always or only if the comma separated
+// 'optionId=value' (or 'optionId!=value') conditions of RequiredIf hold, the
+// minimum and maximum of numeric values, the values allowed (of each element
+// of a list), and the unit of durations given as a number ("s", "m", "h", or
+// "d", default is seconds).
 type Parameter struct {
-	OptionId       string `json: "optionId"`
-	CliArgument    string `json: "cliArgument"`
-	EnvironmentVar string `json: "environmentVar"`
-	PropertyName   string `json: "propertyName"`
-	DefaultVal     string `json: "defaultVal"`
-	Description    string `json: "description"`
+	OptionId       string   `json:"optionId"`
+	CliArgument    string   `json:"cliArgument"`
+	EnvironmentVar string   `json:"environmentVar"`
+	PropertyName   string   `json:"propertyName"`
+	DefaultVal     string   `json:"defaultVal"`
+	Description    string   `json:"description"`
+	Commands       []string `json:"commands,omitempty"`
+	Section        string   `json:"section,omitempty"`
+	Comment        []string `json:"comment,omitempty"`
+	Secret         bool     `json:"secret,omitempty"`
+	Type           string   `json:"type,omitempty"`
+	Required       bool     `json:"required,omitempty"`
+	RequiredIf     string   `json:"requiredIf,omitempty"`
+	Min            *int64   `json:"min,omitempty"`
+	Max            *int64   `json:"max,omitempty"`
+	Enum           []string `json:"enum,omitempty"`
+	Unit           string   `json:"unit,omitempty"`
 }
 
 var ParserConfigPath string
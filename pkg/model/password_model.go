@@ -0,0 +1,182 @@
+package model
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// Stored password tags identifying the hashing algorithm. Legacy unsalted
+	// SHA-256 digests are tagged with HPWD_TAG.
+	APWD_TAG = "A:"
+	BPWD_TAG = "B:"
+
+	PWD_ALGORITHM_ARGON2ID = "argon2id"
+	PWD_ALGORITHM_BCRYPT   = "bcrypt"
+
+	ARGON2ID_SALT_LEN = 16
+	ARGON2ID_KEY_LEN  = 32
+	ARGON2ID_FORMAT   = "$argon2id$v=%d$m=%d,t=%d,p=%d$%v$%v"
+
+	// Limits of the parameters of argon2id hashes verified, so that a crafted
+	// hash can't exhaust the memory, or the time, of a login. ARGON2ID_MAX_MEMORY
+	// is given in KiB.
+	ARGON2ID_MAX_MEMORY = 1024 * 1024
+	ARGON2ID_MAX_TIME   = 64
+)
+
+var (
+	DefaultPasswordOptions = PasswordOptions{
+		Algorithm:    PWD_ALGORITHM_ARGON2ID,
+		ArgonTime:    1,
+		ArgonMemory:  64 * 1024,
+		ArgonThreads: 4,
+		BcryptCost:   bcrypt.DefaultCost,
+	}
+
+	errUnknownPwdTag   = errors.New("unknown password hash tag")
+	errMalformedPwd    = errors.New("malformed password hash")
+	errUnknownPwdAlgor = errors.New("unknown password hashing algorithm")
+)
+
+// PasswordOptions holds the tunable parameters of the password hashing
+// algorithms. ArgonMemory is given in KiB.
+type PasswordOptions struct {
+	Algorithm    string
+	ArgonTime    uint32
+	ArgonMemory  uint32
+	ArgonThreads uint8
+	BcryptCost   int
+}
+
+// argon2idParams holds the parameters encoded in an argon2id hash.
+type argon2idParams struct {
+	version uint32
+	memory  uint32
+	time    uint32
+	threads uint8
+	salt    []byte
+	key     []byte
+}
+
+// HashPassword returns the salted hash of the given text password using the
+// algorithm and parameters of the given options. The returned value is
+// tagged with the algorithm used (see APWD_TAG, BPWD_TAG).
+func HashPassword(text string, opts PasswordOptions) (string, error) {
+	switch opts.Algorithm {
+	case PWD_ALGORITHM_ARGON2ID:
+		salt := make([]byte, ARGON2ID_SALT_LEN)
+		if _, err := rand.Read(salt); err != nil {
+			return "", err
+		}
+		key := argon2.IDKey([]byte(text), salt, opts.ArgonTime, opts.ArgonMemory, opts.ArgonThreads, ARGON2ID_KEY_LEN)
+
+		return fmt.Sprintf("%v"+ARGON2ID_FORMAT, APWD_TAG, argon2.Version, opts.ArgonMemory, opts.ArgonTime, opts.ArgonThreads,
+			base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+	case PWD_ALGORITHM_BCRYPT:
+		hash, err := bcrypt.GenerateFromPassword([]byte(text), opts.BcryptCost)
+		if err != nil {
+			return "", err
+		}
+
+		return fmt.Sprintf("%v%v", BPWD_TAG, string(hash)), nil
+	default:
+		return "", errUnknownPwdAlgor
+	}
+}
+
+// VerifyPassword returns true if the given text password matches the given
+// tagged, stored password hash. Legacy SHA-256 digests (HPWD_TAG) are
+// verified as well as argon2id and bcrypt hashes.
+func VerifyPassword(stored, text string) (bool, error) {
+	switch {
+	case strings.HasPrefix(stored, APWD_TAG):
+		params, err := decodeArgon2id(stored[len(APWD_TAG):])
+		if err != nil {
+			return false, err
+		}
+		key := argon2.IDKey([]byte(text), params.salt, params.time, params.memory, params.threads, uint32(len(params.key)))
+
+		return subtle.ConstantTimeCompare(key, params.key) == 1, nil
+	case strings.HasPrefix(stored, BPWD_TAG):
+		err := bcrypt.CompareHashAndPassword([]byte(stored[len(BPWD_TAG):]), []byte(text))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, nil
+		}
+
+		return err == nil, err
+	case strings.HasPrefix(stored, HPWD_TAG):
+		digest := TextDigestOf(text)
+
+		return subtle.ConstantTimeCompare([]byte(digest), []byte(stored[len(HPWD_TAG):])) == 1, nil
+	default:
+		return false, errUnknownPwdTag
+	}
+}
+
+// PasswordNeedsRehash returns true if the given stored password hash wasn't
+// produced with the algorithm and parameters of the given options, e.g. for
+// legacy SHA-256 digests or after hashing parameters are tuned.
+func PasswordNeedsRehash(stored string, opts PasswordOptions) bool {
+	switch {
+	case strings.HasPrefix(stored, APWD_TAG):
+		if opts.Algorithm != PWD_ALGORITHM_ARGON2ID {
+			return true
+		}
+		params, err := decodeArgon2id(stored[len(APWD_TAG):])
+
+		return err != nil || params.version != argon2.Version || params.time != opts.ArgonTime ||
+			params.memory != opts.ArgonMemory || params.threads != opts.ArgonThreads
+	case strings.HasPrefix(stored, BPWD_TAG):
+		if opts.Algorithm != PWD_ALGORITHM_BCRYPT {
+			return true
+		}
+		cost, err := bcrypt.Cost([]byte(stored[len(BPWD_TAG):]))
+
+		return err != nil || cost != opts.BcryptCost
+	default:
+		return true
+	}
+}
+
+// IsPasswordHash returns true if the given password value is tagged as a
+// hash by any of the supported algorithms. Passwords received from clients
+// must be hashed regardless: a client could otherwise store a crafted hash.
+func IsPasswordHash(pwd string) bool {
+	return strings.HasPrefix(pwd, APWD_TAG) || strings.HasPrefix(pwd, BPWD_TAG) || strings.HasPrefix(pwd, HPWD_TAG)
+}
+
+func decodeArgon2id(encoded string) (*argon2idParams, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != PWD_ALGORITHM_ARGON2ID {
+		return nil, errMalformedPwd
+	}
+
+	params := new(argon2idParams)
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &params.version); err != nil {
+		return nil, errMalformedPwd
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.time, &params.threads); err != nil {
+		return nil, errMalformedPwd
+	}
+	if params.time < 1 || params.time > ARGON2ID_MAX_TIME || params.threads < 1 || params.memory > ARGON2ID_MAX_MEMORY {
+		return nil, errMalformedPwd
+	}
+
+	var err error
+	if params.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return nil, errMalformedPwd
+	}
+	if params.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil || len(params.key) == 0 {
+		return nil, errMalformedPwd
+	}
+
+	return params, nil
+}
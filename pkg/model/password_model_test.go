@@ -0,0 +1,100 @@
+package model
+
+import (
+	"strings"
+	"testing"
+)
+
+var testPasswordOptions = PasswordOptions{
+	Algorithm:    PWD_ALGORITHM_ARGON2ID,
+	ArgonTime:    1,
+	ArgonMemory:  1024,
+	ArgonThreads: 1,
+	BcryptCost:   4,
+}
+
+func TestHashAndVerifyPassword(t *testing.T) {
+	for _, algorithm := range []string{PWD_ALGORITHM_ARGON2ID, PWD_ALGORITHM_BCRYPT} {
+		t.Run(algorithm, func(t *testing.T) {
+			opts := testPasswordOptions
+			opts.Algorithm = algorithm
+
+			hash, err := HashPassword("f4kePas$", opts)
+			if err != nil {
+				t.Fatalf("Error hashing password: %v", err)
+			}
+			if !IsPasswordHash(hash) {
+				t.Errorf("Hash %v isn't tagged.", hash)
+			}
+
+			again, _ := HashPassword("f4kePas$", opts)
+			if again == hash {
+				t.Error("Hashes of the same password aren't salted.")
+			}
+
+			if ok, err := VerifyPassword(hash, "f4kePas$"); !ok || err != nil {
+				t.Errorf("Password not verified against its hash: %v", err)
+			}
+			if ok, _ := VerifyPassword(hash, "wr0ngPas$"); ok {
+				t.Error("Wrong password verified against hash.")
+			}
+			if PasswordNeedsRehash(hash, opts) {
+				t.Error("Hash with current options reported as needing re-hash.")
+			}
+		})
+	}
+}
+
+func TestLegacyPasswordNeedsRehash(t *testing.T) {
+	user := User{CtPass: "f4kePas$"}
+	legacy := user.PwdHash(true)
+
+	if ok, err := VerifyPassword(legacy, "f4kePas$"); !ok || err != nil {
+		t.Errorf("Password not verified against legacy digest: %v", err)
+	}
+	if !PasswordNeedsRehash(legacy, testPasswordOptions) {
+		t.Error("Legacy digest not reported as needing re-hash.")
+	}
+
+	tuned := testPasswordOptions
+	tuned.ArgonTime = 2
+	hash, _ := HashPassword("f4kePas$", testPasswordOptions)
+	if !PasswordNeedsRehash(hash, tuned) {
+		t.Error("Hash with prior options not reported as needing re-hash.")
+	}
+}
+
+func TestHashPwd(t *testing.T) {
+	user := User{CtPass: "f4kePas$"}
+	if err := user.HashPwd(testPasswordOptions); err != nil {
+		t.Fatalf("Error hashing user password: %v", err)
+	}
+	if !strings.HasPrefix(user.CtPass, APWD_TAG) || user.HasTextPwd() {
+		t.Errorf("User password %v not hashed.", user.CtPass)
+	}
+
+	hashed := user.CtPass
+	if err := user.HashPwd(testPasswordOptions); err != nil || user.CtPass != hashed {
+		t.Error("Hashed user password re-hashed.")
+	}
+
+	if !user.Equals(&User{CtPass: "f4kePas$"}) {
+		t.Error("User with hashed password doesn't equal user with text password.")
+	}
+}
+
+func TestVerifyMalformedPassword(t *testing.T) {
+	for _, stored := range []string{
+		"f4kePas$",
+		"A:$argon2id$v=19$bad",
+		"B:$2a$nope",
+		"A:$argon2id$v=19$m=8,t=0,p=1$c2FsdHNhbHQ$a2V5a2V5",
+		"A:$argon2id$v=19$m=8,t=1,p=0$c2FsdHNhbHQ$a2V5a2V5",
+		"A:$argon2id$v=19$m=8,t=65,p=1$c2FsdHNhbHQ$a2V5a2V5",
+		"A:$argon2id$v=19$m=4294967295,t=1,p=1$c2FsdHNhbHQ$a2V5a2V5",
+	} {
+		if ok, err := VerifyPassword(stored, "f4kePas$"); ok || err == nil {
+			t.Errorf("Malformed hash %v verified without error.", stored)
+		}
+	}
+}
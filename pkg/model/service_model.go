@@ -23,12 +23,26 @@ var (
 	DbOpenError         = ServiceError{"D08", "Error opening user info.", nil}
 	DbPKeyError         = ServiceError{"D09", "Primary key already exists.", nil}
 	DbPKeyMissingError  = ServiceError{"D10", "Primary key not found.", nil}
+	DbTimeoutError      = ServiceError{"D11", "User info request timed out.", nil}
+	DbMigrationError    = ServiceError{"D12", "Error migrating user info schema.", nil}
+	DbTransactionError  = ServiceError{"D13", "Error in user info transaction.", nil}
+	DbConflictError     = ServiceError{"D14", "User info transaction conflicted with another.", nil}
 	InvalidKeyError     = ServiceError{"I01", "Incomplete user info.", nil}
 	InvalidMsgError     = ServiceError{"I02", "Invalid request message.", nil}
 	InternalReadError   = ServiceError{"I03", "Error reading request message.", nil}
 	InvalidLoginError   = ServiceError{"I04", "Invalid login credentials provided.", nil}
 	InvalidTokenError   = ServiceError{"I05", "Invalid user access token provided.", nil}
 	ExpiredTokenError   = ServiceError{"I06", "Expired user access token provided.", nil}
+	InvalidContactError = ServiceError{"I07", "Incomplete contact info.", nil}
+	InvalidRefreshError = ServiceError{"I08", "Invalid session refresh token provided.", nil}
+	ExpiredSessionError = ServiceError{"I09", "Expired user session.", nil}
+	InvalidMethodError  = ServiceError{"I10", "Request method not allowed.", nil}
+	UnknownPathError    = ServiceError{"I11", "Unknown API resource.", nil}
+	AmbiguousLoginError = ServiceError{"I12", "Login matches several accounts.", nil}
+	ContactsStoreError  = ServiceError{"N01", "Error accessing contacts store.", nil}
+	ContactMissingError = ServiceError{"N02", "Contact not found.", nil}
+	ContactExistsError  = ServiceError{"N03", "Contact already exists.", nil}
+	NotifyError         = ServiceError{"M01", "Error sending user notification.", nil}
 	ImageDecodingError  = ServiceError{"P01", "Error decoding image.", nil}
 	SystemError         = ServiceError{"S00", "An internal error has occurred.", nil}
 	DatetimeError       = ServiceError{"S01", "A datetime error has occurred.", nil}
@@ -36,6 +50,9 @@ var (
 	UserValidationError = ServiceError{"U01", "User validation period expired.", nil}
 
 	HttpErrorStatus map[string]int
+
+	// All service errors, in declaration order
+	ServiceErrors []ServiceError
 )
 
 // ServiceError represents a base error result
@@ -74,15 +91,75 @@ func init() {
 	HttpErrorStatus[DbOpenError.Code] = 500
 	HttpErrorStatus[DbPKeyError.Code] = 409
 	HttpErrorStatus[DbPKeyMissingError.Code] = 404
+	HttpErrorStatus[DbTimeoutError.Code] = 504
+	HttpErrorStatus[DbMigrationError.Code] = 500
+	HttpErrorStatus[DbTransactionError.Code] = 500
+	HttpErrorStatus[DbConflictError.Code] = 503
 	HttpErrorStatus[InvalidKeyError.Code] = 400
 	HttpErrorStatus[InvalidMsgError.Code] = 400
 	HttpErrorStatus[InternalReadError.Code] = 500
 	HttpErrorStatus[InvalidLoginError.Code] = 403
 	HttpErrorStatus[InvalidTokenError.Code] = 400
 	HttpErrorStatus[ExpiredTokenError.Code] = 403
+	HttpErrorStatus[InvalidContactError.Code] = 400
+	HttpErrorStatus[InvalidRefreshError.Code] = 400
+	HttpErrorStatus[ExpiredSessionError.Code] = 403
+	HttpErrorStatus[InvalidMethodError.Code] = 405
+	HttpErrorStatus[UnknownPathError.Code] = 404
+	HttpErrorStatus[AmbiguousLoginError.Code] = 409
+	HttpErrorStatus[ContactsStoreError.Code] = 502
+	HttpErrorStatus[ContactMissingError.Code] = 404
+	HttpErrorStatus[ContactExistsError.Code] = 409
+	HttpErrorStatus[NotifyError.Code] = 502
 	HttpErrorStatus[ImageDecodingError.Code] = 500
 	HttpErrorStatus[SystemError.Code] = 500
 	HttpErrorStatus[DatetimeError.Code] = 500
 	HttpErrorStatus[IOError.Code] = 500
 	HttpErrorStatus[UserValidationError.Code] = 403
+
+	ServiceErrors = []ServiceError{
+		ClientRequestError,
+		ClientReadError,
+		ClientInputError,
+		ClientOutputError,
+		ClientProtocolError,
+		ClientImageError,
+		ClientError,
+		CloudStorageError,
+		DbQueryError,
+		DbScanError,
+		DbResultsError,
+		DbInsertError,
+		DbPrepareError,
+		DbExecuteError,
+		DbClientError,
+		DbOpenError,
+		DbPKeyError,
+		DbPKeyMissingError,
+		DbTimeoutError,
+		DbMigrationError,
+		DbTransactionError,
+		DbConflictError,
+		InvalidKeyError,
+		InvalidMsgError,
+		InternalReadError,
+		InvalidLoginError,
+		InvalidTokenError,
+		ExpiredTokenError,
+		InvalidContactError,
+		InvalidRefreshError,
+		ExpiredSessionError,
+		InvalidMethodError,
+		UnknownPathError,
+		AmbiguousLoginError,
+		ContactsStoreError,
+		ContactMissingError,
+		ContactExistsError,
+		NotifyError,
+		ImageDecodingError,
+		SystemError,
+		DatetimeError,
+		IOError,
+		UserValidationError,
+	}
 }
@@ -0,0 +1,64 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// Separator of the session identifier and secret of a refresh token.
+	RTOKEN_SEP = "."
+)
+
+var (
+	NoSessionIdError = UserError{"U04", "Session identifier is empty!", nil}
+)
+
+// Session is a user's login session on a single device. A session is created
+// on login and holds the (hashed) secret of the long-lived refresh token
+// exchanged for new access tokens until the session expires or is revoked.
+type Session struct {
+	SessId  string    `json:"sessid"`
+	CtUser  string    `json:"ctuser"`
+	CtProf  string    `json:"ctprof"`
+	UEmail  string    `json:"uemail"`
+	RtHash  string    `json:"-"`
+	Device  string    `json:"device"`
+	Created time.Time `json:"created"`
+	Renewed time.Time `json:"renewed"`
+	Expires time.Time `json:"expires"`
+}
+
+// Owner returns the user key of the session's owner.
+func (s *Session) Owner() *User {
+	return &User{CtUser: s.CtUser, CtProf: s.CtProf, UEmail: s.UEmail}
+}
+
+// OwnedBy returns true if the session belongs to the referenced user.
+func (s *Session) OwnedBy(user *User) bool {
+	return s.CtUser == user.CtUser && s.CtProf == user.CtProf && s.UEmail == user.UEmail
+}
+
+// IsExpired returns true if the session expired before the given time.
+func (s *Session) IsExpired(now time.Time) bool {
+	return !now.Before(s.Expires)
+}
+
+func (s *Session) String() string {
+	return fmt.Sprintf("%v (%v/%v/%v, expires %v)", s.SessId, s.CtUser, s.CtProf, s.UEmail, s.Expires.Format(time.RFC3339))
+}
+
+// RefreshToken returns the refresh token of the session with the given
+// secret.
+func RefreshToken(sessId, secret string) string {
+	return sessId + RTOKEN_SEP + secret
+}
+
+// ParseRefreshToken returns the session identifier and secret of the given
+// refresh token.
+func ParseRefreshToken(token string) (string, string, bool) {
+	sessId, secret, found := strings.Cut(token, RTOKEN_SEP)
+
+	return sessId, secret, found && len(sessId) > 0 && len(secret) > 0
+}
@@ -7,33 +7,40 @@ import (
 )
 
 const (
-	UserExistsError = "Error 1062"
-
 	HPWD_TAG = "H:"
 	OBJK_TAG = "K:"
 )
 
 var (
-	NoUserIdError       = UserError{"U01", "User identifier is empty!", nil}
-	NoProfileIdError    = UserError{"U02", "User profile name is empty!", nil}
-	NoEmailAddressError = UserError{"U03", "User e-mail address is empty!", nil}
+	NoUserIdError        = UserError{"U01", "User identifier is empty!", nil}
+	NoProfileIdError     = UserError{"U02", "User profile name is empty!", nil}
+	NoEmailAddressError  = UserError{"U03", "User e-mail address is empty!", nil}
+	BadEmailAddressError = UserError{"U05", "User e-mail address is invalid!", nil}
 )
 
 // User information related data
 type UserList struct {
-	Users []User `json: "users"`
+	Users []User `json:"users"`
 }
 
 type User struct {
-	CtUser string `json: "ctuser"`
-	CtPass string `json: "ctpass"`
-	CtProf string `json: "ctprof"`
-	CtPpic string `json: "ctppic"`
-	CtImgt string `json: "ctimgt"`
-	UEmail string `json: "uemail"`
-	AToken string `json: "atoken"`
-	LLogin string `json: "llogin"`
-	UValid string `json: "uvalid"`
+	UserId string `json:"uid"`
+	CtUser string `json:"ctuser"`
+	CtPass string `json:"ctpass"`
+	CtProf string `json:"ctprof"`
+	CtPpic string `json:"ctppic"`
+	CtImgt string `json:"ctimgt"`
+	UEmail string `json:"uemail"`
+	AToken string `json:"atoken"`
+	LLogin string `json:"llogin"`
+	UValid string `json:"uvalid"`
+}
+
+// AmbiguousLogin is the cause of an AmbiguousLoginError: the accounts whose
+// credentials match a login by login identifier or e-mail address alone.
+type AmbiguousLogin struct {
+	Login string
+	Users []User
 }
 
 type UserError struct {
@@ -42,6 +49,8 @@ type UserError struct {
 	Cause   error
 }
 
+// PwdHash returns the legacy, unsalted SHA-256 digest of the user's text
+// password (see HashPwd).
 func (u *User) PwdHash(tagged bool) string {
 	hpass := u.CtPass
 
@@ -60,8 +69,24 @@ func (u *User) PwdHash(tagged bool) string {
 	return hpass
 }
 
+// HashPwd replaces the user's text password, if any, with its salted hash
+// using the given hashing options. Passwords already tagged as hashes are
+// kept, for seed and migration data only: passwords received from clients are
+// hashed with HashPassword regardless.
+func (u *User) HashPwd(opts PasswordOptions) error {
+	if u.HasTextPwd() {
+		hpass, err := HashPassword(u.CtPass, opts)
+		if err != nil {
+			return err
+		}
+		u.CtPass = hpass
+	}
+
+	return nil
+}
+
 func (u *User) HasTextPwd() bool {
-	return !strings.HasPrefix(u.CtPass, HPWD_TAG)
+	return !IsPasswordHash(u.CtPass)
 }
 
 func (u *User) HasProfilePicKey() bool {
@@ -78,16 +103,7 @@ func (u *User) Equals(user *User) bool {
 		(u.UEmail == user.UEmail) &&
 		(u.CtPpic == user.CtPpic)
 
-	upass := u.CtPass
-	if u.HasTextPwd() {
-		upass = fmt.Sprintf("%v%v", HPWD_TAG, TextDigestOf(u.CtPass))
-	}
-	userp := user.CtPass
-	if user.HasTextPwd() {
-		userp = fmt.Sprintf("%v%v", HPWD_TAG, TextDigestOf(user.CtPass))
-	}
-	fmt.Printf("HPass: %v, %v\n", upass, userp)
-	eq = eq && (upass == userp)
+	eq = eq && pwdEquals(u.CtPass, user.CtPass)
 
 	return eq
 }
@@ -95,6 +111,7 @@ func (u *User) Equals(user *User) bool {
 func (u *User) Clone() *User {
 	user := new(User)
 
+	user.UserId = u.UserId
 	user.CtUser = u.CtUser
 	user.CtPass = u.CtPass
 	user.CtProf = u.CtProf
@@ -108,6 +125,10 @@ func (u *User) Clone() *User {
 	return user
 }
 
+func (al *AmbiguousLogin) Error() string {
+	return fmt.Sprintf("%d accounts match login '%v'", len(al.Users), al.Login)
+}
+
 func (err UserError) Error() string {
 	if err.Cause == nil {
 		return fmt.Sprintf("%v: %v", err.Code, err.Message)
@@ -120,6 +141,23 @@ func (err UserError) WithCause(src error) UserError {
 	return UserError{err.Code, err.Message, src}
 }
 
+// pwdEquals returns true if the given passwords are equal or if either is
+// the hash of the other.
+func pwdEquals(pwd1, pwd2 string) bool {
+	switch {
+	case IsPasswordHash(pwd1) && IsPasswordHash(pwd2):
+		return pwd1 == pwd2
+	case IsPasswordHash(pwd1):
+		ok, _ := VerifyPassword(pwd1, pwd2)
+		return ok
+	case IsPasswordHash(pwd2):
+		ok, _ := VerifyPassword(pwd2, pwd1)
+		return ok
+	default:
+		return pwd1 == pwd2
+	}
+}
+
 func TextDigestOf(data string) string {
 	return fmt.Sprintf("%x", DigestOf(data))
 }
@@ -0,0 +1,225 @@
+// Package notify provides delivery of user notification e-mails, e.g. of
+// registration confirmation links.
+package notify
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltmpl "html/template"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"net/textproto"
+	"net/url"
+	"strings"
+	texttmpl "text/template"
+	"time"
+
+	"Cloudtacts/pkg/auth"
+	"Cloudtacts/pkg/config"
+	"Cloudtacts/pkg/model"
+	"Cloudtacts/pkg/util"
+)
+
+const (
+	MAILER_TYPE_SMTP   = "smtp"
+	MAILER_TYPE_OUTBOX = "outbox"
+
+	CONFIRM_SUBJECT = "Confirm your Cloudtacts registration"
+)
+
+var (
+	//go:embed templates
+	templateFS embed.FS
+
+	confirmText = texttmpl.Must(texttmpl.ParseFS(templateFS, "templates/confirm.txt"))
+	confirmHtml = htmltmpl.Must(htmltmpl.ParseFS(templateFS, "templates/confirm.html"))
+)
+
+type Mailer interface {
+	// Sends the referenced message.
+	Send(*Message) model.ServiceError
+
+	// Returns the location the mailer delivers to (e.g. server or directory).
+	MailerUrl() string
+
+	// Closes the mailer.
+	Close()
+}
+
+// Message is an e-mail message with plain text and, optionally, HTML
+// alternative bodies.
+type Message struct {
+	From    string
+	To      []string
+	Subject string
+	Text    string
+	Html    string
+}
+
+// confirmData is the data of the confirmation message templates.
+type confirmData struct {
+	CtUser  string
+	CtProf  string
+	Link    string
+	Minutes int
+}
+
+// GetMailer returns the mailer implementation selected by the configured
+// mailer type.
+func GetMailer(cfg *config.Config) (Mailer, model.ServiceError) {
+	mailerType := strings.ToLower(cfg.ValueOfWithDefault(model.KEY_NOTIFY_MAILER_TYPE, MAILER_TYPE_OUTBOX))
+
+	var mailer Mailer
+	serr := model.NoError
+	switch mailerType {
+	case MAILER_TYPE_SMTP:
+		mailer, serr = newSmtpMailer(cfg)
+	case MAILER_TYPE_OUTBOX:
+		mailer, serr = newOutboxMailer(cfg)
+	default:
+		serr = model.NotifyError.WithCause(fmt.Errorf("unknown mailer type '%v'", mailerType))
+	}
+
+	if serr.IsError() {
+		util.LogIt("Cloudtacts", fmt.Sprintf("Failed to create mailer: %v", serr))
+		return nil, serr
+	}
+	traceIt(cfg, fmt.Sprintf("Mailer using %v.", mailer.MailerUrl()))
+
+	return mailer, model.NoError
+}
+
+// SendConfirmation sends the referenced new user a registration confirmation
+// e-mail with a link to confirm the registration with the given token.
+func SendConfirmation(cfg *config.Config, user *model.User, token string) model.ServiceError {
+	msg, serr := ConfirmationMessage(cfg, user, token)
+	if serr.IsError() {
+		return serr
+	}
+
+	mailer, serr := GetMailer(cfg)
+	if serr.IsError() {
+		return serr
+	}
+	defer mailer.Close()
+
+	if serr = mailer.Send(msg); serr.IsError() {
+		util.LogIt("Cloudtacts", fmt.Sprintf("Failed to send confirmation to %v/%v: %v", user.CtUser, user.CtProf, serr))
+	}
+
+	return serr
+}
+
+// ConfirmationMessage returns the registration confirmation message of the
+// referenced new user with a link to confirm the registration with the given
+// token.
+func ConfirmationMessage(cfg *config.Config, user *model.User, token string) (*Message, model.ServiceError) {
+	link, serr := ConfirmationLink(cfg, user, token)
+	if serr.IsError() {
+		return nil, serr
+	}
+
+	data := confirmData{user.CtUser, user.CtProf, link, int(auth.ValidationWindow(cfg).Minutes())}
+
+	var text, html bytes.Buffer
+	if err := confirmText.Execute(&text, data); err != nil {
+		return nil, model.NotifyError.WithCause(err)
+	}
+	if err := confirmHtml.Execute(&html, data); err != nil {
+		return nil, model.NotifyError.WithCause(err)
+	}
+
+	msg := &Message{
+		From:    cfg.ValueOf(model.KEY_NOTIFY_MAIL_FROM),
+		To:      []string{user.UEmail},
+		Subject: CONFIRM_SUBJECT,
+		Text:    text.String(),
+		Html:    html.String(),
+	}
+
+	return msg, model.NoError
+}
+
+// ConfirmationLink returns the URL of the validate user function target with
+// the referenced user's key and the given confirmation token as query
+// parameters.
+func ConfirmationLink(cfg *config.Config, user *model.User, token string) (string, model.ServiceError) {
+	link, err := url.Parse(cfg.ValueOf(model.KEY_NOTIFY_CONFIRM_URL))
+	if err != nil {
+		return "", model.NotifyError.WithCause(err)
+	}
+
+	query := link.Query()
+	query.Set("ctuser", user.CtUser)
+	query.Set("ctprof", user.CtProf)
+	query.Set("uemail", user.UEmail)
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+
+	return link.String(), model.NoError
+}
+
+// Bytes returns the message formatted for delivery (RFC 5322) as a
+// multipart/alternative message if it has an HTML body. Its From and To
+// headers are formatted from their parsed addresses, so an invalid address
+// can't inject headers.
+func (m *Message) Bytes() ([]byte, error) {
+	var buff bytes.Buffer
+
+	from, err := mail.ParseAddress(m.From)
+	if err != nil {
+		return nil, err
+	}
+	to := make([]string, len(m.To))
+	for i, rcpt := range m.To {
+		addr, err := mail.ParseAddress(rcpt)
+		if err != nil {
+			return nil, err
+		}
+		to[i] = addr.String()
+	}
+
+	fmt.Fprintf(&buff, "From: %v\r\n", from)
+	fmt.Fprintf(&buff, "To: %v\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&buff, "Subject: %v\r\n", mime.QEncoding.Encode("utf-8", m.Subject))
+	fmt.Fprintf(&buff, "Date: %v\r\n", time.Now().UTC().Format(time.RFC1123Z))
+	fmt.Fprint(&buff, "MIME-Version: 1.0\r\n")
+
+	if len(m.Html) == 0 {
+		fmt.Fprint(&buff, "Content-Type: text/plain; charset=utf-8\r\n\r\n")
+		buff.WriteString(toCRLF(m.Text))
+		return buff.Bytes(), nil
+	}
+
+	var body bytes.Buffer
+	mpw := multipart.NewWriter(&body)
+	fmt.Fprintf(&buff, "Content-Type: multipart/alternative; boundary=%v\r\n\r\n", mpw.Boundary())
+
+	for _, part := range [][]string{{"text/plain", m.Text}, {"text/html", m.Html}} {
+		pw, err := mpw.CreatePart(textproto.MIMEHeader{"Content-Type": {part[0] + "; charset=utf-8"}})
+		if err != nil {
+			return nil, err
+		}
+		if _, err = pw.Write([]byte(toCRLF(part[1]))); err != nil {
+			return nil, err
+		}
+	}
+	if err := mpw.Close(); err != nil {
+		return nil, err
+	}
+	buff.Write(body.Bytes())
+
+	return buff.Bytes(), nil
+}
+
+func toCRLF(text string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(text, "\r\n", "\n"), "\n", "\r\n")
+}
+
+func traceIt(cfg *config.Config, message string) {
+	if testMode, _ := cfg.Bool(model.KEY_USERDB_TEST_MODE); testMode {
+		util.LogIt("Cloudtacts", message)
+	}
+}
@@ -0,0 +1,172 @@
+package notify
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+
+	"Cloudtacts/pkg/config"
+	"Cloudtacts/pkg/model"
+	"Cloudtacts/pkg/util"
+)
+
+var cfg *config.Config
+
+var testUser = &model.User{CtUser: "pendracon1", CtProf: "Pendracon & Co", UEmail: "pendracon1@example.com"}
+
+func TestConfirmationMessage(t *testing.T) {
+	msg, serr := ConfirmationMessage(cfg, testUser, "token1")
+	if serr.IsError() {
+		t.Fatalf("Error creating confirmation message: %v", serr)
+	}
+
+	link, _ := ConfirmationLink(cfg, testUser, "token1")
+	ulink, err := url.Parse(link)
+	if err != nil {
+		t.Fatalf("Error parsing confirmation link: %v", err)
+	}
+	query := ulink.Query()
+	if query.Get("ctuser") != testUser.CtUser || query.Get("ctprof") != testUser.CtProf ||
+		query.Get("uemail") != testUser.UEmail || query.Get("token") != "token1" {
+		t.Errorf("Confirmation link doesn't reference user and token: %v", link)
+	}
+	if !strings.HasSuffix(ulink.Path, "/ValidateUser") {
+		t.Errorf("Confirmation link doesn't reference validate user target: %v", link)
+	}
+
+	if len(msg.To) != 1 || msg.To[0] != testUser.UEmail {
+		t.Errorf("Unexpected recipients: %v", msg.To)
+	}
+	if !strings.Contains(msg.Text, link) {
+		t.Errorf("Text body missing confirmation link:\n%v", msg.Text)
+	}
+	if !strings.Contains(msg.Html, "&amp;token=token1") || strings.Contains(msg.Html, "Pendracon & Co") {
+		t.Errorf("HTML body missing escaped confirmation link:\n%v", msg.Html)
+	}
+}
+
+func TestMessageHeaders(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		from string
+		to   string
+		ok   bool
+	}{
+		{"addresses", "Cloudtacts <noreply@example.com>", "pendracon1@example.com", true},
+		{"injected recipient header", "noreply@example.com", "pendracon1@example.com\r\nBcc: other@example.com", false},
+		{"injected sender header", "noreply@example.com\r\nBcc: other@example.com", "pendracon1@example.com", false},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			msg := &Message{From: test.from, To: []string{test.to}, Subject: CONFIRM_SUBJECT, Text: "text"}
+			data, err := msg.Bytes()
+			if (err == nil) != test.ok {
+				t.Fatalf("Expected message formatted %v, got error: %v", test.ok, err)
+			}
+			if test.ok && !strings.Contains(string(data), "From: \"Cloudtacts\" <noreply@example.com>\r\nTo: <pendracon1@example.com>\r\n") {
+				t.Errorf("Unexpected message headers:\n%s", data)
+			}
+		})
+	}
+}
+
+func TestOutboxMailer(t *testing.T) {
+	dir := t.TempDir()
+	mailer := &outboxMailer{dir}
+
+	msg, _ := ConfirmationMessage(cfg, testUser, "token1")
+	if serr := mailer.Send(msg); serr.IsError() {
+		t.Fatalf("Error sending message: %v", serr)
+	}
+
+	files, _ := os.ReadDir(dir)
+	if len(files) != 1 || !strings.HasSuffix(files[0].Name(), "-pendracon1@example.com.eml") {
+		t.Fatalf("Expected one outbox message, got: %v", files)
+	}
+
+	data, _ := os.ReadFile(fmt.Sprintf("%v/%v", dir, files[0].Name()))
+	for _, expected := range []string{"To: <pendracon1@example.com>\r\n", "Content-Type: multipart/alternative;", "Content-Type: text/html;", "token=token1"} {
+		if !strings.Contains(string(data), expected) {
+			t.Errorf("Outbox message missing %q:\n%s", expected, data)
+		}
+	}
+}
+
+func TestSmtpMailer(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	received := make(chan string, 1)
+	go fakeSmtpServer(listener, received)
+
+	mailer := &smtpMailer{addr: listener.Addr().String()}
+	msg, _ := ConfirmationMessage(cfg, testUser, "token1")
+	if serr := mailer.Send(msg); serr.IsError() {
+		t.Fatalf("Error sending message: %v", serr)
+	}
+
+	data := <-received
+	if !strings.Contains(data, "RCPT TO:<pendracon1@example.com>") || !strings.Contains(data, "token=token1") {
+		t.Errorf("Unexpected SMTP session:\n%v", data)
+	}
+}
+
+// fakeSmtpServer accepts a single SMTP session on the given listener and
+// sends its transcript to the given channel.
+func fakeSmtpServer(listener net.Listener, received chan<- string) {
+	conn, err := listener.Accept()
+	if err != nil {
+		received <- err.Error()
+		return
+	}
+	defer conn.Close()
+
+	var transcript strings.Builder
+	reader := bufio.NewReader(conn)
+	fmt.Fprint(conn, "220 localhost ESMTP\r\n")
+
+	inData := false
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			break
+		}
+		transcript.WriteString(line)
+
+		switch {
+		case inData:
+			if line == ".\r\n" {
+				inData = false
+				fmt.Fprint(conn, "250 OK\r\n")
+			}
+		case strings.HasPrefix(line, "EHLO"):
+			fmt.Fprint(conn, "250 localhost\r\n")
+		case strings.HasPrefix(line, "DATA"):
+			inData = true
+			fmt.Fprint(conn, "354 Go ahead\r\n")
+		case strings.HasPrefix(line, "QUIT"):
+			fmt.Fprint(conn, "221 Bye\r\n")
+			received <- transcript.String()
+			return
+		default:
+			fmt.Fprint(conn, "250 OK\r\n")
+		}
+	}
+	received <- transcript.String()
+}
+
+func init() {
+	model.ParserConfigPath = "../../config/parameters_config.json"
+	model.ApplicationConfigPath = "../../config/application.properties"
+	var err error
+	cfg, err = config.ContextConfig()
+	if err != nil {
+		util.LogError("", "notify_test:init", err)
+	}
+}
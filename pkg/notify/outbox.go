@@ -0,0 +1,58 @@
+package notify
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"Cloudtacts/pkg/config"
+	"Cloudtacts/pkg/model"
+)
+
+var unsafeNameChars = regexp.MustCompile(`[^A-Za-z0-9@._-]+`)
+
+// outboxMailer is a stand-in for an SMTP server for development and testing
+// which writes each message to its own .eml file in a local outbox
+// directory, named {timestamp}-{recipient}.eml.
+type outboxMailer struct {
+	dir string
+}
+
+func (om *outboxMailer) Send(msg *Message) model.ServiceError {
+	data, err := msg.Bytes()
+	if err != nil {
+		return model.NotifyError.WithCause(err)
+	}
+
+	if err = os.MkdirAll(om.dir, 0755); err != nil {
+		return model.NotifyError.WithCause(err)
+	}
+
+	name := fmt.Sprintf("%v-%v.eml", time.Now().UTC().Format("20060102150405.000000000"),
+		unsafeNameChars.ReplaceAllString(strings.Join(msg.To, "_"), "_"))
+	if err = os.WriteFile(filepath.Join(om.dir, name), data, 0644); err != nil {
+		return model.NotifyError.WithCause(err)
+	}
+
+	return model.NoError
+}
+
+func (om *outboxMailer) MailerUrl() string {
+	return fmt.Sprintf("file://%v", om.dir)
+}
+
+func (om *outboxMailer) Close() {
+	// nothing to release
+}
+
+func newOutboxMailer(cfg *config.Config) (*outboxMailer, model.ServiceError) {
+	dir, err := filepath.Abs(cfg.ValueOfWithDefault(model.KEY_NOTIFY_OUTBOX_PATH, "./outbox"))
+	if err != nil {
+		return nil, model.NotifyError.WithCause(err)
+	}
+
+	return &outboxMailer{dir}, model.NoError
+}
@@ -0,0 +1,56 @@
+package notify
+
+import (
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+
+	"Cloudtacts/pkg/config"
+	"Cloudtacts/pkg/model"
+)
+
+// smtpMailer sends messages through an SMTP server, using STARTTLS when the
+// server supports it and PLAIN authentication when a login is configured.
+type smtpMailer struct {
+	addr string
+	auth smtp.Auth
+}
+
+func (sm *smtpMailer) Send(msg *Message) model.ServiceError {
+	from, err := mail.ParseAddress(msg.From)
+	if err != nil {
+		return model.NotifyError.WithCause(err)
+	}
+
+	data, err := msg.Bytes()
+	if err != nil {
+		return model.NotifyError.WithCause(err)
+	}
+
+	if err = smtp.SendMail(sm.addr, sm.auth, from.Address, msg.To, data); err != nil {
+		return model.NotifyError.WithCause(err)
+	}
+
+	return model.NoError
+}
+
+func (sm *smtpMailer) MailerUrl() string {
+	return fmt.Sprintf("smtp://%v", sm.addr)
+}
+
+func (sm *smtpMailer) Close() {
+	// connections are opened per message
+}
+
+func newSmtpMailer(cfg *config.Config) (*smtpMailer, model.ServiceError) {
+	host := cfg.ValueOfWithDefault(model.KEY_NOTIFY_SMTP_HOST, "localhost")
+
+	sm := new(smtpMailer)
+	sm.addr = net.JoinHostPort(host, cfg.ValueOfWithDefault(model.KEY_NOTIFY_SMTP_PORT, "587"))
+	if cfg.AssignedValue(model.KEY_NOTIFY_SMTP_LOGIN) {
+		sm.auth = smtp.PlainAuth("", cfg.ValueOf(model.KEY_NOTIFY_SMTP_LOGIN), cfg.ValueOf(model.KEY_NOTIFY_SMTP_PASSWORD), host)
+	}
+
+	return sm, model.NoError
+}
@@ -2,136 +2,111 @@ package storage
 
 import (
 	"context"
-	"encoding/base64"
+	"errors"
 	"fmt"
-	"strings"
+	"io"
 	"time"
 
 	gcs "cloud.google.com/go/storage"
 
 	"Cloudtacts/pkg/config"
 	"Cloudtacts/pkg/model"
-	"Cloudtacts/pkg/util"
 )
 
-const OBJECT_KEY_TMPL = "%v/%v/image.%v"
+const GCS_TIMEOUT = time.Second * 10
 
-func SaveProfilePic(cfg *config.Config, user *model.User) (bool, model.ServiceError) {
-	data, err := base64.StdEncoding.DecodeString(user.CtPpic)
-	if err != nil {
-		return false, model.ImageDecodingError.WithCause(err)
-	}
-
-	ctx, client, bucket, serr := findBucket(cfg)
-	if serr.IsError() {
-		return false, serr
-	}
-	defer client.Close()
-
-	objectKey := fmt.Sprintf(OBJECT_KEY_TMPL, user.CtUser, user.CtProf, user.CtImgt)
-	opic := bucket.Object(objectKey)
+// gcsStore keeps objects in a Google Cloud Storage bucket.
+type gcsStore struct {
+	ctx    context.Context
+	client *gcs.Client
+	bucket *gcs.BucketHandle
+	name   string
+}
 
-	ctx, cancel := context.WithTimeout(ctx, time.Second*10)
+func (gs *gcsStore) SaveObject(key string, data []byte) model.ServiceError {
+	ctx, cancel := context.WithTimeout(gs.ctx, GCS_TIMEOUT)
 	defer cancel()
 
-	w := opic.NewWriter(ctx)
-	if _, err = w.Write(data); err != nil {
-		serr = model.CloudStorageError.WithCause(err)
-		util.LogIt("Cloudtacts", fmt.Sprintf("Failed to save pic to cloud storage: %v", serr))
-		return false, serr
+	w := gs.bucket.Object(key).NewWriter(ctx)
+	if _, err := w.Write(data); err != nil {
+		w.Close()
+		return model.CloudStorageError.WithCause(err)
 	}
-	user.CtPpic = fmt.Sprintf("%v%v", model.OBJK_TAG, objectKey)
 
 	if err := w.Close(); err != nil {
-		serr = model.CloudStorageError.WithCause(err)
-		util.LogIt("Cloudtacts", fmt.Sprintf("Failed to close object writer: %v", serr))
-		return false, serr
+		return model.CloudStorageError.WithCause(err)
 	}
 
-	return true, model.NoError
+	return model.NoError
 }
 
-func DeleteProfilePic(cfg *config.Config, user *model.User) (bool, model.ServiceError) {
-	ctx, client, bucket, serr := findBucket(cfg)
-	if !serr.IsError() {
-		defer client.Close()
+func (gs *gcsStore) ReadObject(key string) ([]byte, model.ServiceError) {
+	ctx, cancel := context.WithTimeout(gs.ctx, GCS_TIMEOUT)
+	defer cancel()
 
-		objectKey := fmt.Sprintf(OBJECT_KEY_TMPL, user.CtUser, user.CtProf, user.CtImgt)
-		ctx, cancel := context.WithTimeout(ctx, time.Second*10)
-		defer cancel()
+	reader, err := gs.bucket.Object(key).NewReader(ctx)
+	if err != nil {
+		return nil, model.CloudStorageError.WithCause(err)
+	}
+	defer reader.Close()
 
-		err := bucket.Object(objectKey).Delete(ctx)
-		if err != nil {
-			serr = model.CloudStorageError.WithCause(err)
-		}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, model.CloudStorageError.WithCause(err)
 	}
 
-	return !serr.IsError(), serr
+	return data, model.NoError
 }
 
-func ReadProfilePic(cfg *config.Config, imageKey string) ([]byte, model.ServiceError) {
-	var ppic []byte
+func (gs *gcsStore) DeleteObject(key string) model.ServiceError {
+	ctx, cancel := context.WithTimeout(gs.ctx, GCS_TIMEOUT)
+	defer cancel()
 
-	ctx, client, bucket, serr := findBucket(cfg)
-	if !serr.IsError() {
-		defer client.Close()
+	if err := gs.bucket.Object(key).Delete(ctx); err != nil {
+		return model.CloudStorageError.WithCause(err)
+	}
 
-		ctx, cancel := context.WithTimeout(ctx, time.Second*10)
-		defer cancel()
+	return model.NoError
+}
 
-		obj := bucket.Object(imageKey)
-		if attrs, err := obj.Attrs(ctx); err != nil {
-			serr = model.CloudStorageError.WithCause(err)
-		} else {
-			ppic = make([]byte, attrs.Size)
-		}
+func (gs *gcsStore) ObjectExists(key string) (bool, model.ServiceError) {
+	ctx, cancel := context.WithTimeout(gs.ctx, GCS_TIMEOUT)
+	defer cancel()
 
-		reader, err := obj.NewReader(ctx)
-		if err != nil {
-			serr = model.CloudStorageError.WithCause(err)
-		} else {
-			if _, err = reader.Read(ppic); err != nil {
-				serr = model.CloudStorageError.WithCause(err)
-			}
+	if _, err := gs.bucket.Object(key).Attrs(ctx); err != nil {
+		if errors.Is(err, gcs.ErrObjectNotExist) {
+			return false, model.NoError
 		}
+		return false, model.CloudStorageError.WithCause(err)
 	}
 
-	return ppic, serr
+	return true, model.NoError
 }
 
-func GetEncodedImage(cfg *config.Config, imageKey string) (string, model.ServiceError) {
-	var serr model.ServiceError
-	var encImg string
-
-	var bbuff []byte
-	if strings.HasPrefix(imageKey, model.OBJK_TAG) {
-		bbuff, serr = ReadProfilePic(cfg, imageKey[2:])
-	} else {
-		bbuff, serr = ReadProfilePic(cfg, imageKey)
-	}
-	encImg = base64.StdEncoding.EncodeToString(bbuff)
-
-	return encImg, serr
+func (gs *gcsStore) StoreUrl() string {
+	return fmt.Sprintf("gs://%v", gs.name)
 }
 
-func findBucket(cfg *config.Config) (context.Context, *gcs.Client, *gcs.BucketHandle, model.ServiceError) {
-	serr := model.NoError
+func (gs *gcsStore) Close() {
+	gs.client.Close()
+}
 
+func newGcsStore(cfg *config.Config) (*gcsStore, model.ServiceError) {
 	var ctx context.Context
 	if ctx = cfg.Context(); ctx == nil {
 		ctx = context.Background()
 	}
 
-	bucketName := cfg.ValueOf(model.KEY_STORAGE_BUCKET)
-	var bucket *gcs.BucketHandle
-
 	client, err := gcs.NewClient(ctx)
 	if err != nil {
-		serr = model.CloudStorageError.WithCause(err)
-		util.LogIt("Cloudtacts", fmt.Sprintf("Failed to create cloud storage client: %v", serr))
-	} else {
-		bucket = client.Bucket(bucketName)
+		return nil, model.CloudStorageError.WithCause(err)
 	}
 
-	return ctx, client, bucket, serr
+	gs := new(gcsStore)
+	gs.ctx = ctx
+	gs.client = client
+	gs.name = cfg.ValueOf(model.KEY_STORAGE_BUCKET)
+	gs.bucket = client.Bucket(gs.name)
+
+	return gs, model.NoError
 }
@@ -0,0 +1,111 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"Cloudtacts/pkg/config"
+	"Cloudtacts/pkg/model"
+)
+
+// fileStore keeps objects as files under a local directory, laid out as
+// {root}/{bucket}/{key} to match the layout of a local MinIO data directory.
+type fileStore struct {
+	root string
+}
+
+func (fs *fileStore) SaveObject(key string, data []byte) model.ServiceError {
+	path, serr := fs.objectPath(key)
+	if serr.IsError() {
+		return serr
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return model.CloudStorageError.WithCause(err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return model.CloudStorageError.WithCause(err)
+	}
+
+	return model.NoError
+}
+
+func (fs *fileStore) ReadObject(key string) ([]byte, model.ServiceError) {
+	path, serr := fs.objectPath(key)
+	if serr.IsError() {
+		return nil, serr
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, model.CloudStorageError.WithCause(err)
+	}
+
+	return data, model.NoError
+}
+
+func (fs *fileStore) DeleteObject(key string) model.ServiceError {
+	path, serr := fs.objectPath(key)
+	if serr.IsError() {
+		return serr
+	}
+
+	if err := os.Remove(path); err != nil {
+		return model.CloudStorageError.WithCause(err)
+	}
+
+	return model.NoError
+}
+
+func (fs *fileStore) ObjectExists(key string) (bool, model.ServiceError) {
+	path, serr := fs.objectPath(key)
+	if serr.IsError() {
+		return false, serr
+	}
+
+	if _, err := os.Stat(path); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, model.NoError
+		}
+		return false, model.CloudStorageError.WithCause(err)
+	}
+
+	return true, model.NoError
+}
+
+func (fs *fileStore) StoreUrl() string {
+	return fmt.Sprintf("file://%v", fs.root)
+}
+
+func (fs *fileStore) Close() {
+	// nothing to release
+}
+
+// objectPath returns the file path of the given object key, rejecting keys
+// that would resolve outside of the store's root directory.
+func (fs *fileStore) objectPath(key string) (string, model.ServiceError) {
+	path := filepath.Join(fs.root, filepath.FromSlash(key))
+	if !strings.HasPrefix(path, fs.root+string(filepath.Separator)) {
+		return "", model.CloudStorageError.WithCause(errors.New("invalid object key: " + key))
+	}
+
+	return path, model.NoError
+}
+
+func newFileStore(cfg *config.Config) (*fileStore, model.ServiceError) {
+	root, err := filepath.Abs(cfg.ValueOfWithDefault(model.KEY_STORAGE_LOCAL_PATH, "./minio/data"))
+	if err != nil {
+		return nil, model.CloudStorageError.WithCause(err)
+	}
+	if cfg.AssignedValue(model.KEY_STORAGE_BUCKET) {
+		root = filepath.Join(root, cfg.ValueOf(model.KEY_STORAGE_BUCKET))
+	}
+
+	fs := new(fileStore)
+	fs.root = root
+
+	return fs, model.NoError
+}
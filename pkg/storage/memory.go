@@ -0,0 +1,85 @@
+package storage
+
+import (
+	"errors"
+	"sync"
+
+	"Cloudtacts/pkg/model"
+)
+
+var (
+	memStore     *memoryStore
+	memStoreOnce sync.Once
+)
+
+// memoryStore keeps objects in process memory. It's intended for testing and
+// local development only; objects are lost on exit.
+type memoryStore struct {
+	mutex   sync.RWMutex
+	objects map[string][]byte
+}
+
+func (ms *memoryStore) SaveObject(key string, data []byte) model.ServiceError {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	ms.objects[key] = append([]byte(nil), data...)
+
+	return model.NoError
+}
+
+func (ms *memoryStore) ReadObject(key string) ([]byte, model.ServiceError) {
+	ms.mutex.RLock()
+	defer ms.mutex.RUnlock()
+
+	data, ok := ms.objects[key]
+	if !ok {
+		return nil, model.CloudStorageError.WithCause(errors.New("object not found: " + key))
+	}
+
+	return append([]byte(nil), data...), model.NoError
+}
+
+func (ms *memoryStore) DeleteObject(key string) model.ServiceError {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	if _, ok := ms.objects[key]; !ok {
+		return model.CloudStorageError.WithCause(errors.New("object not found: " + key))
+	}
+	delete(ms.objects, key)
+
+	return model.NoError
+}
+
+func (ms *memoryStore) ObjectExists(key string) (bool, model.ServiceError) {
+	ms.mutex.RLock()
+	defer ms.mutex.RUnlock()
+
+	_, ok := ms.objects[key]
+
+	return ok, model.NoError
+}
+
+func (ms *memoryStore) StoreUrl() string {
+	return "memory"
+}
+
+func (ms *memoryStore) Close() {
+	// objects are kept for the life of the process
+}
+
+func newMemoryStore() *memoryStore {
+	ms := new(memoryStore)
+	ms.objects = make(map[string][]byte)
+
+	return ms
+}
+
+func sharedMemoryStore() *memoryStore {
+	memStoreOnce.Do(func() {
+		memStore = newMemoryStore()
+	})
+
+	return memStore
+}
@@ -0,0 +1,124 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+
+	"Cloudtacts/pkg/config"
+	"Cloudtacts/pkg/model"
+)
+
+// Default maximum time of an S3 request; objects are uploaded and downloaded
+// whole, within a single request.
+const S3_TIMEOUT = time.Second * 30
+
+// s3Store keeps objects in a bucket of an S3 protocol compatible service,
+// such as a local MinIO server.
+type s3Store struct {
+	ctx      context.Context
+	client   *minio.Client
+	bucket   string
+	endpoint string
+	timeout  time.Duration
+}
+
+func (ss *s3Store) SaveObject(key string, data []byte) model.ServiceError {
+	ctx, cancel := context.WithTimeout(ss.ctx, ss.timeout)
+	defer cancel()
+
+	_, err := ss.client.PutObject(ctx, ss.bucket, key, bytes.NewReader(data), int64(len(data)), minio.PutObjectOptions{
+		ContentType: http.DetectContentType(data),
+	})
+	if err != nil {
+		return model.CloudStorageError.WithCause(err)
+	}
+
+	return model.NoError
+}
+
+func (ss *s3Store) ReadObject(key string) ([]byte, model.ServiceError) {
+	ctx, cancel := context.WithTimeout(ss.ctx, ss.timeout)
+	defer cancel()
+
+	obj, err := ss.client.GetObject(ctx, ss.bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, model.CloudStorageError.WithCause(err)
+	}
+	defer obj.Close()
+
+	data, err := io.ReadAll(obj)
+	if err != nil {
+		return nil, model.CloudStorageError.WithCause(err)
+	}
+
+	return data, model.NoError
+}
+
+func (ss *s3Store) DeleteObject(key string) model.ServiceError {
+	ctx, cancel := context.WithTimeout(ss.ctx, ss.timeout)
+	defer cancel()
+
+	if err := ss.client.RemoveObject(ctx, ss.bucket, key, minio.RemoveObjectOptions{}); err != nil {
+		return model.CloudStorageError.WithCause(err)
+	}
+
+	return model.NoError
+}
+
+func (ss *s3Store) ObjectExists(key string) (bool, model.ServiceError) {
+	ctx, cancel := context.WithTimeout(ss.ctx, ss.timeout)
+	defer cancel()
+
+	if _, err := ss.client.StatObject(ctx, ss.bucket, key, minio.StatObjectOptions{}); err != nil {
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return false, model.NoError
+		}
+		return false, model.CloudStorageError.WithCause(err)
+	}
+
+	return true, model.NoError
+}
+
+func (ss *s3Store) StoreUrl() string {
+	return fmt.Sprintf("s3://%v/%v", ss.endpoint, ss.bucket)
+}
+
+func (ss *s3Store) Close() {
+	// the minio client holds no connections that need releasing
+}
+
+func newS3Store(cfg *config.Config) (*s3Store, model.ServiceError) {
+	var ctx context.Context
+	if ctx = cfg.Context(); ctx == nil {
+		ctx = context.Background()
+	}
+
+	endpoint := cfg.ValueOfWithDefault(model.KEY_STORAGE_S3_ENDPOINT, "localhost:9000")
+	useSsl, _ := cfg.Bool(model.KEY_STORAGE_S3_USE_SSL)
+	client, err := minio.New(endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(cfg.ValueOf(model.KEY_STORAGE_S3_ACCESS_KEY), cfg.ValueOf(model.KEY_STORAGE_S3_SECRET_KEY), ""),
+		Secure: useSsl,
+	})
+	if err != nil {
+		return nil, model.CloudStorageError.WithCause(err)
+	}
+
+	ss := new(s3Store)
+	ss.ctx = ctx
+	ss.client = client
+	ss.bucket = cfg.ValueOfWithDefault(model.KEY_STORAGE_S3_BUCKET, "cloudtacts")
+	ss.endpoint = endpoint
+	ss.timeout = S3_TIMEOUT
+	if dval, err := cfg.Duration(model.KEY_STORAGE_S3_TIMEOUT); err == nil {
+		ss.timeout = dval
+	}
+
+	return ss, model.NoError
+}
@@ -0,0 +1,155 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+
+	"Cloudtacts/pkg/model"
+)
+
+const testBucket = "cloudtacts-test"
+
+// fakeS3 is an S3 protocol server keeping the objects of a single bucket in
+// memory. Requests for keys under 'denied/' are refused.
+type fakeS3 struct {
+	mutex   sync.Mutex
+	objects map[string][]byte
+}
+
+func (fs3 *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if _, ok := r.URL.Query()["location"]; ok {
+		fmt.Fprint(w, `<LocationConstraint xmlns="http://s3.amazonaws.com/doc/2006-03-01/">us-east-1</LocationConstraint>`)
+		return
+	}
+
+	key, found := strings.CutPrefix(r.URL.Path, "/"+testBucket+"/")
+	if !found {
+		fs3.writeError(w, r, http.StatusNotFound, "NoSuchBucket")
+		return
+	}
+	if strings.HasPrefix(key, "denied/") {
+		fs3.writeError(w, r, http.StatusForbidden, "AccessDenied")
+		return
+	}
+
+	fs3.mutex.Lock()
+	defer fs3.mutex.Unlock()
+
+	data, exists := fs3.objects[key]
+	switch r.Method {
+	case http.MethodPut:
+		body, _ := io.ReadAll(r.Body)
+		if strings.HasPrefix(r.Header.Get("X-Amz-Content-Sha256"), "STREAMING-") {
+			body = decodeChunks(body)
+		}
+		fs3.objects[key] = body
+		w.Header().Set("ETag", `"0"`)
+	case http.MethodDelete:
+		delete(fs3.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodGet, http.MethodHead:
+		if !exists {
+			fs3.writeError(w, r, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+		w.Header().Set("ETag", `"0"`)
+		w.Header().Set("Last-Modified", time.Now().UTC().Format(http.TimeFormat))
+		w.Header().Set("Content-Length", fmt.Sprint(len(data)))
+		if r.Method == http.MethodGet {
+			w.Write(data)
+		}
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// writeError writes an S3 error response with the given status and code;
+// responses to HEAD requests have only the status.
+func (fs3 *fakeS3) writeError(w http.ResponseWriter, r *http.Request, status int, code string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	if r.Method != http.MethodHead {
+		fmt.Fprintf(w, `<Error><Code>%v</Code><Message>%v</Message><Resource>%v</Resource></Error>`, code, code, r.URL.Path)
+	}
+}
+
+// decodeChunks returns the payload of the given signed chunked (aws-chunked)
+// request body, each chunk being '{hex size};chunk-signature={sig}\r\n{data}\r\n'.
+func decodeChunks(body []byte) []byte {
+	var data []byte
+	for len(body) > 0 {
+		header, rest, _ := strings.Cut(string(body), "\r\n")
+		var size int
+		fmt.Sscanf(header, "%x;", &size)
+		if size == 0 || size > len(rest) {
+			break
+		}
+		data = append(data, rest[:size]...)
+		body = []byte(strings.TrimPrefix(rest[size:], "\r\n"))
+	}
+
+	return data
+}
+
+// newFakeS3Store returns an S3 store of a fake S3 server's bucket.
+func newFakeS3Store(t *testing.T) *s3Store {
+	server := httptest.NewServer(&fakeS3{objects: map[string][]byte{}})
+	t.Cleanup(server.Close)
+
+	surl, _ := url.Parse(server.URL)
+	client, err := minio.New(surl.Host, &minio.Options{
+		Creds: credentials.NewStaticV4("access", "secret", ""),
+	})
+	if err != nil {
+		t.Fatalf("Error creating minio client: %v", err)
+	}
+
+	return &s3Store{ctx: context.Background(), client: client, bucket: testBucket, endpoint: surl.Host, timeout: S3_TIMEOUT}
+}
+
+func TestS3ObjectExists(t *testing.T) {
+	store := newFakeS3Store(t)
+
+	if serr := store.SaveObject("pendracon1/Pendracon1.png", []byte("not really a png")); serr.IsError() {
+		t.Fatalf("Error saving object: %v", serr)
+	}
+
+	for _, test := range []struct {
+		key    string
+		exists bool
+		serr   model.ServiceError
+	}{
+		{"pendracon1/Pendracon1.png", true, model.NoError},
+		{"pendracon1/missing.png", false, model.NoError},
+		{"denied/Pendracon1.png", false, model.CloudStorageError},
+	} {
+		exists, serr := store.ObjectExists(test.key)
+		if exists != test.exists || serr.Code != test.serr.Code {
+			t.Errorf("Expected object %v exists %v (%v), got %v: %v", test.key, test.exists, test.serr, exists, serr)
+		}
+	}
+
+	if _, serr := store.ReadObject("pendracon1/missing.png"); serr.Code != model.CloudStorageError.Code {
+		t.Errorf("Expected %v reading missing object, got: %v", model.CloudStorageError, serr)
+	}
+}
+
+func TestS3StoreTimeout(t *testing.T) {
+	store := newFakeS3Store(t)
+	store.timeout = time.Nanosecond
+
+	if serr := store.SaveObject("pendracon1/Pendracon1.png", []byte("x")); serr.Code != model.CloudStorageError.Code {
+		t.Errorf("Expected %v saving object past the store timeout, got: %v", model.CloudStorageError, serr)
+	}
+}
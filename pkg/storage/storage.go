@@ -0,0 +1,186 @@
+// Package storage provides access to the application's object store where
+// users' profile images are kept.
+package storage
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"Cloudtacts/pkg/config"
+	"Cloudtacts/pkg/model"
+	"Cloudtacts/pkg/util"
+)
+
+const (
+	OBJECT_KEY_TMPL = "%v/image.%v"
+
+	STORE_TYPE_GCS    = "gcs"
+	STORE_TYPE_LOCAL  = "local"
+	STORE_TYPE_MEMORY = "memory"
+	STORE_TYPE_S3     = "s3"
+)
+
+type ObjectStore interface {
+	// Saves the given data to the object with the given key, replacing any
+	// existing object.
+	SaveObject(string, []byte) model.ServiceError
+
+	// Returns the data of the object with the given key.
+	ReadObject(string) ([]byte, model.ServiceError)
+
+	// Deletes the object with the given key.
+	DeleteObject(string) model.ServiceError
+
+	// Returns true if an object with the given key exists.
+	ObjectExists(string) (bool, model.ServiceError)
+
+	// Returns the location of the store (e.g. bucket URL or directory).
+	StoreUrl() string
+
+	// Closes the store connection.
+	Close()
+}
+
+// GetObjectStore returns the object store implementation selected by the
+// configured store type.
+func GetObjectStore(cfg *config.Config) (ObjectStore, model.ServiceError) {
+	storeType := strings.ToLower(cfg.ValueOfWithDefault(model.KEY_STORAGE_TYPE, STORE_TYPE_GCS))
+
+	var store ObjectStore
+	serr := model.NoError
+	switch storeType {
+	case STORE_TYPE_GCS:
+		store, serr = newGcsStore(cfg)
+	case STORE_TYPE_LOCAL:
+		store, serr = newFileStore(cfg)
+	case STORE_TYPE_S3:
+		store, serr = newS3Store(cfg)
+	case STORE_TYPE_MEMORY:
+		store = sharedMemoryStore()
+	default:
+		serr = model.CloudStorageError.WithCause(fmt.Errorf("unknown store type '%v'", storeType))
+	}
+
+	if serr.IsError() {
+		util.LogIt("Cloudtacts", fmt.Sprintf("Failed to create object store client: %v", serr))
+		return nil, serr
+	}
+	traceIt(cfg, fmt.Sprintf("Object store using %v.", store.StoreUrl()))
+
+	return store, model.NoError
+}
+
+// SaveProfilePic decodes the referenced user's Base64 encoded profile image
+// and saves it to object storage. The user's image is replaced with its
+// tagged object key on success.
+func SaveProfilePic(cfg *config.Config, user *model.User) (bool, model.ServiceError) {
+	data, serr := DecodeProfilePic(user)
+	if serr.IsError() {
+		return false, serr
+	}
+
+	store, serr := GetObjectStore(cfg)
+	if serr.IsError() {
+		return false, serr
+	}
+	defer store.Close()
+
+	objectKey := ProfilePicKey(user)
+	if serr = store.SaveObject(objectKey, data); serr.IsError() {
+		util.LogIt("Cloudtacts", fmt.Sprintf("Failed to save pic to object storage: %v", serr))
+		return false, serr
+	}
+	user.CtPpic = fmt.Sprintf("%v%v", model.OBJK_TAG, objectKey)
+
+	return true, model.NoError
+}
+
+// DecodeProfilePic returns the referenced user's Base64 encoded profile
+// image.
+func DecodeProfilePic(user *model.User) ([]byte, model.ServiceError) {
+	data, err := base64.StdEncoding.DecodeString(user.CtPpic)
+	if err != nil {
+		return nil, model.ImageDecodingError.WithCause(err)
+	}
+
+	return data, model.NoError
+}
+
+// DeleteProfilePic deletes the referenced user's profile image from object
+// storage.
+func DeleteProfilePic(cfg *config.Config, user *model.User) (bool, model.ServiceError) {
+	store, serr := GetObjectStore(cfg)
+	if !serr.IsError() {
+		defer store.Close()
+
+		serr = store.DeleteObject(savedPicKey(user))
+	}
+
+	return !serr.IsError(), serr
+}
+
+// ReadProfilePic returns the profile image saved with the given object key.
+func ReadProfilePic(cfg *config.Config, imageKey string) ([]byte, model.ServiceError) {
+	var ppic []byte
+
+	store, serr := GetObjectStore(cfg)
+	if !serr.IsError() {
+		defer store.Close()
+
+		ppic, serr = store.ReadObject(imageKey)
+	}
+
+	return ppic, serr
+}
+
+// ProfilePicExists returns true if the referenced user's profile image is
+// saved in object storage.
+func ProfilePicExists(cfg *config.Config, user *model.User) (bool, model.ServiceError) {
+	store, serr := GetObjectStore(cfg)
+	if serr.IsError() {
+		return false, serr
+	}
+	defer store.Close()
+
+	return store.ObjectExists(savedPicKey(user))
+}
+
+func GetEncodedImage(cfg *config.Config, imageKey string) (string, model.ServiceError) {
+	var serr model.ServiceError
+	var encImg string
+
+	var bbuff []byte
+	if strings.HasPrefix(imageKey, model.OBJK_TAG) {
+		bbuff, serr = ReadProfilePic(cfg, imageKey[2:])
+	} else {
+		bbuff, serr = ReadProfilePic(cfg, imageKey)
+	}
+	encImg = base64.StdEncoding.EncodeToString(bbuff)
+
+	return encImg, serr
+}
+
+// ProfilePicKey returns the object key of the referenced user's profile
+// image. The key is the user's surrogate ID, assigned by the server: users
+// may share a login identifier and profile name (see auth.UniquenessPolicy).
+func ProfilePicKey(user *model.User) string {
+	return fmt.Sprintf(OBJECT_KEY_TMPL, user.UserId, user.CtImgt)
+}
+
+// savedPicKey returns the object key saved as the referenced user's image,
+// if any, which may predate keying images by user ID, or else its
+// ProfilePicKey.
+func savedPicKey(user *model.User) string {
+	if user.HasProfilePicKey() {
+		return strings.TrimPrefix(user.CtPpic, model.OBJK_TAG)
+	}
+
+	return ProfilePicKey(user)
+}
+
+func traceIt(cfg *config.Config, message string) {
+	if testMode, _ := cfg.Bool(model.KEY_USERDB_TEST_MODE); testMode {
+		util.LogIt("Cloudtacts", message)
+	}
+}
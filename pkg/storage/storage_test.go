@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"bytes"
+	"testing"
+
+	"Cloudtacts/pkg/model"
+)
+
+func TestObjectStores(t *testing.T) {
+	stores := map[string]ObjectStore{
+		STORE_TYPE_LOCAL:  &fileStore{root: t.TempDir()},
+		STORE_TYPE_MEMORY: newMemoryStore(),
+		STORE_TYPE_S3:     newFakeS3Store(t),
+	}
+
+	user := &model.User{UserId: "9b2f7c1e-3d4a-4e5f-8a6b-7c8d9e0f1a2b", CtUser: "pendracon1", CtProf: "Pendracon1", CtImgt: "png"}
+	data := []byte("not really a png")
+
+	for storeType, store := range stores {
+		t.Run(storeType, func(t *testing.T) {
+			key := ProfilePicKey(user)
+			if serr := store.SaveObject(key, data); serr.IsError() {
+				t.Fatalf("Error saving object: %v", serr)
+			}
+
+			if exists, serr := store.ObjectExists(key); serr.IsError() || !exists {
+				t.Errorf("Saved object not found: %v", serr)
+			}
+
+			rdata, serr := store.ReadObject(key)
+			if serr.IsError() {
+				t.Fatalf("Error reading object: %v", serr)
+			}
+			if !bytes.Equal(rdata, data) {
+				t.Errorf("Read data %q doesn't match saved data %q.", rdata, data)
+			}
+
+			if serr := store.DeleteObject(key); serr.IsError() {
+				t.Errorf("Error deleting object: %v", serr)
+			}
+			if _, serr := store.ReadObject(key); !serr.IsError() {
+				t.Error("Deleted object returned in read.")
+			}
+			if exists, serr := store.ObjectExists(key); serr.IsError() || exists {
+				t.Errorf("Deleted object reported as existing: %v", serr)
+			}
+		})
+	}
+}
+
+func TestFileStoreRejectsEscapingKeys(t *testing.T) {
+	store := &fileStore{root: t.TempDir()}
+
+	if serr := store.SaveObject("../outside/image.png", []byte("x")); !serr.IsError() {
+		t.Error("Saved object outside of store root.")
+	}
+}
+
+func TestSavedPicKey(t *testing.T) {
+	for _, test := range []struct {
+		user model.User
+		key  string
+	}{
+		{model.User{UserId: "uid1", CtUser: "pendracon1", CtProf: "Pendracon1", CtImgt: "png"}, "uid1/image.png"},
+		{model.User{UserId: "uid1", CtUser: "pendracon1", CtProf: "Pendracon1", CtPpic: model.OBJK_TAG + "uid1/image.gif"}, "uid1/image.gif"},
+		// saved before images were keyed by user ID
+		{model.User{UserId: "uid1", CtUser: "pendracon1", CtProf: "Pendracon1", CtPpic: model.OBJK_TAG + "pendracon1/Pendracon1/image.png"}, "pendracon1/Pendracon1/image.png"},
+	} {
+		if key := savedPicKey(&test.user); key != test.key {
+			t.Errorf("Expected key %q of %v, got: %q", test.key, test.user, key)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package util
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Options are parsed command line arguments (see ParseOptions).
+type Options struct {
+	// Values of each option, in the order given
+	Values map[string][]string
+
+	// Positional arguments, in the order given
+	Positional []string
+}
+
+// Value returns the last value given of the named option, and true if the
+// option was given.
+func (opts *Options) Value(name string) (string, bool) {
+	values := opts.Values[name]
+	if len(values) == 0 {
+		return "", false
+	}
+	return values[len(values)-1], true
+}
+
+// ParseOptions parses the given command line arguments. Options are prefixed
+// by the given switch (e.g. "--"), and separated from their values by the
+// given separator: one of ' ' (the value is the next argument), '=', ',',
+// ':', or ';'. An option given without a value (e.g. "--dryRun") is a boolean
+// switch with value "true". With a space separator, an option is a switch if
+// no value follows it, or if it is one of the given switches and the next
+// argument isn't a boolean. Options given more than once keep all their
+// values. Arguments which aren't options, and all arguments after a "--"
+// terminator, are positional.
+func ParseOptions(argSwitch string, argSeparator uint8, args []string, switches ...string) *Options {
+	if argSeparator == 0 {
+		argSeparator = ' '
+	}
+
+	opts := &Options{Values: map[string][]string{}, Positional: []string{}}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			opts.Positional = append(opts.Positional, args[i+1:]...)
+			break
+		}
+
+		name, ok := optionName(argSwitch, argSeparator, arg)
+		switch {
+		case !ok:
+			opts.Positional = append(opts.Positional, arg)
+		case argSeparator != ' ':
+			if key, value, found := strings.Cut(name, string(argSeparator)); found {
+				opts.Values[key] = append(opts.Values[key], value)
+			} else {
+				opts.Values[name] = append(opts.Values[name], "true")
+			}
+		case i+1 < len(args) && takesValue(argSwitch, argSeparator, args[i+1], slices.Contains(switches, name)):
+			i++
+			opts.Values[name] = append(opts.Values[name], args[i])
+		default:
+			opts.Values[name] = append(opts.Values[name], "true")
+		}
+	}
+
+	return opts
+}
+
+// optionName returns the given argument without its prefix switch, and true
+// if the argument is an option. Without a switch, arguments containing the
+// separator (other than a space) are options. Negative numbers (e.g. "-1")
+// aren't options.
+func optionName(argSwitch string, argSeparator uint8, arg string) (string, bool) {
+	if len(argSwitch) == 0 {
+		ok := argSeparator != ' ' && strings.IndexByte(arg, argSeparator) > 0
+		return arg, ok
+	}
+
+	name, found := strings.CutPrefix(arg, argSwitch)
+	if !found || len(name) == 0 || strings.IndexByte("-.0123456789", name[0]) >= 0 || name[0] == argSeparator {
+		return "", false
+	}
+	return name, true
+}
+
+// takesValue returns true if the given argument following an option is the
+// option's value: it isn't an option, or a terminator, and if the option is
+// a switch, it is a boolean.
+func takesValue(argSwitch string, argSeparator uint8, next string, isSwitch bool) bool {
+	if _, ok := optionName(argSwitch, argSeparator, next); ok || next == "--" {
+		return false
+	}
+	if isSwitch {
+		_, err := strconv.ParseBool(next)
+		return err == nil
+	}
+	return true
+}
@@ -0,0 +1,131 @@
+package util
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseOptions(t *testing.T) {
+	for _, test := range []struct {
+		name         string
+		argSwitch    string
+		argSeparator uint8
+		args         []string
+		switches     []string
+		values       map[string][]string
+		positional   []string
+	}{
+		{"none", "--", '=', nil, nil, map[string][]string{}, []string{}},
+		{"equals", "--", '=', []string{"--a=1", "--b=x=y"}, nil,
+			map[string][]string{"a": {"1"}, "b": {"x=y"}}, []string{}},
+		{"comma", "-", ',', []string{"-a,1", "-b,"}, nil,
+			map[string][]string{"a": {"1"}, "b": {""}}, []string{}},
+		{"colon", "--", ':', []string{"--url:http://host:80"}, nil,
+			map[string][]string{"url": {"http://host:80"}}, []string{}},
+		{"semi-colon", "/", ';', []string{"/a;1", "b;2"}, nil,
+			map[string][]string{"a": {"1"}}, []string{"b;2"}},
+		{"space", "--", ' ', []string{"--a", "1", "--b", "x y"}, nil,
+			map[string][]string{"a": {"1"}, "b": {"x y"}}, []string{}},
+		{"space last option", "--", ' ', []string{"--a"}, nil,
+			map[string][]string{"a": {"true"}}, []string{}},
+		{"space zero separator", "--", 0, []string{"--a", "1"}, nil,
+			map[string][]string{"a": {"1"}}, []string{}},
+		{"space switch before option", "--", ' ', []string{"--dryRun", "--a", "1"}, nil,
+			map[string][]string{"dryRun": {"true"}, "a": {"1"}}, []string{}},
+		{"space switch before argument", "--", ' ', []string{"--dryRun", "file"}, []string{"dryRun"},
+			map[string][]string{"dryRun": {"true"}}, []string{"file"}},
+		{"space switch with value", "--", ' ', []string{"--dryRun", "false", "file"}, []string{"dryRun"},
+			map[string][]string{"dryRun": {"false"}}, []string{"file"}},
+		{"switch", "--", '=', []string{"--dryRun", "--a=1"}, nil,
+			map[string][]string{"dryRun": {"true"}, "a": {"1"}}, []string{}},
+		{"repeated", "--", '=', []string{"--a=1", "--b=2", "--a=3"}, nil,
+			map[string][]string{"a": {"1", "3"}, "b": {"2"}}, []string{}},
+		{"positional", "--", '=', []string{"explain", "--a=1", "more"}, nil,
+			map[string][]string{"a": {"1"}}, []string{"explain", "more"}},
+		{"terminator", "--", '=', []string{"--a=1", "--", "--b=2", "--"}, nil,
+			map[string][]string{"a": {"1"}}, []string{"--b=2", "--"}},
+		{"space terminator", "--", ' ', []string{"--a", "--", "--b"}, nil,
+			map[string][]string{"a": {"true"}}, []string{"--b"}},
+		{"negative numbers", "-", ' ', []string{"-a", "-1", "-.5", "-b", "-2"}, nil,
+			map[string][]string{"a": {"-1"}, "b": {"-2"}}, []string{"-.5"}},
+		{"not options", "--", '=', []string{"--", "-", "---a=1", "--=1"}, nil,
+			map[string][]string{}, []string{"-", "---a=1", "--=1"}},
+		{"no switch", "", '=', []string{"a=1", "b", "=c", "a=2"}, nil,
+			map[string][]string{"a": {"1", "2"}}, []string{"b", "=c"}},
+		{"no switch space", "", ' ', []string{"a", "1"}, nil,
+			map[string][]string{}, []string{"a", "1"}},
+		{"other switch", "--", ' ', []string{"-a", "1"}, nil,
+			map[string][]string{}, []string{"-a", "1"}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			opts := ParseOptions(test.argSwitch, test.argSeparator, test.args, test.switches...)
+			if !reflect.DeepEqual(opts.Values, test.values) {
+				t.Errorf("Expected values %q, got: %q", test.values, opts.Values)
+			}
+			if !reflect.DeepEqual(opts.Positional, test.positional) {
+				t.Errorf("Expected positional arguments %q, got: %q", test.positional, opts.Positional)
+			}
+		})
+	}
+}
+
+func TestOptionsValue(t *testing.T) {
+	opts := ParseOptions("--", '=', []string{"--a=1", "--a=2", "--b="})
+	for _, test := range []struct {
+		name  string
+		value string
+		given bool
+	}{
+		{"a", "2", true},
+		{"b", "", true},
+		{"c", "", false},
+	} {
+		if value, given := opts.Value(test.name); value != test.value || given != test.given {
+			t.Errorf("Expected %v value %q (%v), got: %q (%v)", test.name, test.value, test.given, value, given)
+		}
+	}
+}
+
+func FuzzParseOptions(f *testing.F) {
+	f.Add("--", uint8(0), "--a\n1\n--dryRun\n--\nfile")
+	f.Add("--", uint8(1), "--a=1\n--a=2\nexplain\n--b")
+	f.Add("-", uint8(2), "-a,1\n-1\n--")
+	f.Add("", uint8(3), "a:1\n:b\nc")
+	f.Add("/", uint8(4), "/a;1;2\n/;\n/")
+
+	f.Fuzz(func(t *testing.T, argSwitch string, separator uint8, joined string) {
+		argSeparator := " =,:;"[separator%5]
+		args := strings.Split(joined, "\n")
+
+		opts := ParseOptions(argSwitch, argSeparator, args, "dryRun")
+		count := len(opts.Positional)
+		for _, values := range opts.Values {
+			count += len(values)
+		}
+		if count > len(args) {
+			t.Fatalf("Expected at most %d values and positional arguments, got: %d", len(args), count)
+		}
+		for _, arg := range opts.Positional {
+			if !slices.Contains(args, arg) {
+				t.Fatalf("Expected positional argument %q among the arguments", arg)
+			}
+		}
+
+		if argSeparator == ' ' {
+			return
+		}
+		formatted := []string{}
+		for name, values := range opts.Values {
+			for _, value := range values {
+				formatted = append(formatted, argSwitch+name+string(argSeparator)+value)
+			}
+		}
+		formatted = append(append(formatted, "--"), opts.Positional...)
+		again := ParseOptions(argSwitch, argSeparator, formatted, "dryRun")
+		if !reflect.DeepEqual(again, opts) {
+			t.Fatalf("Expected %q re-parsed as %+v, got: %+v", formatted, opts, again)
+		}
+	})
+}
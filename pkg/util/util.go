@@ -90,27 +90,15 @@ func ToUserList(data []byte, userList *model.UserList) error {
 	return nil
 }
 
-func ParseOptions(argSwitch string, argSeparator uint8, args []string) *map[string]string {
-	opts := make(map[string]string)
-
-	var v []string
-	for idx, arg := range args {
-		if argSeparator == ' ' {
-			if idx%2 == 1 {
-				opts[v[0]] = arg
-			} else {
-				v[0] = arg
-				opts[arg] = ""
-			}
-		} else if strings.Contains(arg, string(argSeparator)) {
-			v = strings.Split(strings.TrimPrefix(arg, argSwitch), string(argSeparator))
-			opts[v[0]] = v[1]
-		} else {
-			LogIt("", fmt.Sprintf("Argument separator not found: '%v'", string(argSeparator)))
-		}
+// ToUser converts the given JSON data to a single user instance.
+func ToUser(data []byte, user *model.User) error {
+	err := json.Unmarshal(data, user)
+	if err != nil {
+		LogIt("", fmt.Sprintf("Error converting data to User:\n%v", data))
+		return WrappedError(err, "unmarshal")
 	}
 
-	return &opts
+	return nil
 }
 
 func StripDateStamp(datetime string) string {